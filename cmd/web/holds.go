@@ -0,0 +1,42 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"github.com/NhanNT-VNG/hotel-booking/internal/handlers"
+)
+
+// sweepExpiredHolds releases expired room holds and cancels bookings that
+// were not paid in time every minute until ctx is cancelled.
+func sweepExpiredHolds(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(time.Minute)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+
+			// a failure here must not stop unpaid bookings from being released
+			removed, err := handlers.Repo.DB.DeleteExpiredHolds(ctx)
+			if err != nil {
+				app.ErrorLog.Println(err)
+			} else if removed > 0 {
+				app.InfoLog.Println("Released expired room holds:", removed)
+			}
+
+			cancelled, err := handlers.Repo.CancelUnpaidReservations(ctx)
+			if err != nil {
+				app.ErrorLog.Println(err)
+				continue
+			}
+			if cancelled > 0 {
+				app.InfoLog.Println("Cancelled unpaid reservations:", cancelled)
+			}
+		}
+	}()
+}
@@ -1,18 +1,25 @@
 package main
 
 import (
+	"context"
 	"encoding/gob"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
 	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
 	"time"
 
 	"github.com/NhanNT-VNG/hotel-booking/internal/config"
 	"github.com/NhanNT-VNG/hotel-booking/internal/driver"
 	"github.com/NhanNT-VNG/hotel-booking/internal/handlers"
 	"github.com/NhanNT-VNG/hotel-booking/internal/helpers"
+	"github.com/NhanNT-VNG/hotel-booking/internal/mailer"
 	"github.com/NhanNT-VNG/hotel-booking/internal/models"
+	"github.com/NhanNT-VNG/hotel-booking/internal/payments"
 	"github.com/NhanNT-VNG/hotel-booking/internal/render"
 
 	"github.com/alexedwards/scs/v2"
@@ -34,9 +41,12 @@ func main() {
 	}
 
 	defer db.SQL.Close()
-	defer close(app.MailChan)
+
+	sweepCtx, stopSweeping := context.WithCancel(context.Background())
+	defer stopSweeping()
 
 	listenForMail()
+	sweepExpiredHolds(sweepCtx)
 
 	fmt.Println("Starting mail listener...")
 	fmt.Println("App listen on port", portNumber)
@@ -46,20 +56,90 @@ func main() {
 		Handler: routes(&app),
 	}
 
-	err = src.ListenAndServe()
-	log.Fatal(err)
+	go func() {
+		err := src.ListenAndServe()
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatal(err)
+		}
+	}()
+
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, os.Interrupt, syscall.SIGTERM)
+	<-quit
+
+	log.Println("Shutting down...")
+	stopSweeping()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	err = src.Shutdown(ctx)
+	if err != nil {
+		errorLog.Println(err)
+	}
+	drainMail(ctx)
 }
 
 func run() (*driver.DB, error) {
 	gob.Register(models.Reservation{})
+	gob.Register(models.Guests{})
 	gob.Register(models.Room{})
 	gob.Register(models.Restriction{})
 	gob.Register(models.User{})
 
-	mailChan := make(chan models.MailData)
-	app.MailChan = mailChan
+	app.MailQueued = make(chan struct{}, 1)
+	app.MailWorkers = 2
+	app.MailMaxAttempts = 5
 
 	app.InProduction = false
+	app.DBTimeout = 3 * time.Second
+	app.HoldMinutes = 15
+	app.BaseURL = "http://localhost" + portNumber
+	// manage booking links are signed with this key, so a known default
+	// would let anyone change other guests' bookings
+	app.SigningKey = os.Getenv("SIGNING_KEY")
+	if app.SigningKey == "" {
+		return nil, errors.New("SIGNING_KEY must be set")
+	}
+	app.CancellationDays = 2
+	app.BookingHorizonDays = 365
+	app.Hotel = models.Hotel{
+		Name:    "Hotel Booking",
+		Address: "1 Main Street, Springfield",
+		Email:   "hotel-booking@mail.com",
+		Phone:   "+1 555 0100",
+	}
+
+	// webhooks signed with this secret confirm bookings as paid
+	webhookSecret := os.Getenv("PAYMENT_WEBHOOK_SECRET")
+	if webhookSecret == "" {
+		return nil, errors.New("PAYMENT_WEBHOOK_SECRET must be set")
+	}
+	app.Payments = payments.NewFakeGateway(webhookSecret)
+	app.PaymentMode = models.PaymentDeposit
+	app.DepositPercent = 30
+	app.PaymentMinutes = 30
+
+	smtpPort, err := strconv.Atoi(getEnv("SMTP_PORT", "1025"))
+	if err != nil {
+		return nil, fmt.Errorf("SMTP_PORT: %w", err)
+	}
+	app.MailFrom = getEnv("MAIL_FROM", "hotel-booking@mail.com")
+	app.Mail = mailer.Config{
+		Transport: getEnv("MAIL_TRANSPORT", mailer.TransportSMTP),
+		SMTP: mailer.SMTPConfig{
+			Host:       getEnv("SMTP_HOST", "localhost"),
+			Port:       smtpPort,
+			Username:   os.Getenv("SMTP_USERNAME"),
+			Password:   os.Getenv("SMTP_PASSWORD"),
+			Encryption: getEnv("SMTP_ENCRYPTION", mailer.EncryptionNone),
+		},
+		Dir: getEnv("MAIL_DIR", "./tmp/mail"),
+	}
+	app.Mailer, err = mailer.New(app.Mail)
+	if err != nil {
+		return nil, err
+	}
 
 	infoLog = log.New(os.Stdout, "INFO\t", log.Ldate|log.Ltime)
 	errorLog = log.New(os.Stdout, "ERROR\t ", log.Ldate|log.Ltime|log.Lshortfile)
@@ -101,3 +181,11 @@ func run() (*driver.DB, error) {
 
 	return db, nil
 }
+
+// getEnv returns the environment variable key, or fallback when it is unset.
+func getEnv(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
+}
@@ -15,6 +15,7 @@ func NoSurf(next http.Handler) http.Handler {
 		Secure:   app.InProduction,
 		SameSite: http.SameSiteLaxMode,
 	})
+	csrfHandle.ExemptPath("/payments/webhook")
 	return csrfHandle
 }
 
@@ -19,13 +19,19 @@ func routes(app *config.AppConfig) http.Handler {
 
 	mux.Get("/", http.HandlerFunc(handlers.Repo.Home))
 	mux.Get("/about", http.HandlerFunc(handlers.Repo.About))
-	mux.Get("/generals-quarters", handlers.Repo.Generals)
-	mux.Get("/majors-suite", handlers.Repo.Majors)
+	mux.Get("/rooms", handlers.Repo.Rooms)
+	mux.Get("/rooms/{slug}", handlers.Repo.ShowRoom)
+	mux.Handle("/generals-quarters", http.RedirectHandler("/rooms/generals-quarters", http.StatusMovedPermanently))
+	mux.Handle("/majors-suite", http.RedirectHandler("/rooms/majors-suite", http.StatusMovedPermanently))
 
 	mux.Get("/search-availability", handlers.Repo.Availability)
 	mux.Post("/search-availability-json", handlers.Repo.AvailabilityJson)
 	mux.Post("/search-availability", handlers.Repo.PostAvailability)
+	mux.Get("/choose-room", handlers.Repo.ChooseRooms)
 	mux.Get("/choose-room/{id}", handlers.Repo.ChooseRoom)
+	mux.Get("/choose-combination", handlers.Repo.ChooseCombination)
+	mux.Get("/add-room", handlers.Repo.AddCartRoom)
+	mux.Get("/remove-room/{index}", handlers.Repo.RemoveCartRoom)
 	mux.Get("/book-room", handlers.Repo.BookRoom)
 
 	mux.Get("/contact", handlers.Repo.Contact)
@@ -37,6 +43,16 @@ func routes(app *config.AppConfig) http.Handler {
 	mux.Get("/make-reservation", handlers.Repo.Reservation)
 	mux.Get("/reservation-summary", handlers.Repo.ReservationSummary)
 	mux.Post("/make-reservation", handlers.Repo.PostReservation)
+	mux.Post("/make-reservation/promo", handlers.Repo.PostReservationPromo)
+	mux.Get("/payment", handlers.Repo.Payment)
+	mux.Post("/payment", handlers.Repo.PostPayment)
+	mux.Post("/payments/webhook", handlers.Repo.PaymentWebhook)
+
+	mux.Get("/manage-booking/{code}/{signature}", handlers.Repo.ManageBooking)
+	mux.Post("/manage-booking/{code}/{signature}/cancel", handlers.Repo.PostCancelBooking)
+	mux.Post("/manage-booking/{code}/{signature}/rooms/{id}/cancel", handlers.Repo.PostCancelBookingRoom)
+	mux.Post("/manage-booking/{code}/{signature}/change-dates", handlers.Repo.PostChangeBookingDates)
+	mux.Get("/manage-booking/{code}/{signature}/invoice", handlers.Repo.BookingInvoice)
 
 	fileServer := http.FileServer(http.Dir("./static/"))
 	mux.Handle("/static/*", http.StripPrefix("/static", fileServer))
@@ -48,11 +64,60 @@ func routes(app *config.AppConfig) http.Handler {
 		r.Get("/reservations-new", handlers.Repo.AdminNewReservations)
 		r.Get("/reservations-all", handlers.Repo.AdminAllReservations)
 		r.Get("/reservations-calendar", handlers.Repo.AdminReservationsCalendar)
-		r.Get("/process-reservation/{src}/{id}", handlers.Repo.AdminProcessReservation)
+		r.Post("/reservations-calendar", handlers.Repo.AdminPostReservationsCalendar)
+		r.Get("/reservations-trash", handlers.Repo.AdminTrashReservations)
+		r.Get("/reservation-status/{src}/{id}/{status}", handlers.Repo.AdminUpdateReservationStatus)
 		r.Get("/delete-reservation/{src}/{id}", handlers.Repo.AdminDeleteReservation)
+		r.Get("/restore-reservation/{src}/{id}", handlers.Repo.AdminRestoreReservation)
 
 		r.Get("/reservations/{src}/{id}", handlers.Repo.AdminShowReservation)
 		r.Post("/reservations/{src}/{id}", handlers.Repo.AdminPostShowReservation)
+		r.Post("/reservations/{src}/{id}/room", handlers.Repo.AdminAssignReservationRoom)
+		r.Post("/reservations/{src}/{id}/refund", handlers.Repo.AdminPostRefund)
+		r.Get("/reservations/{src}/{id}/invoice", handlers.Repo.AdminReservationInvoice)
+		r.Get("/reservations/{src}/{id}/history", handlers.Repo.AdminReservationHistory)
+
+		r.Get("/cancellation-policies", handlers.Repo.AdminCancellationPolicies)
+		r.Post("/cancellation-policies", handlers.Repo.AdminPostCancellationPolicy)
+		r.Post("/cancellation-policies/room-types", handlers.Repo.AdminPostRoomTypeCancellationPolicy)
+
+		r.Get("/rooms", handlers.Repo.AdminRooms)
+		r.Post("/rooms", handlers.Repo.AdminPostRoom)
+		r.Get("/move-room/{id}/{direction}", handlers.Repo.AdminMoveRoom)
+
+		r.Get("/restrictions", handlers.Repo.AdminRestrictions)
+		r.Post("/restrictions", handlers.Repo.AdminPostRestriction)
+		r.Get("/delete-restriction/{id}", handlers.Repo.AdminDeleteRestriction)
+
+		r.Get("/room-types", handlers.Repo.AdminRoomTypes)
+		r.Get("/room-types/new", handlers.Repo.AdminNewRoomType)
+		r.Post("/room-types/new", handlers.Repo.AdminPostRoomType)
+		r.Get("/room-types/{id}", handlers.Repo.AdminShowRoomType)
+		r.Post("/room-types/{id}", handlers.Repo.AdminPostRoomType)
+		r.Get("/delete-room-type/{id}", handlers.Repo.AdminDeleteRoomType)
+
+		r.Get("/room-rates", handlers.Repo.AdminRoomRates)
+		r.Post("/room-rates", handlers.Repo.AdminPostRoomRate)
+		r.Post("/room-rates/base", handlers.Repo.AdminPostRoomTypeBaseRate)
+		r.Get("/delete-room-rate/{id}", handlers.Repo.AdminDeleteRoomRate)
+
+		r.Get("/stay-rules", handlers.Repo.AdminStayRules)
+		r.Post("/stay-rules", handlers.Repo.AdminPostStayRule)
+		r.Get("/delete-stay-rule/{id}", handlers.Repo.AdminDeleteStayRule)
+
+		r.Get("/promo-codes", handlers.Repo.AdminPromoCodes)
+		r.Get("/promo-codes/new", handlers.Repo.AdminNewPromoCode)
+		r.Post("/promo-codes/new", handlers.Repo.AdminPostPromoCode)
+		r.Get("/promo-codes/{id}", handlers.Repo.AdminShowPromoCode)
+		r.Post("/promo-codes/{id}", handlers.Repo.AdminPostPromoCode)
+		r.Get("/delete-promo-code/{id}", handlers.Repo.AdminDeletePromoCode)
+
+		r.Get("/taxes-fees", handlers.Repo.AdminTaxFees)
+		r.Post("/taxes-fees", handlers.Repo.AdminPostTaxFee)
+		r.Get("/delete-tax-fee/{id}", handlers.Repo.AdminDeleteTaxFee)
+
+		r.Get("/mail-outbox", handlers.Repo.AdminMailOutbox)
+		r.Post("/mail-outbox/{id}/resend", handlers.Repo.AdminResendMail)
 	})
 	return mux
 }
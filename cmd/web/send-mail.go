@@ -1,57 +1,123 @@
 package main
 
 import (
-	"fmt"
-	"io/ioutil"
-	"log"
-	"strings"
+	"context"
+	"database/sql"
+	"errors"
+	"sync"
 	"time"
 
+	"github.com/NhanNT-VNG/hotel-booking/internal/handlers"
 	"github.com/NhanNT-VNG/hotel-booking/internal/models"
-	mail "github.com/xhit/go-simple-mail/v2"
 )
 
+const (
+	// mailPollInterval is how often idle workers look for mail that is due
+	// for another attempt.
+	mailPollInterval = 30 * time.Second
+	// mailLease is how long a mail is held by the worker sending it before
+	// another worker may pick it up.
+	mailLease         = 2 * time.Minute
+	mailRetryDelay    = time.Minute
+	mailMaxRetryDelay = time.Hour
+)
+
+var mailStop = make(chan struct{})
+var mailWorkers sync.WaitGroup
+
+// listenForMail starts the workers that send the mail in the outbox.
 func listenForMail() {
+	for i := 0; i < app.MailWorkers; i++ {
+		mailWorkers.Add(1)
+		go func() {
+			defer mailWorkers.Done()
+
+			ticker := time.NewTicker(mailPollInterval)
+			defer ticker.Stop()
+
+			for {
+				for deliverMail() {
+				}
+
+				select {
+				case <-mailStop:
+					for deliverMail() {
+					}
+					return
+				case <-app.MailQueued:
+				case <-ticker.C:
+				}
+			}
+		}()
+	}
+}
+
+// drainMail stops the mail workers once they have sent the mail that is due.
+// Mail left in the outbox when ctx is done is sent on the next start.
+func drainMail(ctx context.Context) {
+	close(mailStop)
+
+	done := make(chan struct{})
 	go func() {
-		for {
-			msg := <-app.MailChan
-			sendMsg(msg)
-		}
+		mailWorkers.Wait()
+		close(done)
 	}()
+
+	select {
+	case <-done:
+	case <-ctx.Done():
+		app.ErrorLog.Println("Mail outbox not drained:", ctx.Err())
+	}
 }
 
-func sendMsg(m models.MailData) {
-	server := mail.NewSMTPClient()
-	server.Host = "localhost"
-	server.Port = 1025
-	server.KeepAlive = false
-	server.ConnectTimeout = 10 * time.Second
-	server.SendTimeout = 10 * time.Second
+// deliverMail sends the next mail that is due and reports whether there was
+// one. A mail that cannot be sent is retried with exponential backoff until
+// it runs out of attempts and is marked as failed.
+func deliverMail() bool {
+	ctx := context.Background()
 
-	client, err := server.Connect()
+	outboxMail, err := handlers.Repo.DB.ClaimMail(ctx, mailLease)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false
+	}
 	if err != nil {
-		errorLog.Println(err)
+		app.ErrorLog.Println(err)
+		return false
 	}
-	email := mail.NewMSG()
-	email.SetFrom(m.From).AddTo(m.To).SetSubject(m.Subject)
 
-	if m.Template == "" {
-		email.SetBody(mail.TextHTML, m.Content)
-	} else {
-		data, err := ioutil.ReadFile(fmt.Sprintf("./email-templates/%s", m.Template))
-		if err != nil {
-			app.ErrorLog.Println(err)
-		}
-		mailTemplate := string(data)
-		msgToSend := strings.Replace(mailTemplate, "[%body%]", m.Content, 1)
-		email.SetBody(mail.TextHTML, msgToSend)
+	err = app.Mailer.Send(ctx, outboxMail.Mail)
+	switch {
+	case err == nil:
+		outboxMail.Status = models.MailSent
+		outboxMail.SentAt = time.Now()
+		outboxMail.LastError = ""
+		app.InfoLog.Println("Mail sent to", outboxMail.Mail.To)
+	case outboxMail.Attempts >= app.MailMaxAttempts:
+		outboxMail.Status = models.MailFailed
+		outboxMail.LastError = err.Error()
+		app.ErrorLog.Printf("Mail %d to %s failed after %d attempts: %v", outboxMail.ID, outboxMail.Mail.To, outboxMail.Attempts, err)
+	default:
+		outboxMail.LastError = err.Error()
+		outboxMail.NextAttemptAt = time.Now().Add(mailBackoff(outboxMail.Attempts))
+		app.ErrorLog.Printf("Mail %d to %s failed, retrying at %s: %v", outboxMail.ID, outboxMail.Mail.To, outboxMail.NextAttemptAt.Format("15:04:05"), err)
 	}
 
-	err = email.Send(client)
-
+	err = handlers.Repo.DB.UpdateOutboxMail(ctx, outboxMail)
 	if err != nil {
-		log.Panicln(err)
-	} else {
-		log.Println("Mail send!")
+		app.ErrorLog.Println(err)
+	}
+	return true
+}
+
+// mailBackoff is how long to wait before the next attempt after the given
+// number of failed attempts.
+func mailBackoff(attempts int) time.Duration {
+	delay := mailRetryDelay
+	for i := 1; i < attempts && delay < mailMaxRetryDelay; i++ {
+		delay *= 2
+	}
+	if delay > mailMaxRetryDelay {
+		delay = mailMaxRetryDelay
 	}
+	return delay
 }
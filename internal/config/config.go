@@ -3,8 +3,11 @@ package config
 import (
 	"html/template"
 	"log"
+	"time"
 
+	"github.com/NhanNT-VNG/hotel-booking/internal/mailer"
 	"github.com/NhanNT-VNG/hotel-booking/internal/models"
+	"github.com/NhanNT-VNG/hotel-booking/internal/payments"
 	"github.com/alexedwards/scs/v2"
 )
 
@@ -15,5 +18,25 @@ type AppConfig struct {
 	ErrorLog      *log.Logger
 	InProduction  bool
 	Session       *scs.SessionManager
-	MailChan      chan models.MailData
+	MailQueued    chan struct{}
+	DBTimeout     time.Duration
+	HoldMinutes   int
+	BaseURL       string
+	SigningKey    string
+
+	CancellationDays   int
+	BookingHorizonDays int
+
+	Hotel models.Hotel
+
+	MailWorkers     int
+	MailMaxAttempts int
+	MailFrom        string
+	Mail            mailer.Config
+	Mailer          mailer.Mailer
+
+	Payments       payments.PaymentGateway
+	PaymentMode    string
+	DepositPercent int
+	PaymentMinutes int
 }
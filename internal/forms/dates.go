@@ -0,0 +1,68 @@
+package forms
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// DateLayout is the format used by the date pickers
+const DateLayout = "2006-01-02"
+
+// ValidateDateRange parses a stay from start to end and checks that it starts
+// today or later, ends after it starts and ends within horizonDays from today.
+// A horizon of zero or less means there is no limit.
+func ValidateDateRange(start, end string, horizonDays int) (time.Time, time.Time, errors) {
+	errs := errors(map[string][]string{})
+
+	startDate, err := time.Parse(DateLayout, start)
+	if err != nil {
+		errs.Add("start", "Invalid arrival date")
+	}
+	endDate, err := time.Parse(DateLayout, end)
+	if err != nil {
+		errs.Add("end", "Invalid departure date")
+	}
+	if len(errs) > 0 {
+		return startDate, endDate, errs
+	}
+
+	today := time.Now().Truncate(24 * time.Hour)
+	if startDate.Before(today) {
+		errs.Add("start", "Arrival date cannot be in the past")
+	}
+	if !endDate.After(startDate) {
+		errs.Add("end", "Departure date must be after arrival date")
+	}
+	if horizonDays > 0 && endDate.After(today.AddDate(0, 0, horizonDays)) {
+		errs.Add("end", fmt.Sprintf("Bookings can only be made up to %d days ahead", horizonDays))
+	}
+
+	return startDate, endDate, errs
+}
+
+// DateRange validates the stay given by the start and end fields
+func (f *Form) DateRange(startField, endField string, horizonDays int) (time.Time, time.Time) {
+	startDate, endDate, errs := ValidateDateRange(f.Get(startField), f.Get(endField), horizonDays)
+	for _, message := range errs["start"] {
+		f.Errors.Add(startField, message)
+	}
+	for _, message := range errs["end"] {
+		f.Errors.Add(endField, message)
+	}
+	return startDate, endDate
+}
+
+// Date returns the date in field, or the zero time when the field is empty
+func (f *Form) Date(field string) time.Time {
+	x := strings.TrimSpace(f.Get(field))
+	if x == "" {
+		return time.Time{}
+	}
+	date, err := time.Parse(DateLayout, x)
+	if err != nil {
+		f.Errors.Add(field, "Invalid date")
+		return time.Time{}
+	}
+	return date
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"net/http"
 	"net/url"
+	"strconv"
 	"strings"
 
 	"github.com/asaskevich/govalidator"
@@ -58,3 +59,17 @@ func (f *Form) IsEmail(field string) {
 		f.Errors.Add(field, "Invalid email address")
 	}
 }
+
+// Int returns the whole number in field, or fallback when the field is empty
+func (f *Form) Int(field string, min, max, fallback int) int {
+	x := strings.TrimSpace(f.Get(field))
+	if x == "" {
+		return fallback
+	}
+	n, err := strconv.Atoi(x)
+	if err != nil || n < min || n > max {
+		f.Errors.Add(field, fmt.Sprintf("This field must be a number from %d to %d", min, max))
+		return fallback
+	}
+	return n
+}
@@ -0,0 +1,203 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/NhanNT-VNG/hotel-booking/internal/helpers"
+	"github.com/NhanNT-VNG/hotel-booking/internal/models"
+	"github.com/NhanNT-VNG/hotel-booking/internal/render"
+	"github.com/NhanNT-VNG/hotel-booking/internal/repository"
+)
+
+type calendarDay struct {
+	Date        string
+	Day         int
+	Restriction models.RoomRestriction
+}
+
+type calendarRoom struct {
+	Room models.Room
+	Days []calendarDay
+}
+
+func calendarMonth(r *http.Request) time.Time {
+	now := time.Now()
+	if r.URL.Query().Get("y") != "" {
+		year, _ := strconv.Atoi(r.URL.Query().Get("y"))
+		month, _ := strconv.Atoi(r.URL.Query().Get("m"))
+		now = time.Date(year, time.Month(month), 1, 0, 0, 0, 0, time.UTC)
+	}
+
+	year, month, _ := now.Date()
+	return time.Date(year, month, 1, 0, 0, 0, 0, time.UTC)
+}
+
+func (repo *Repository) AdminReservationsCalendar(w http.ResponseWriter, r *http.Request) {
+	firstOfMonth := calendarMonth(r)
+	lastOfMonth := firstOfMonth.AddDate(0, 1, -1)
+
+	next := firstOfMonth.AddDate(0, 1, 0)
+	last := firstOfMonth.AddDate(0, -1, 0)
+
+	stringMap := make(map[string]string)
+
+	stringMap["next_month"] = next.Format("01")
+	stringMap["next_month_year"] = next.Format("2006")
+
+	stringMap["last_month"] = last.Format("01")
+	stringMap["last_month_year"] = last.Format("2006")
+
+	stringMap["this_month"] = firstOfMonth.Format("01")
+	stringMap["this_month_year"] = firstOfMonth.Format("2006")
+
+	rooms, err := repo.DB.AllRooms(r.Context())
+	if err != nil {
+		helpers.ServerError(w, err)
+		return
+	}
+
+	var calendar []calendarRoom
+	for _, room := range rooms {
+		restrictions, err := repo.DB.RestrictionsForRoomByDate(r.Context(), room.ID, firstOfMonth, lastOfMonth.AddDate(0, 0, 1))
+		if err != nil {
+			helpers.ServerError(w, err)
+			return
+		}
+
+		item := calendarRoom{Room: room}
+		for day := firstOfMonth; !day.After(lastOfMonth); day = day.AddDate(0, 0, 1) {
+			calDay := calendarDay{
+				Date: day.Format("2006-01-02"),
+				Day:  day.Day(),
+			}
+			for _, rr := range restrictions {
+				if !day.Before(rr.StartDate) && day.Before(rr.EndDate) {
+					calDay.Restriction = rr
+				}
+			}
+			item.Days = append(item.Days, calDay)
+		}
+		calendar = append(calendar, item)
+	}
+
+	allRestrictions, err := repo.DB.AllRestrictions(r.Context())
+	if err != nil {
+		helpers.ServerError(w, err)
+		return
+	}
+
+	var blockTypes []models.Restriction
+	for _, restriction := range allRestrictions {
+		if restriction.ID != models.RestrictionReservation && restriction.ID != models.RestrictionHold {
+			blockTypes = append(blockTypes, restriction)
+		}
+	}
+
+	data := make(map[string]interface{})
+	data["now"] = firstOfMonth
+	data["calendar"] = calendar
+	data["restrictions"] = allRestrictions
+	data["block_types"] = blockTypes
+
+	render.RenderTemplate(w, r, "admin-reservations-calendar.page.html", &models.TemplateData{
+		StringMap: stringMap,
+		Data:      data,
+	})
+}
+
+// AdminPostReservationsCalendar applies the block checkboxes of the calendar.
+// Each checked day without a block gets a one-night block of the selected
+// type; unchecking a day removes the whole block covering it.
+func (repo *Repository) AdminPostReservationsCalendar(w http.ResponseWriter, r *http.Request) {
+	err := r.ParseForm()
+	if err != nil {
+		helpers.ServerError(w, err)
+		return
+	}
+
+	year, _ := strconv.Atoi(r.Form.Get("y"))
+	month, _ := strconv.Atoi(r.Form.Get("m"))
+	calendarURL := fmt.Sprintf("/admin/reservations-calendar?y=%d&m=%02d", year, month)
+
+	restrictionId, _ := strconv.Atoi(r.Form.Get("restriction_id"))
+	if restrictionId == models.RestrictionReservation || restrictionId == models.RestrictionHold {
+		restrictionId = models.RestrictionOwnerBlock
+	}
+	if _, err = repo.DB.GetRestrictionById(r.Context(), restrictionId); err != nil {
+		repo.App.Session.Put(r.Context(), "error", "Invalid block type")
+		http.Redirect(w, r, calendarURL, http.StatusSeeOther)
+		return
+	}
+
+	deleted := make(map[int]bool)
+	for key := range r.PostForm {
+		if !strings.HasPrefix(key, "existing_") {
+			continue
+		}
+
+		day := strings.TrimPrefix(key, "existing_")
+		blockId, _ := strconv.Atoi(r.PostForm.Get(key))
+		if r.PostForm.Get("block_"+day) != "" || deleted[blockId] {
+			continue
+		}
+
+		if err = repo.DB.DeleteBlockById(r.Context(), blockId); err != nil {
+			helpers.ServerError(w, err)
+			return
+		}
+		deleted[blockId] = true
+	}
+
+	layout := "2006-01-02"
+	var collisions []string
+	for key := range r.PostForm {
+		if !strings.HasPrefix(key, "block_") {
+			continue
+		}
+
+		day := strings.TrimPrefix(key, "block_")
+		if r.PostForm.Get("existing_"+day) != "" {
+			continue
+		}
+
+		parts := strings.SplitN(day, "_", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		roomId, errRoom := strconv.Atoi(parts[0])
+		date, errDate := time.Parse(layout, parts[1])
+		if errRoom != nil || errDate != nil {
+			continue
+		}
+
+		err = repo.DB.InsertRoomRestrictions(r.Context(), models.RoomRestriction{
+			StartDate:     date,
+			EndDate:       date.AddDate(0, 0, 1),
+			RoomId:        roomId,
+			RestrictionId: restrictionId,
+		})
+		if errors.Is(err, repository.ErrRoomUnavailable) {
+			collisions = append(collisions, date.Format(layout))
+			continue
+		}
+		if err != nil {
+			helpers.ServerError(w, err)
+			return
+		}
+	}
+
+	if len(collisions) > 0 {
+		sort.Strings(collisions)
+		repo.App.Session.Put(r.Context(), "error",
+			fmt.Sprintf("These days are already taken and were not blocked: %s", strings.Join(collisions, ", ")))
+	} else {
+		repo.App.Session.Put(r.Context(), "flash", "Changes saved")
+	}
+	http.Redirect(w, r, calendarURL, http.StatusSeeOther)
+}
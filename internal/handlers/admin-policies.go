@@ -0,0 +1,125 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/NhanNT-VNG/hotel-booking/internal/helpers"
+	"github.com/NhanNT-VNG/hotel-booking/internal/models"
+	"github.com/NhanNT-VNG/hotel-booking/internal/render"
+)
+
+func (repo *Repository) AdminCancellationPolicies(w http.ResponseWriter, r *http.Request) {
+	policies, err := repo.DB.AllCancellationPolicies(r.Context())
+	if err != nil {
+		helpers.ServerError(w, err)
+		return
+	}
+
+	roomTypes, err := repo.DB.AllRoomTypes(r.Context())
+	if err != nil {
+		helpers.ServerError(w, err)
+		return
+	}
+
+	data := make(map[string]interface{})
+	data["policies"] = policies
+	data["room_types"] = roomTypes
+	data["penalty_types"] = models.PenaltyTypes
+
+	render.RenderTemplate(w, r, "admin-cancellation-policies.page.html", &models.TemplateData{
+		Data: data,
+	})
+}
+
+func (repo *Repository) AdminPostCancellationPolicy(w http.ResponseWriter, r *http.Request) {
+	err := r.ParseForm()
+	if err != nil {
+		helpers.ServerError(w, err)
+		return
+	}
+
+	id, _ := strconv.Atoi(r.Form.Get("id"))
+	freeDays, errDays := strconv.Atoi(r.Form.Get("free_cancellation_days"))
+	percent, errPercent := strconv.Atoi(r.Form.Get("penalty_percent"))
+
+	policy := models.CancellationPolicy{
+		ID:                   id,
+		Name:                 r.Form.Get("name"),
+		FreeCancellationDays: freeDays,
+		PenaltyType:          r.Form.Get("penalty_type"),
+		PenaltyPercent:       percent,
+	}
+
+	var msg string
+	switch {
+	case policy.Name == "":
+		msg = "Policy name cannot be empty"
+	case errDays != nil || freeDays < 0:
+		msg = "Free cancellation days must be a positive number"
+	case !models.IsValidPenaltyType(policy.PenaltyType):
+		msg = "Invalid penalty type"
+	case policy.PenaltyType == models.PenaltyPercent && (errPercent != nil || percent < 0 || percent > 100):
+		msg = "Penalty percent must be between 0 and 100"
+	}
+	if msg != "" {
+		repo.App.Session.Put(r.Context(), "error", msg)
+		http.Redirect(w, r, "/admin/cancellation-policies", http.StatusSeeOther)
+		return
+	}
+
+	if policy.PenaltyType != models.PenaltyPercent {
+		policy.PenaltyPercent = 0
+	}
+
+	var before models.CancellationPolicy
+	action := models.AuditActionCreate
+	if policy.ID > 0 {
+		action = models.AuditActionUpdate
+		before, err = repo.DB.GetCancellationPolicyById(r.Context(), policy.ID)
+		if err == nil {
+			err = repo.DB.UpdateCancellationPolicy(r.Context(), policy)
+		}
+	} else {
+		policy.ID, err = repo.DB.InsertCancellationPolicy(r.Context(), policy)
+	}
+	if err != nil {
+		helpers.ServerError(w, err)
+		return
+	}
+
+	repo.recordAudit(r, action, models.AuditEntityCancellationPolicy, policy.ID, before, policy)
+
+	repo.App.Session.Put(r.Context(), "flash", "Cancellation policy saved")
+	http.Redirect(w, r, "/admin/cancellation-policies", http.StatusSeeOther)
+}
+
+func (repo *Repository) AdminPostRoomTypeCancellationPolicy(w http.ResponseWriter, r *http.Request) {
+	err := r.ParseForm()
+	if err != nil {
+		helpers.ServerError(w, err)
+		return
+	}
+
+	roomTypeId, _ := strconv.Atoi(r.Form.Get("room_type_id"))
+	policyId, _ := strconv.Atoi(r.Form.Get("policy_id"))
+
+	before, err := repo.DB.GetRoomTypeById(r.Context(), roomTypeId)
+	if err != nil {
+		helpers.ServerError(w, err)
+		return
+	}
+
+	err = repo.DB.UpdateRoomTypeCancellationPolicy(r.Context(), roomTypeId, policyId)
+	if err != nil {
+		helpers.ServerError(w, err)
+		return
+	}
+
+	after := before
+	after.CancellationPolicyId = policyId
+	repo.recordAudit(r, models.AuditActionUpdate, models.AuditEntityRoomType, roomTypeId, before, after)
+
+	repo.App.Session.Put(r.Context(), "flash", "Room type cancellation policy saved")
+	http.Redirect(w, r, "/admin/cancellation-policies", http.StatusSeeOther)
+}
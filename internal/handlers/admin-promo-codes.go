@@ -0,0 +1,209 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/NhanNT-VNG/hotel-booking/internal/forms"
+	"github.com/NhanNT-VNG/hotel-booking/internal/helpers"
+	"github.com/NhanNT-VNG/hotel-booking/internal/models"
+	"github.com/NhanNT-VNG/hotel-booking/internal/render"
+	"github.com/NhanNT-VNG/hotel-booking/internal/repository"
+	"github.com/go-chi/chi/v5"
+)
+
+func (repo *Repository) AdminPromoCodes(w http.ResponseWriter, r *http.Request) {
+	promos, err := repo.DB.AllPromoCodes(r.Context())
+	if err != nil {
+		helpers.ServerError(w, err)
+		return
+	}
+
+	data := make(map[string]interface{})
+	data["promo_codes"] = promos
+
+	render.RenderTemplate(w, r, "admin-promo-codes.page.html", &models.TemplateData{
+		Data: data,
+	})
+}
+
+func (repo *Repository) renderPromoCodeForm(w http.ResponseWriter, r *http.Request, promo models.PromoCode, form *forms.Form) {
+	roomTypes, err := repo.DB.AllRoomTypes(r.Context())
+	if err != nil {
+		helpers.ServerError(w, err)
+		return
+	}
+
+	data := make(map[string]interface{})
+	data["promo_code"] = promo
+	data["room_types"] = roomTypes
+	data["discount_types"] = models.DiscountTypes
+
+	stringMap := make(map[string]string)
+	stringMap["discount_value"] = strconv.Itoa(promo.DiscountValue)
+	if promo.DiscountType == models.DiscountFixed {
+		stringMap["discount_value"] = render.FormatAmount(promo.DiscountValue)
+	}
+	if !promo.StayStart.IsZero() {
+		stringMap["stay_start"] = promo.StayStart.Format(forms.DateLayout)
+	}
+	if !promo.StayEnd.IsZero() {
+		stringMap["stay_end"] = promo.StayEnd.Format(forms.DateLayout)
+	}
+	if !promo.BookStart.IsZero() {
+		stringMap["book_start"] = promo.BookStart.Format(forms.DateLayout)
+	}
+	if !promo.BookEnd.IsZero() {
+		stringMap["book_end"] = promo.BookEnd.Format(forms.DateLayout)
+	}
+
+	intMap := make(map[string]int)
+	for _, id := range promo.RoomTypeIds {
+		intMap[strconv.Itoa(id)] = 1
+	}
+
+	render.RenderTemplate(w, r, "admin-promo-code.page.html", &models.TemplateData{
+		Form:      form,
+		Data:      data,
+		StringMap: stringMap,
+		IntMap:    intMap,
+	})
+}
+
+func (repo *Repository) AdminNewPromoCode(w http.ResponseWriter, r *http.Request) {
+	promo := models.PromoCode{
+		DiscountType:  models.DiscountPercent,
+		DiscountValue: 10,
+		Active:        true,
+	}
+	repo.renderPromoCodeForm(w, r, promo, forms.New(nil))
+}
+
+func (repo *Repository) AdminShowPromoCode(w http.ResponseWriter, r *http.Request) {
+	id, _ := strconv.Atoi(chi.URLParam(r, "id"))
+
+	promo, err := repo.DB.GetPromoCodeById(r.Context(), id)
+	if err != nil {
+		helpers.ServerError(w, err)
+		return
+	}
+
+	repo.renderPromoCodeForm(w, r, promo, forms.New(nil))
+}
+
+func (repo *Repository) AdminPostPromoCode(w http.ResponseWriter, r *http.Request) {
+	err := r.ParseForm()
+	if err != nil {
+		helpers.ServerError(w, err)
+		return
+	}
+
+	id, _ := strconv.Atoi(chi.URLParam(r, "id"))
+
+	var before models.PromoCode
+	if id > 0 {
+		before, err = repo.DB.GetPromoCodeById(r.Context(), id)
+		if err != nil {
+			helpers.ServerError(w, err)
+			return
+		}
+	}
+
+	form := forms.New(r.PostForm)
+	form.Required("code")
+
+	promo := before
+	promo.Code = models.NormalizePromoCode(r.Form.Get("code"))
+	promo.Name = strings.TrimSpace(r.Form.Get("name"))
+	promo.DiscountType = r.Form.Get("discount_type")
+	promo.StayStart = form.Date("stay_start")
+	promo.StayEnd = form.Date("stay_end")
+	promo.BookStart = form.Date("book_start")
+	promo.BookEnd = form.Date("book_end")
+	promo.MinNights = form.Int("min_nights", 0, 365, 0)
+	promo.MaxUses = form.Int("max_uses", 0, 1000000, 0)
+	promo.Active = r.Form.Get("active") != ""
+
+	promo.RoomTypeIds = nil
+	for _, value := range r.Form["room_type_ids"] {
+		if roomTypeId, err := strconv.Atoi(value); err == nil {
+			promo.RoomTypeIds = append(promo.RoomTypeIds, roomTypeId)
+		}
+	}
+
+	if strings.ContainsAny(promo.Code, " \t") {
+		form.Errors.Add("code", "Codes cannot contain spaces")
+	}
+
+	switch promo.DiscountType {
+	case models.DiscountPercent:
+		promo.DiscountValue = form.Int("discount_value", 1, 100, 0)
+	case models.DiscountFixed:
+		promo.DiscountValue, err = helpers.ParseMoney(r.Form.Get("discount_value"))
+		if err != nil || promo.DiscountValue == 0 {
+			form.Errors.Add("discount_value", "Discount must be a positive amount")
+		}
+	default:
+		form.Errors.Add("discount_type", "Invalid discount type")
+	}
+
+	if !promo.StayStart.IsZero() && !promo.StayEnd.IsZero() && promo.StayEnd.Before(promo.StayStart) {
+		form.Errors.Add("stay_end", "End date must not be before start date")
+	}
+	if !promo.BookStart.IsZero() && !promo.BookEnd.IsZero() && promo.BookEnd.Before(promo.BookStart) {
+		form.Errors.Add("book_end", "End date must not be before start date")
+	}
+
+	if form.Valid() {
+		if id > 0 {
+			err = repo.DB.UpdatePromoCode(r.Context(), promo)
+		} else {
+			promo.ID, err = repo.DB.InsertPromoCode(r.Context(), promo)
+		}
+
+		if errors.Is(err, repository.ErrDuplicatePromoCode) {
+			form.Errors.Add("code", "This code already exists")
+		} else if err != nil {
+			helpers.ServerError(w, err)
+			return
+		}
+	}
+
+	if !form.Valid() {
+		repo.renderPromoCodeForm(w, r, promo, form)
+		return
+	}
+
+	if id > 0 {
+		repo.recordAudit(r, models.AuditActionUpdate, models.AuditEntityPromoCode, id, before, promo)
+	} else {
+		repo.recordAudit(r, models.AuditActionCreate, models.AuditEntityPromoCode, promo.ID, models.PromoCode{}, promo)
+	}
+
+	repo.App.Session.Put(r.Context(), "flash", fmt.Sprintf("Promo code %s saved", promo.Code))
+	http.Redirect(w, r, "/admin/promo-codes", http.StatusSeeOther)
+}
+
+func (repo *Repository) AdminDeletePromoCode(w http.ResponseWriter, r *http.Request) {
+	id, _ := strconv.Atoi(chi.URLParam(r, "id"))
+
+	before, err := repo.DB.GetPromoCodeById(r.Context(), id)
+	if err != nil {
+		helpers.ServerError(w, err)
+		return
+	}
+
+	err = repo.DB.DeletePromoCode(r.Context(), id)
+	if err != nil {
+		helpers.ServerError(w, err)
+		return
+	}
+
+	repo.recordAudit(r, models.AuditActionDelete, models.AuditEntityPromoCode, id, before, models.PromoCode{})
+
+	repo.App.Session.Put(r.Context(), "flash", fmt.Sprintf("Promo code %s deleted", before.Code))
+	http.Redirect(w, r, "/admin/promo-codes", http.StatusSeeOther)
+}
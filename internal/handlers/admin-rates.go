@@ -0,0 +1,148 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/NhanNT-VNG/hotel-booking/internal/helpers"
+	"github.com/NhanNT-VNG/hotel-booking/internal/models"
+	"github.com/NhanNT-VNG/hotel-booking/internal/render"
+	"github.com/go-chi/chi/v5"
+)
+
+func (repo *Repository) AdminRoomRates(w http.ResponseWriter, r *http.Request) {
+	roomTypes, err := repo.DB.AllRoomTypes(r.Context())
+	if err != nil {
+		helpers.ServerError(w, err)
+		return
+	}
+
+	rates, err := repo.DB.AllRoomRates(r.Context())
+	if err != nil {
+		helpers.ServerError(w, err)
+		return
+	}
+
+	data := make(map[string]interface{})
+	data["room_types"] = roomTypes
+	data["rates"] = rates
+	data["weekdays"] = models.Weekdays
+
+	render.RenderTemplate(w, r, "admin-room-rates.page.html", &models.TemplateData{
+		Data: data,
+	})
+}
+
+func (repo *Repository) AdminPostRoomTypeBaseRate(w http.ResponseWriter, r *http.Request) {
+	err := r.ParseForm()
+	if err != nil {
+		helpers.ServerError(w, err)
+		return
+	}
+
+	roomTypeId, _ := strconv.Atoi(r.Form.Get("room_type_id"))
+	rate, err := helpers.ParseMoney(r.Form.Get("base_rate"))
+	if err != nil {
+		repo.App.Session.Put(r.Context(), "error", "Base rate must be a positive amount")
+		http.Redirect(w, r, "/admin/room-rates", http.StatusSeeOther)
+		return
+	}
+
+	before, err := repo.DB.GetRoomTypeById(r.Context(), roomTypeId)
+	if err != nil {
+		helpers.ServerError(w, err)
+		return
+	}
+
+	err = repo.DB.UpdateRoomTypeBaseRate(r.Context(), roomTypeId, rate)
+	if err != nil {
+		helpers.ServerError(w, err)
+		return
+	}
+
+	after := before
+	after.BaseRate = rate
+	repo.recordAudit(r, models.AuditActionUpdate, models.AuditEntityRoomType, roomTypeId, before, after)
+
+	repo.App.Session.Put(r.Context(), "flash", "Base rate saved")
+	http.Redirect(w, r, "/admin/room-rates", http.StatusSeeOther)
+}
+
+func (repo *Repository) AdminPostRoomRate(w http.ResponseWriter, r *http.Request) {
+	err := r.ParseForm()
+	if err != nil {
+		helpers.ServerError(w, err)
+		return
+	}
+
+	layout := "2006-01-02"
+	roomTypeId, _ := strconv.Atoi(r.Form.Get("room_type_id"))
+	startDate, errStart := time.Parse(layout, r.Form.Get("start"))
+	endDate, errEnd := time.Parse(layout, r.Form.Get("end"))
+	rate, errRate := helpers.ParseMoney(r.Form.Get("rate"))
+
+	var days []time.Weekday
+	for _, value := range r.Form["weekdays"] {
+		day, err := strconv.Atoi(value)
+		if err == nil && day >= 0 && day <= 6 {
+			days = append(days, time.Weekday(day))
+		}
+	}
+
+	roomRate := models.RoomRate{
+		RoomTypeId: roomTypeId,
+		Name:       r.Form.Get("name"),
+		StartDate:  startDate,
+		EndDate:    endDate,
+		Weekdays:   models.WeekdayMask(days...),
+		Rate:       rate,
+	}
+
+	var msg string
+	switch {
+	case errStart != nil || errEnd != nil:
+		msg = "Invalid dates"
+	case endDate.Before(startDate):
+		msg = "End date must not be before start date"
+	case errRate != nil:
+		msg = "Rate must be a positive amount"
+	}
+	if msg != "" {
+		repo.App.Session.Put(r.Context(), "error", msg)
+		http.Redirect(w, r, "/admin/room-rates", http.StatusSeeOther)
+		return
+	}
+
+	roomRate.ID, err = repo.DB.InsertRoomRate(r.Context(), roomRate)
+	if err != nil {
+		helpers.ServerError(w, err)
+		return
+	}
+
+	repo.recordAudit(r, models.AuditActionCreate, models.AuditEntityRoomRate, roomRate.ID, models.RoomRate{}, roomRate)
+
+	repo.App.Session.Put(r.Context(), "flash", "Rate override saved")
+	http.Redirect(w, r, "/admin/room-rates", http.StatusSeeOther)
+}
+
+func (repo *Repository) AdminDeleteRoomRate(w http.ResponseWriter, r *http.Request) {
+	id, _ := strconv.Atoi(chi.URLParam(r, "id"))
+
+	before, err := repo.DB.GetRoomRateById(r.Context(), id)
+	if err != nil {
+		helpers.ServerError(w, err)
+		return
+	}
+
+	err = repo.DB.DeleteRoomRate(r.Context(), id)
+	if err != nil {
+		helpers.ServerError(w, err)
+		return
+	}
+
+	repo.recordAudit(r, models.AuditActionDelete, models.AuditEntityRoomRate, id, before, models.RoomRate{})
+
+	repo.App.Session.Put(r.Context(), "flash", "Rate override deleted")
+	http.Redirect(w, r, "/admin/room-rates", http.StatusSeeOther)
+}
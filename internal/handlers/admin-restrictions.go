@@ -0,0 +1,109 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/NhanNT-VNG/hotel-booking/internal/helpers"
+	"github.com/NhanNT-VNG/hotel-booking/internal/models"
+	"github.com/NhanNT-VNG/hotel-booking/internal/render"
+	"github.com/NhanNT-VNG/hotel-booking/internal/repository"
+	"github.com/go-chi/chi/v5"
+)
+
+var colorPattern = regexp.MustCompile(`^#[0-9a-fA-F]{6}$`)
+
+func (repo *Repository) AdminRestrictions(w http.ResponseWriter, r *http.Request) {
+	restrictions, err := repo.DB.AllRestrictions(r.Context())
+	if err != nil {
+		helpers.ServerError(w, err)
+		return
+	}
+
+	data := make(map[string]interface{})
+	data["restrictions"] = restrictions
+
+	render.RenderTemplate(w, r, "admin-restrictions.page.html", &models.TemplateData{
+		Data: data,
+	})
+}
+
+func (repo *Repository) AdminPostRestriction(w http.ResponseWriter, r *http.Request) {
+	err := r.ParseForm()
+	if err != nil {
+		helpers.ServerError(w, err)
+		return
+	}
+
+	id, _ := strconv.Atoi(r.Form.Get("id"))
+
+	restriction := models.Restriction{
+		ID:              id,
+		RestrictionName: strings.TrimSpace(r.Form.Get("restriction_name")),
+		Color:           r.Form.Get("color"),
+		CountsAsSold:    r.Form.Get("counts_as_sold") != "",
+	}
+
+	var msg string
+	switch {
+	case restriction.RestrictionName == "":
+		msg = "Restriction name cannot be empty"
+	case !colorPattern.MatchString(restriction.Color):
+		msg = "Colour must be a hex value such as #007bff"
+	}
+	if msg != "" {
+		repo.App.Session.Put(r.Context(), "error", msg)
+		http.Redirect(w, r, "/admin/restrictions", http.StatusSeeOther)
+		return
+	}
+
+	var before models.Restriction
+	action := models.AuditActionCreate
+	if restriction.ID > 0 {
+		action = models.AuditActionUpdate
+		before, err = repo.DB.GetRestrictionById(r.Context(), restriction.ID)
+		if err == nil {
+			err = repo.DB.UpdateRestriction(r.Context(), restriction)
+		}
+	} else {
+		restriction.ID, err = repo.DB.InsertRestriction(r.Context(), restriction)
+	}
+	if err != nil {
+		helpers.ServerError(w, err)
+		return
+	}
+
+	repo.recordAudit(r, action, models.AuditEntityRestriction, restriction.ID, before, restriction)
+
+	repo.App.Session.Put(r.Context(), "flash", "Restriction type saved")
+	http.Redirect(w, r, "/admin/restrictions", http.StatusSeeOther)
+}
+
+func (repo *Repository) AdminDeleteRestriction(w http.ResponseWriter, r *http.Request) {
+	id, _ := strconv.Atoi(chi.URLParam(r, "id"))
+
+	before, err := repo.DB.GetRestrictionById(r.Context(), id)
+	if err != nil {
+		helpers.ServerError(w, err)
+		return
+	}
+
+	err = repo.DB.DeleteRestriction(r.Context(), id)
+	if errors.Is(err, repository.ErrRestrictionInUse) {
+		repo.App.Session.Put(r.Context(), "error", "This restriction type is in use and cannot be deleted")
+		http.Redirect(w, r, "/admin/restrictions", http.StatusSeeOther)
+		return
+	}
+	if err != nil {
+		helpers.ServerError(w, err)
+		return
+	}
+
+	repo.recordAudit(r, models.AuditActionDelete, models.AuditEntityRestriction, id, before, models.Restriction{})
+
+	repo.App.Session.Put(r.Context(), "flash", "Restriction type deleted")
+	http.Redirect(w, r, "/admin/restrictions", http.StatusSeeOther)
+}
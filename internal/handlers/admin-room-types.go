@@ -0,0 +1,176 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/NhanNT-VNG/hotel-booking/internal/forms"
+	"github.com/NhanNT-VNG/hotel-booking/internal/helpers"
+	"github.com/NhanNT-VNG/hotel-booking/internal/models"
+	"github.com/NhanNT-VNG/hotel-booking/internal/render"
+	"github.com/NhanNT-VNG/hotel-booking/internal/repository"
+	"github.com/go-chi/chi/v5"
+)
+
+func (repo *Repository) AdminRoomTypes(w http.ResponseWriter, r *http.Request) {
+	roomTypes, err := repo.DB.AllRoomTypes(r.Context())
+	if err != nil {
+		helpers.ServerError(w, err)
+		return
+	}
+
+	data := make(map[string]interface{})
+	data["room_types"] = roomTypes
+
+	render.RenderTemplate(w, r, "admin-room-types.page.html", &models.TemplateData{
+		Data: data,
+	})
+}
+
+func (repo *Repository) renderRoomTypeForm(w http.ResponseWriter, r *http.Request, roomType models.RoomType, form *forms.Form) {
+	data := make(map[string]interface{})
+	data["room_type"] = roomType
+
+	stringMap := make(map[string]string)
+	stringMap["amenities"] = strings.Join(roomType.Amenities, "\n")
+	stringMap["photos"] = strings.Join(roomType.Photos, "\n")
+
+	render.RenderTemplate(w, r, "admin-room-type.page.html", &models.TemplateData{
+		Form:      form,
+		Data:      data,
+		StringMap: stringMap,
+	})
+}
+
+func (repo *Repository) AdminNewRoomType(w http.ResponseWriter, r *http.Request) {
+	repo.renderRoomTypeForm(w, r, models.RoomType{Capacity: 2, BaseRate: 10000}, forms.New(nil))
+}
+
+func (repo *Repository) AdminShowRoomType(w http.ResponseWriter, r *http.Request) {
+	id, _ := strconv.Atoi(chi.URLParam(r, "id"))
+
+	roomType, err := repo.DB.GetRoomTypeById(r.Context(), id)
+	if err != nil {
+		helpers.ServerError(w, err)
+		return
+	}
+
+	repo.renderRoomTypeForm(w, r, roomType, forms.New(nil))
+}
+
+// splitLines turns a textarea into a list, one entry per non-empty line.
+func splitLines(value string) []string {
+	var lines []string
+	for _, line := range strings.Split(value, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+func (repo *Repository) AdminPostRoomType(w http.ResponseWriter, r *http.Request) {
+	err := r.ParseForm()
+	if err != nil {
+		helpers.ServerError(w, err)
+		return
+	}
+
+	id, _ := strconv.Atoi(chi.URLParam(r, "id"))
+
+	var before models.RoomType
+	if id > 0 {
+		before, err = repo.DB.GetRoomTypeById(r.Context(), id)
+		if err != nil {
+			helpers.ServerError(w, err)
+			return
+		}
+	}
+
+	roomType := before
+	roomType.Name = strings.TrimSpace(r.Form.Get("name"))
+	roomType.Slug = strings.TrimSpace(r.Form.Get("slug"))
+	roomType.Description = strings.TrimSpace(r.Form.Get("description"))
+	roomType.Amenities = splitLines(r.Form.Get("amenities"))
+	roomType.Photos = splitLines(r.Form.Get("photos"))
+	if roomType.Slug == "" {
+		roomType.Slug = models.Slugify(roomType.Name)
+	}
+
+	form := forms.New(r.PostForm)
+	form.Required("name")
+
+	if !models.IsSlug(roomType.Slug) {
+		form.Errors.Add("slug", "Use lowercase letters, digits and dashes only")
+	}
+
+	roomType.Capacity, err = strconv.Atoi(r.Form.Get("capacity"))
+	if err != nil || roomType.Capacity < 1 {
+		form.Errors.Add("capacity", "Capacity must be at least 1")
+	}
+
+	roomType.MaxAdults = form.Int("max_adults", 0, 100, 0)
+
+	roomType.BaseRate, err = helpers.ParseMoney(r.Form.Get("base_rate"))
+	if err != nil {
+		form.Errors.Add("base_rate", "Base rate must be a positive amount")
+	}
+
+	if form.Valid() {
+		if id > 0 {
+			err = repo.DB.UpdateRoomType(r.Context(), roomType)
+		} else {
+			roomType.ID, err = repo.DB.InsertRoomType(r.Context(), roomType)
+		}
+
+		if errors.Is(err, repository.ErrDuplicateSlug) {
+			form.Errors.Add("slug", "This slug is already used by another room")
+		} else if err != nil {
+			helpers.ServerError(w, err)
+			return
+		}
+	}
+
+	if !form.Valid() {
+		repo.renderRoomTypeForm(w, r, roomType, form)
+		return
+	}
+
+	if id > 0 {
+		repo.recordAudit(r, models.AuditActionUpdate, models.AuditEntityRoomType, id, before, roomType)
+	} else {
+		repo.recordAudit(r, models.AuditActionCreate, models.AuditEntityRoomType, roomType.ID, models.RoomType{}, roomType)
+	}
+
+	repo.App.Session.Put(r.Context(), "flash", fmt.Sprintf("%s saved", roomType.Name))
+	http.Redirect(w, r, "/admin/room-types", http.StatusSeeOther)
+}
+
+func (repo *Repository) AdminDeleteRoomType(w http.ResponseWriter, r *http.Request) {
+	id, _ := strconv.Atoi(chi.URLParam(r, "id"))
+
+	before, err := repo.DB.GetRoomTypeById(r.Context(), id)
+	if err != nil {
+		helpers.ServerError(w, err)
+		return
+	}
+
+	err = repo.DB.DeleteRoomType(r.Context(), id)
+	if errors.Is(err, repository.ErrRoomTypeInUse) {
+		repo.App.Session.Put(r.Context(), "error", fmt.Sprintf("%s has reservations and cannot be deleted", before.Name))
+		http.Redirect(w, r, "/admin/room-types", http.StatusSeeOther)
+		return
+	}
+	if err != nil {
+		helpers.ServerError(w, err)
+		return
+	}
+
+	repo.recordAudit(r, models.AuditActionDelete, models.AuditEntityRoomType, id, before, models.RoomType{})
+
+	repo.App.Session.Put(r.Context(), "flash", fmt.Sprintf("%s deleted", before.Name))
+	http.Redirect(w, r, "/admin/room-types", http.StatusSeeOther)
+}
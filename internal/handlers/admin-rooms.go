@@ -0,0 +1,125 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/NhanNT-VNG/hotel-booking/internal/helpers"
+	"github.com/NhanNT-VNG/hotel-booking/internal/models"
+	"github.com/NhanNT-VNG/hotel-booking/internal/render"
+	"github.com/go-chi/chi/v5"
+)
+
+func (repo *Repository) AdminRooms(w http.ResponseWriter, r *http.Request) {
+	rooms, err := repo.DB.AllRooms(r.Context())
+	if err != nil {
+		helpers.ServerError(w, err)
+		return
+	}
+
+	roomTypes, err := repo.DB.AllRoomTypes(r.Context())
+	if err != nil {
+		helpers.ServerError(w, err)
+		return
+	}
+
+	data := make(map[string]interface{})
+	data["rooms"] = rooms
+	data["room_types"] = roomTypes
+
+	render.RenderTemplate(w, r, "admin-rooms.page.html", &models.TemplateData{
+		Data: data,
+	})
+}
+
+func (repo *Repository) AdminPostRoom(w http.ResponseWriter, r *http.Request) {
+	err := r.ParseForm()
+	if err != nil {
+		helpers.ServerError(w, err)
+		return
+	}
+
+	id, _ := strconv.Atoi(r.Form.Get("id"))
+	roomTypeId, _ := strconv.Atoi(r.Form.Get("room_type_id"))
+
+	room := models.Room{
+		ID:         id,
+		RoomName:   strings.TrimSpace(r.Form.Get("room_name")),
+		RoomTypeId: roomTypeId,
+		Active:     r.Form.Get("active") != "",
+	}
+
+	if room.RoomName == "" {
+		repo.App.Session.Put(r.Context(), "error", "Room name cannot be empty")
+		http.Redirect(w, r, "/admin/rooms", http.StatusSeeOther)
+		return
+	}
+
+	if _, err = repo.DB.GetRoomTypeById(r.Context(), roomTypeId); err != nil {
+		repo.App.Session.Put(r.Context(), "error", "Invalid room type")
+		http.Redirect(w, r, "/admin/rooms", http.StatusSeeOther)
+		return
+	}
+
+	var before models.Room
+	action := models.AuditActionCreate
+	if room.ID > 0 {
+		action = models.AuditActionUpdate
+		before, err = repo.DB.GetRoomById(r.Context(), room.ID)
+		if err == nil {
+			room.SortOrder = before.SortOrder
+			err = repo.DB.UpdateRoom(r.Context(), room)
+		}
+	} else {
+		room.ID, err = repo.DB.InsertRoom(r.Context(), room)
+	}
+	if err != nil {
+		helpers.ServerError(w, err)
+		return
+	}
+
+	repo.recordAudit(r, action, models.AuditEntityRoom, room.ID, before, room)
+
+	repo.App.Session.Put(r.Context(), "flash", "Room saved")
+	http.Redirect(w, r, "/admin/rooms", http.StatusSeeOther)
+}
+
+// AdminMoveRoom swaps a room with its neighbour in the display order.
+func (repo *Repository) AdminMoveRoom(w http.ResponseWriter, r *http.Request) {
+	id, _ := strconv.Atoi(chi.URLParam(r, "id"))
+
+	rooms, err := repo.DB.AllRooms(r.Context())
+	if err != nil {
+		helpers.ServerError(w, err)
+		return
+	}
+
+	roomIds := make([]int, len(rooms))
+	for i, room := range rooms {
+		roomIds[i] = room.ID
+	}
+
+	for i, roomId := range roomIds {
+		if roomId != id {
+			continue
+		}
+
+		j := i + 1
+		if chi.URLParam(r, "direction") == "up" {
+			j = i - 1
+		}
+		if j >= 0 && j < len(roomIds) {
+			roomIds[i], roomIds[j] = roomIds[j], roomIds[i]
+		}
+		break
+	}
+
+	err = repo.DB.ReorderRooms(r.Context(), roomIds)
+	if err != nil {
+		helpers.ServerError(w, err)
+		return
+	}
+
+	http.Redirect(w, r, "/admin/rooms", http.StatusSeeOther)
+}
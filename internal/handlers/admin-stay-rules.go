@@ -0,0 +1,119 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/NhanNT-VNG/hotel-booking/internal/helpers"
+	"github.com/NhanNT-VNG/hotel-booking/internal/models"
+	"github.com/NhanNT-VNG/hotel-booking/internal/render"
+	"github.com/go-chi/chi/v5"
+)
+
+func (repo *Repository) AdminStayRules(w http.ResponseWriter, r *http.Request) {
+	roomTypes, err := repo.DB.AllRoomTypes(r.Context())
+	if err != nil {
+		helpers.ServerError(w, err)
+		return
+	}
+
+	rules, err := repo.DB.AllStayRules(r.Context())
+	if err != nil {
+		helpers.ServerError(w, err)
+		return
+	}
+
+	data := make(map[string]interface{})
+	data["room_types"] = roomTypes
+	data["rules"] = rules
+	data["weekdays"] = models.Weekdays
+
+	render.RenderTemplate(w, r, "admin-stay-rules.page.html", &models.TemplateData{
+		Data: data,
+	})
+}
+
+func (repo *Repository) AdminPostStayRule(w http.ResponseWriter, r *http.Request) {
+	err := r.ParseForm()
+	if err != nil {
+		helpers.ServerError(w, err)
+		return
+	}
+
+	layout := "2006-01-02"
+	roomTypeId, _ := strconv.Atoi(r.Form.Get("room_type_id"))
+	startDate, errStart := time.Parse(layout, r.Form.Get("start"))
+	endDate, errEnd := time.Parse(layout, r.Form.Get("end"))
+	minNights, errMin := strconv.Atoi(r.Form.Get("min_nights"))
+	maxNights, errMax := strconv.Atoi(r.Form.Get("max_nights"))
+
+	var days []time.Weekday
+	for _, value := range r.Form["arrival_weekdays"] {
+		day, err := strconv.Atoi(value)
+		if err == nil && day >= 0 && day <= 6 {
+			days = append(days, time.Weekday(day))
+		}
+	}
+
+	rule := models.StayRule{
+		RoomTypeId:        roomTypeId,
+		Name:              r.Form.Get("name"),
+		StartDate:         startDate,
+		EndDate:           endDate,
+		MinNights:         minNights,
+		MaxNights:         maxNights,
+		ClosedToArrival:   r.Form.Get("closed_to_arrival") != "",
+		ClosedToDeparture: r.Form.Get("closed_to_departure") != "",
+		ArrivalWeekdays:   models.WeekdayMask(days...),
+	}
+
+	var msg string
+	switch {
+	case errStart != nil || errEnd != nil:
+		msg = "Invalid dates"
+	case endDate.Before(startDate):
+		msg = "End date must not be before start date"
+	case errMin != nil || errMax != nil || minNights < 0 || maxNights < 0:
+		msg = "Minimum and maximum nights must be positive numbers"
+	case maxNights > 0 && maxNights < minNights:
+		msg = "Maximum nights must not be less than minimum nights"
+	}
+	if msg != "" {
+		repo.App.Session.Put(r.Context(), "error", msg)
+		http.Redirect(w, r, "/admin/stay-rules", http.StatusSeeOther)
+		return
+	}
+
+	rule.ID, err = repo.DB.InsertStayRule(r.Context(), rule)
+	if err != nil {
+		helpers.ServerError(w, err)
+		return
+	}
+
+	repo.recordAudit(r, models.AuditActionCreate, models.AuditEntityStayRule, rule.ID, models.StayRule{}, rule)
+
+	repo.App.Session.Put(r.Context(), "flash", "Stay rule saved")
+	http.Redirect(w, r, "/admin/stay-rules", http.StatusSeeOther)
+}
+
+func (repo *Repository) AdminDeleteStayRule(w http.ResponseWriter, r *http.Request) {
+	id, _ := strconv.Atoi(chi.URLParam(r, "id"))
+
+	before, err := repo.DB.GetStayRuleById(r.Context(), id)
+	if err != nil {
+		helpers.ServerError(w, err)
+		return
+	}
+
+	err = repo.DB.DeleteStayRule(r.Context(), id)
+	if err != nil {
+		helpers.ServerError(w, err)
+		return
+	}
+
+	repo.recordAudit(r, models.AuditActionDelete, models.AuditEntityStayRule, id, before, models.StayRule{})
+
+	repo.App.Session.Put(r.Context(), "flash", "Stay rule deleted")
+	http.Redirect(w, r, "/admin/stay-rules", http.StatusSeeOther)
+}
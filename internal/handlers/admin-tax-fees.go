@@ -0,0 +1,107 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/NhanNT-VNG/hotel-booking/internal/helpers"
+	"github.com/NhanNT-VNG/hotel-booking/internal/models"
+	"github.com/NhanNT-VNG/hotel-booking/internal/render"
+	"github.com/go-chi/chi/v5"
+)
+
+func (repo *Repository) AdminTaxFees(w http.ResponseWriter, r *http.Request) {
+	fees, err := repo.DB.AllTaxFees(r.Context())
+	if err != nil {
+		helpers.ServerError(w, err)
+		return
+	}
+
+	data := make(map[string]interface{})
+	data["tax_fees"] = fees
+
+	render.RenderTemplate(w, r, "admin-tax-fees.page.html", &models.TemplateData{
+		Data: data,
+	})
+}
+
+func (repo *Repository) AdminPostTaxFee(w http.ResponseWriter, r *http.Request) {
+	err := r.ParseForm()
+	if err != nil {
+		helpers.ServerError(w, err)
+		return
+	}
+
+	id, _ := strconv.Atoi(r.Form.Get("id"))
+	amount, errAmount := helpers.ParseMoney(r.Form.Get("amount"))
+
+	fee := models.TaxFee{
+		ID:             id,
+		Name:           strings.TrimSpace(r.Form.Get("name")),
+		Kind:           r.Form.Get("kind"),
+		Amount:         amount,
+		ExemptChildren: r.Form.Get("exempt_children") != "",
+		Active:         r.Form.Get("active") != "",
+	}
+
+	var msg string
+	switch {
+	case fee.Name == "":
+		msg = "Name cannot be empty"
+	case !models.IsValidFeeKind(fee.Kind):
+		msg = "Invalid kind"
+	case errAmount != nil:
+		msg = "Amount must be a positive number"
+	case fee.Kind == models.FeePercent && fee.Amount > 10000:
+		msg = "Percentage cannot be more than 100"
+	}
+	if msg != "" {
+		repo.App.Session.Put(r.Context(), "error", msg)
+		http.Redirect(w, r, "/admin/taxes-fees", http.StatusSeeOther)
+		return
+	}
+
+	var before models.TaxFee
+	action := models.AuditActionCreate
+	if fee.ID > 0 {
+		action = models.AuditActionUpdate
+		before, err = repo.DB.GetTaxFeeById(r.Context(), fee.ID)
+		if err == nil {
+			err = repo.DB.UpdateTaxFee(r.Context(), fee)
+		}
+	} else {
+		fee.ID, err = repo.DB.InsertTaxFee(r.Context(), fee)
+	}
+	if err != nil {
+		helpers.ServerError(w, err)
+		return
+	}
+
+	repo.recordAudit(r, action, models.AuditEntityTaxFee, fee.ID, before, fee)
+
+	repo.App.Session.Put(r.Context(), "flash", fmt.Sprintf("%s saved", fee.Name))
+	http.Redirect(w, r, "/admin/taxes-fees", http.StatusSeeOther)
+}
+
+func (repo *Repository) AdminDeleteTaxFee(w http.ResponseWriter, r *http.Request) {
+	id, _ := strconv.Atoi(chi.URLParam(r, "id"))
+
+	before, err := repo.DB.GetTaxFeeById(r.Context(), id)
+	if err != nil {
+		helpers.ServerError(w, err)
+		return
+	}
+
+	err = repo.DB.DeleteTaxFee(r.Context(), id)
+	if err != nil {
+		helpers.ServerError(w, err)
+		return
+	}
+
+	repo.recordAudit(r, models.AuditActionDelete, models.AuditEntityTaxFee, id, before, models.TaxFee{})
+
+	repo.App.Session.Put(r.Context(), "flash", fmt.Sprintf("%s deleted", before.Name))
+	http.Redirect(w, r, "/admin/taxes-fees", http.StatusSeeOther)
+}
@@ -0,0 +1,69 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/NhanNT-VNG/hotel-booking/internal/helpers"
+	"github.com/NhanNT-VNG/hotel-booking/internal/models"
+	"github.com/NhanNT-VNG/hotel-booking/internal/render"
+	"github.com/go-chi/chi/v5"
+)
+
+// recordAudit stores who changed what. The change itself is already saved at
+// this point, so a failure is logged instead of failing the request.
+func (repo *Repository) recordAudit(r *http.Request, action, entityType string, entityId int, before, after interface{}) {
+	event := models.AuditEvent{
+		UserId:     repo.App.Session.GetInt(r.Context(), "user_id"),
+		Action:     action,
+		EntityType: entityType,
+		EntityId:   entityId,
+		Changes:    models.Diff(before, after),
+	}
+
+	if err := repo.DB.InsertAuditEvent(r.Context(), event); err != nil {
+		repo.App.ErrorLog.Println("audit:", err)
+	}
+}
+
+func (repo *Repository) auditReservation(r *http.Request, action string, before models.Reservation) {
+	after, err := repo.DB.GetReservationById(r.Context(), before.ID)
+	if err != nil {
+		repo.App.ErrorLog.Println("audit:", err)
+		return
+	}
+
+	repo.recordAudit(r, action, models.AuditEntityReservation, before.ID, before, after)
+}
+
+func (repo *Repository) AdminReservationHistory(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		helpers.ClientError(w, http.StatusNotFound)
+		return
+	}
+
+	reservation, err := repo.DB.GetReservationById(r.Context(), id)
+	if err != nil {
+		helpers.ServerError(w, err)
+		return
+	}
+
+	events, err := repo.DB.AuditEventsByEntity(r.Context(), models.AuditEntityReservation, id)
+	if err != nil {
+		helpers.ServerError(w, err)
+		return
+	}
+
+	data := make(map[string]interface{})
+	data["reservation"] = reservation
+	data["events"] = events
+
+	stringMap := make(map[string]string)
+	stringMap["src"] = chi.URLParam(r, "src")
+
+	render.RenderTemplate(w, r, "admin-reservation-history.page.html", &models.TemplateData{
+		Data:      data,
+		StringMap: stringMap,
+	})
+}
@@ -0,0 +1,570 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/NhanNT-VNG/hotel-booking/internal/forms"
+	"github.com/NhanNT-VNG/hotel-booking/internal/helpers"
+	"github.com/NhanNT-VNG/hotel-booking/internal/models"
+	"github.com/NhanNT-VNG/hotel-booking/internal/pricing"
+	"github.com/NhanNT-VNG/hotel-booking/internal/render"
+	"github.com/NhanNT-VNG/hotel-booking/internal/repository"
+	"github.com/go-chi/chi/v5"
+)
+
+// The booking being made is kept in the session as the "reservation". Its
+// first room is the reservation itself and every further room is one of its
+// Lines. Each room holds its own unit for its own dates until the booking is
+// made. A room added to the booking is for the stay searched for, unless the
+// guest gave other dates for it, which are kept in the session as the "stay".
+
+// cartGuests returns the party the booking in the session is made for.
+func (repo *Repository) cartGuests(ctx context.Context, res models.Reservation) models.Guests {
+	guests, ok := repo.App.Session.Get(ctx, "guests").(models.Guests)
+	if !ok || guests.Rooms < 1 {
+		guests = models.Guests{Adults: res.Adults, Children: res.Children, Rooms: 1}
+	}
+	if guests.Adults < 1 {
+		guests.Adults = 1
+	}
+	return guests
+}
+
+// emptyCart releases the holds of every room in the booking and returns the
+// booking without rooms.
+func (repo *Repository) emptyCart(ctx context.Context, res models.Reservation) (models.Reservation, error) {
+	for _, line := range res.Rooms() {
+		if line.HoldToken == "" {
+			continue
+		}
+		if err := repo.DB.DeleteRoomHold(ctx, line.HoldToken); err != nil {
+			return res, err
+		}
+	}
+
+	return models.Reservation{
+		StartDate: res.StartDate,
+		EndDate:   res.EndDate,
+		Adults:    res.Adults,
+		Children:  res.Children,
+	}, nil
+}
+
+// cartStay returns the dates the next room of the booking is chosen for.
+func (repo *Repository) cartStay(ctx context.Context, res models.Reservation) (time.Time, time.Time) {
+	stay, ok := repo.App.Session.Get(ctx, "stay").(models.Reservation)
+	if !ok || res.RoomTypeId == 0 {
+		return res.StartDate, res.EndDate
+	}
+	return stay.StartDate, stay.EndDate
+}
+
+// addToCart holds a room of the type for the stay and adds it to the
+// booking. It returns why the room cannot be booked, if it cannot.
+func (repo *Repository) addToCart(ctx context.Context, res *models.Reservation, roomTypeId int, startDate, endDate time.Time) (string, error) {
+	violation, err := repo.stayViolation(ctx, roomTypeId, startDate, endDate)
+	if err != nil || violation != "" {
+		return violation, err
+	}
+
+	line := models.Reservation{
+		RoomTypeId: roomTypeId,
+		StartDate:  startDate,
+		EndDate:    endDate,
+	}
+	if err = repo.holdRoom(ctx, &line); err != nil {
+		return "", err
+	}
+
+	if res.RoomTypeId == 0 {
+		res.RoomTypeId = line.RoomTypeId
+		res.RoomId = line.RoomId
+		res.HoldToken = line.HoldToken
+	} else {
+		res.Lines = append(res.Lines, line)
+	}
+	return "", nil
+}
+
+// allocateCart spreads the party over the rooms of the booking and fills in
+// their room types. It reports whether the rooms sleep the whole party.
+func (repo *Repository) allocateCart(ctx context.Context, res *models.Reservation, guests models.Guests) (bool, error) {
+	rooms := res.Rooms()
+
+	var roomTypes []models.RoomType
+	for _, line := range rooms {
+		roomType, err := repo.DB.GetRoomTypeById(ctx, line.RoomTypeId)
+		if err != nil {
+			return false, err
+		}
+		roomTypes = append(roomTypes, roomType)
+	}
+
+	shares, fits := models.AllocateGuests(roomTypes, guests)
+
+	res.RoomType = roomTypes[0]
+	res.Adults = shares[0].Adults
+	res.Children = shares[0].Children
+	for i := range res.Lines {
+		res.Lines[i].RoomType = roomTypes[i+1]
+		res.Lines[i].Adults = shares[i+1].Adults
+		res.Lines[i].Children = shares[i+1].Children
+	}
+	return fits, nil
+}
+
+// priceCart sets the total, promo code discount and taxes and fees of every
+// room in the booking and returns their quotes, the first room first. It also
+// returns why the promo code of the booking cannot be used, if it cannot.
+func (repo *Repository) priceCart(ctx context.Context, res *models.Reservation) ([]pricing.Quote, string, error) {
+	var quotes []pricing.Quote
+
+	for i, line := range res.Rooms() {
+		quote, err := pricing.QuoteRoomType(ctx, repo.DB, line.RoomTypeId, line.StartDate, line.EndDate)
+		if err != nil {
+			return quotes, "", err
+		}
+		if i == 0 {
+			res.Total = quote.Total
+			res.Discount = 0
+		} else {
+			res.Lines[i-1].Total = quote.Total
+			res.Lines[i-1].Discount = 0
+		}
+		quotes = append(quotes, quote)
+	}
+
+	promoError, err := repo.applyPromo(ctx, res)
+	if err != nil {
+		return quotes, "", err
+	}
+
+	res.FirstNight = quotes[0].DiscountedFirstNight(res.Discount)
+	for i := range res.Lines {
+		res.Lines[i].FirstNight = quotes[i+1].DiscountedFirstNight(res.Lines[i].Discount)
+	}
+
+	catalog, err := repo.DB.AllTaxFees(ctx)
+	if err != nil {
+		return quotes, "", err
+	}
+	res.Charges = pricing.Charges(catalog, *res)
+	for i := range res.Lines {
+		res.Lines[i].Charges = pricing.Charges(catalog, res.Lines[i])
+	}
+
+	return quotes, promoError, nil
+}
+
+// applyPromo takes the promo code the guest entered off the rooms priced by
+// priceCart. Rooms the code does not apply to keep their price. It returns
+// why the code cannot be used at all, in which case nothing is taken off.
+func (repo *Repository) applyPromo(ctx context.Context, res *models.Reservation) (string, error) {
+	res.PromoCodeId = 0
+	if res.PromoCode == "" {
+		return "", nil
+	}
+
+	promo, err := repo.DB.GetPromoCodeByCode(ctx, res.PromoCode)
+	if errors.Is(err, sql.ErrNoRows) {
+		return fmt.Sprintf("Promo code %s is not valid", models.NormalizePromoCode(res.PromoCode)), nil
+	}
+	if err != nil {
+		return "", err
+	}
+
+	if msg := promo.UsableAt(time.Now()); msg != "" {
+		return msg, nil
+	}
+
+	rooms := res.Rooms()
+	totals := make([]int, len(rooms))
+	eligible := make([]bool, len(rooms))
+	violation := ""
+	for i, line := range rooms {
+		totals[i] = line.Total
+		violation = promo.StayViolation(line.RoomTypeId, line.StartDate, line.EndDate)
+		eligible[i] = violation == ""
+	}
+	for _, ok := range eligible {
+		if ok {
+			violation = ""
+		}
+	}
+	if violation != "" {
+		return violation, nil
+	}
+
+	discounts := promo.Discounts(totals, eligible)
+	res.PromoCodeId = promo.ID
+	res.PromoCode = promo.Code
+	res.Discount = discounts[0]
+	res.Total -= discounts[0]
+	for i := range res.Lines {
+		res.Lines[i].Discount = discounts[i+1]
+		res.Lines[i].Total -= discounts[i+1]
+	}
+	return "", nil
+}
+
+// holdRoom places a temporary hold on a free unit of the line's room type so
+// no one else can book it while the guest fills in the form. A hold the line
+// already had is released first.
+func (repo *Repository) holdRoom(ctx context.Context, line *models.Reservation) error {
+	if line.HoldToken != "" {
+		if err := repo.DB.DeleteRoomHold(ctx, line.HoldToken); err != nil {
+			return err
+		}
+		line.HoldToken = ""
+	}
+
+	holdToken, err := helpers.RandomToken()
+	if err != nil {
+		return err
+	}
+
+	roomId, err := repo.DB.CreateRoomHold(ctx, models.RoomRestriction{
+		StartDate:     line.StartDate,
+		EndDate:       line.EndDate,
+		RoomId:        line.RoomId,
+		RestrictionId: models.RestrictionHold,
+		HoldToken:     holdToken,
+		ExpiresAt:     time.Now().Add(time.Duration(repo.App.HoldMinutes) * time.Minute),
+	}, line.RoomTypeId)
+	if err != nil {
+		return err
+	}
+
+	line.RoomId = roomId
+	line.HoldToken = holdToken
+	return nil
+}
+
+// ChooseRooms shows the rooms free for the stay in the session again, so
+// more rooms can be added to the booking.
+func (repo *Repository) ChooseRooms(w http.ResponseWriter, r *http.Request) {
+	res, ok := repo.App.Session.Get(r.Context(), "reservation").(models.Reservation)
+	if !ok {
+		http.Redirect(w, r, "/search-availability", http.StatusSeeOther)
+		return
+	}
+
+	repo.renderChooseRoom(w, r, res, repo.cartGuests(r.Context(), res))
+}
+
+// ChooseRoom adds a room of the type to the booking. A booking that already
+// has all the rooms asked for is started over.
+func (repo *Repository) ChooseRoom(w http.ResponseWriter, r *http.Request) {
+	roomTypeId, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		helpers.ServerError(w, err)
+		return
+	}
+
+	res, ok := repo.App.Session.Get(r.Context(), "reservation").(models.Reservation)
+
+	if !ok {
+		helpers.ServerError(w, errors.New("cannot get reservation from session"))
+		return
+	}
+
+	roomType, err := repo.DB.GetRoomTypeById(r.Context(), roomTypeId)
+	if err != nil {
+		helpers.ServerError(w, err)
+		return
+	}
+
+	guests := repo.cartGuests(r.Context(), res)
+	if guests.Rooms == 1 {
+		if violation := fitViolation(roomType, guests.Adults, guests.Children); violation != "" {
+			repo.App.Session.Put(r.Context(), "error", violation)
+			http.Redirect(w, r, "/search-availability", http.StatusSeeOther)
+			return
+		}
+	}
+
+	if res.RoomTypeId == 0 || len(res.Rooms()) >= guests.Rooms {
+		res, err = repo.emptyCart(r.Context(), res)
+		if err != nil {
+			helpers.ServerError(w, err)
+			return
+		}
+	}
+
+	startDate, endDate := repo.cartStay(r.Context(), res)
+	violation, err := repo.addToCart(r.Context(), &res, roomTypeId, startDate, endDate)
+	if errors.Is(err, repository.ErrRoomUnavailable) {
+		violation = "This room was just taken, please choose another one"
+	} else if err != nil {
+		helpers.ServerError(w, err)
+		return
+	}
+	if violation != "" {
+		repo.App.Session.Put(r.Context(), "error", violation)
+		http.Redirect(w, r, "/search-availability", http.StatusSeeOther)
+		return
+	}
+
+	if _, err = repo.allocateCart(r.Context(), &res, guests); err != nil {
+		helpers.ServerError(w, err)
+		return
+	}
+	repo.App.Session.Remove(r.Context(), "stay")
+	repo.App.Session.Put(r.Context(), "reservation", res)
+
+	if n := len(res.Rooms()); n < guests.Rooms {
+		repo.App.Session.Put(r.Context(), "flash", fmt.Sprintf("Room added to your booking, please choose room %d of %d", n+1, guests.Rooms))
+		http.Redirect(w, r, "/choose-room", http.StatusSeeOther)
+		return
+	}
+	http.Redirect(w, r, "/make-reservation", http.StatusSeeOther)
+}
+
+// ChooseCombination books all rooms of a suggested combination at once,
+// replacing the rooms already in the booking.
+func (repo *Repository) ChooseCombination(w http.ResponseWriter, r *http.Request) {
+	res, ok := repo.App.Session.Get(r.Context(), "reservation").(models.Reservation)
+	if !ok {
+		helpers.ServerError(w, errors.New("cannot get reservation from session"))
+		return
+	}
+
+	var roomTypeIds []int
+	for _, value := range r.URL.Query()["type"] {
+		roomTypeId, err := strconv.Atoi(value)
+		if err != nil {
+			helpers.ClientError(w, http.StatusBadRequest)
+			return
+		}
+		roomTypeIds = append(roomTypeIds, roomTypeId)
+	}
+
+	guests := repo.cartGuests(r.Context(), res)
+	guests.Rooms = len(roomTypeIds)
+	if guests.Rooms == 0 || guests.Rooms > guests.Adults {
+		repo.App.Session.Put(r.Context(), "error", "Every room needs at least one adult")
+		http.Redirect(w, r, "/choose-room", http.StatusSeeOther)
+		return
+	}
+
+	res, err := repo.emptyCart(r.Context(), res)
+	if err != nil {
+		helpers.ServerError(w, err)
+		return
+	}
+
+	for _, roomTypeId := range roomTypeIds {
+		violation, err := repo.addToCart(r.Context(), &res, roomTypeId, res.StartDate, res.EndDate)
+		if errors.Is(err, repository.ErrRoomUnavailable) {
+			violation = "One of these rooms was just taken, please choose again"
+		} else if err != nil {
+			helpers.ServerError(w, err)
+			return
+		}
+		if violation != "" {
+			res, _ = repo.emptyCart(r.Context(), res)
+			repo.App.Session.Put(r.Context(), "reservation", res)
+			repo.App.Session.Put(r.Context(), "error", violation)
+			http.Redirect(w, r, "/choose-room", http.StatusSeeOther)
+			return
+		}
+	}
+
+	if _, err = repo.allocateCart(r.Context(), &res, guests); err != nil {
+		helpers.ServerError(w, err)
+		return
+	}
+	repo.App.Session.Put(r.Context(), "guests", guests)
+	repo.App.Session.Put(r.Context(), "reservation", res)
+	http.Redirect(w, r, "/make-reservation", http.StatusSeeOther)
+}
+
+// AddCartRoom lets the guest choose one more room for the party, for the
+// stay of the booking or for the dates given.
+func (repo *Repository) AddCartRoom(w http.ResponseWriter, r *http.Request) {
+	res, ok := repo.App.Session.Get(r.Context(), "reservation").(models.Reservation)
+	if !ok {
+		http.Redirect(w, r, "/search-availability", http.StatusSeeOther)
+		return
+	}
+
+	if res.RoomTypeId == 0 {
+		http.Redirect(w, r, "/choose-room", http.StatusSeeOther)
+		return
+	}
+
+	guests := repo.cartGuests(r.Context(), res)
+	if len(res.Rooms()) >= guests.Adults {
+		repo.App.Session.Put(r.Context(), "error", "Every room needs at least one adult")
+		http.Redirect(w, r, "/make-reservation", http.StatusSeeOther)
+		return
+	}
+
+	stay := models.Reservation{StartDate: res.StartDate, EndDate: res.EndDate}
+	form := forms.New(r.URL.Query())
+	if form.Get("start") != "" || form.Get("end") != "" {
+		stay.StartDate, stay.EndDate = form.DateRange("start", "end", repo.App.BookingHorizonDays)
+		if !form.Valid() {
+			repo.App.Session.Put(r.Context(), "error", dateRangeError(form, "start", "end"))
+			http.Redirect(w, r, "/make-reservation", http.StatusSeeOther)
+			return
+		}
+	}
+
+	guests.Rooms = len(res.Rooms()) + 1
+	repo.App.Session.Put(r.Context(), "guests", guests)
+	repo.App.Session.Put(r.Context(), "stay", stay)
+	http.Redirect(w, r, "/choose-room", http.StatusSeeOther)
+}
+
+// RemoveCartRoom takes a room out of the booking and releases its hold.
+func (repo *Repository) RemoveCartRoom(w http.ResponseWriter, r *http.Request) {
+	index, err := strconv.Atoi(chi.URLParam(r, "index"))
+	if err != nil {
+		helpers.ClientError(w, http.StatusBadRequest)
+		return
+	}
+
+	res, ok := repo.App.Session.Get(r.Context(), "reservation").(models.Reservation)
+	if !ok {
+		http.Redirect(w, r, "/search-availability", http.StatusSeeOther)
+		return
+	}
+
+	rooms := res.Rooms()
+	if index < 0 || index >= len(rooms) || res.RoomTypeId == 0 {
+		helpers.ClientError(w, http.StatusNotFound)
+		return
+	}
+
+	if rooms[index].HoldToken != "" {
+		if err = repo.DB.DeleteRoomHold(r.Context(), rooms[index].HoldToken); err != nil {
+			helpers.ServerError(w, err)
+			return
+		}
+	}
+	rooms = append(rooms[:index], rooms[index+1:]...)
+
+	guests := repo.cartGuests(r.Context(), res)
+	if guests.Rooms > 1 {
+		guests.Rooms--
+	}
+	repo.App.Session.Put(r.Context(), "guests", guests)
+
+	if len(rooms) == 0 {
+		repo.App.Session.Put(r.Context(), "reservation", models.Reservation{StartDate: res.StartDate, EndDate: res.EndDate})
+		http.Redirect(w, r, "/choose-room", http.StatusSeeOther)
+		return
+	}
+
+	res = rooms[0]
+	res.Lines = rooms[1:]
+	if _, err = repo.allocateCart(r.Context(), &res, guests); err != nil {
+		helpers.ServerError(w, err)
+		return
+	}
+	repo.App.Session.Put(r.Context(), "reservation", res)
+	http.Redirect(w, r, "/make-reservation", http.StatusSeeOther)
+}
+
+// renderChooseRoom lists the room types free for the stay. While a booking
+// is being put together every free room can be added to it, otherwise only
+// the room types that sleep the party are listed, with combinations of
+// rooms that sleep it together.
+func (repo *Repository) renderChooseRoom(w http.ResponseWriter, r *http.Request, res models.Reservation, guests models.Guests) {
+	startDate, endDate := res.StartDate, res.EndDate
+	building := res.RoomTypeId != 0 && len(res.Rooms()) < guests.Rooms
+	if building {
+		startDate, endDate = repo.cartStay(r.Context(), res)
+	}
+
+	search := guests
+	if building {
+		search = models.Guests{Adults: 1, Rooms: 1}
+	}
+
+	roomTypes, err := repo.DB.SearchAvailabilityAllRoomTypes(r.Context(), startDate, endDate, search)
+	if err != nil {
+		helpers.ServerError(w, err)
+		return
+	}
+	roomTypes, violation, err := repo.withoutStayViolations(r.Context(), roomTypes, startDate, endDate)
+	if err != nil {
+		helpers.ServerError(w, err)
+		return
+	}
+
+	// any free room may be part of a combination, whatever it sleeps
+	anyRoom, err := repo.DB.SearchAvailabilityAllRoomTypes(r.Context(), startDate, endDate, models.Guests{Adults: 1, Rooms: 1})
+	if err != nil {
+		helpers.ServerError(w, err)
+		return
+	}
+	anyRoom, _, err = repo.withoutStayViolations(r.Context(), anyRoom, startDate, endDate)
+	if err != nil {
+		helpers.ServerError(w, err)
+		return
+	}
+
+	var combinations []models.RoomCombination
+	if !building {
+		combinations = models.SuggestCombinations(anyRoom, guests)
+	}
+
+	if len(roomTypes) == 0 && len(combinations) == 0 {
+		msg := "No rom availability"
+		if violation != "" {
+			msg = violation
+		}
+		repo.App.Session.Put(r.Context(), "error", msg)
+		if building {
+			http.Redirect(w, r, "/make-reservation", http.StatusSeeOther)
+			return
+		}
+		http.Redirect(w, r, "/search-availability", http.StatusSeeOther)
+		return
+	}
+
+	prices := make(map[int]int)
+	for _, item := range anyRoom {
+		rates, err := repo.DB.RoomRatesForPeriod(r.Context(), item.RoomType.ID, startDate, endDate)
+		if err != nil {
+			helpers.ServerError(w, err)
+			return
+		}
+		prices[item.RoomType.ID] = pricing.Calculate(item.RoomType, rates, startDate, endDate).Total
+	}
+	for i := range combinations {
+		for _, roomType := range combinations[i].RoomTypes {
+			combinations[i].Total += prices[roomType.ID]
+		}
+	}
+
+	data := make(map[string]interface{})
+	data["room_types"] = roomTypes
+	data["prices"] = prices
+	data["guests"] = guests
+	data["combinations"] = combinations
+	data["building"] = building
+
+	intMap := make(map[string]int)
+	if building {
+		intMap["chosen"] = len(res.Rooms())
+	}
+
+	stringMap := make(map[string]string)
+	stringMap["start_date"] = startDate.Format("2006-01-02")
+	stringMap["end_date"] = endDate.Format("2006-01-02")
+
+	render.RenderTemplate(w, r, "choose-room.page.html", &models.TemplateData{
+		Data:      data,
+		IntMap:    intMap,
+		StringMap: stringMap,
+	})
+}
@@ -1,6 +1,8 @@
 package handlers
 
 import (
+	"context"
+	"database/sql"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -15,6 +17,7 @@ import (
 	"github.com/NhanNT-VNG/hotel-booking/internal/forms"
 	"github.com/NhanNT-VNG/hotel-booking/internal/helpers"
 	"github.com/NhanNT-VNG/hotel-booking/internal/models"
+	"github.com/NhanNT-VNG/hotel-booking/internal/pricing"
 	"github.com/NhanNT-VNG/hotel-booking/internal/render"
 	"github.com/NhanNT-VNG/hotel-booking/internal/repository"
 	dbrepo "github.com/NhanNT-VNG/hotel-booking/internal/repository/dbRepo"
@@ -35,6 +38,13 @@ func NewRepo(_app *config.AppConfig, db *driver.DB) *Repository {
 	}
 }
 
+func NewTestRepo(_app *config.AppConfig) *Repository {
+	return &Repository{
+		App: _app,
+		DB:  dbrepo.NewTestingRepo(_app),
+	}
+}
+
 func NewHandlers(r *Repository) {
 	Repo = r
 }
@@ -52,44 +62,99 @@ func (repo *Repository) Contact(w http.ResponseWriter, r *http.Request) {
 	render.RenderTemplate(w, r, "contact.page.html", &models.TemplateData{})
 }
 
-func (repo *Repository) Generals(w http.ResponseWriter, r *http.Request) {
-	render.RenderTemplate(w, r, "generals.page.html", &models.TemplateData{})
-}
+func (repo *Repository) Reservation(w http.ResponseWriter, r *http.Request) {
+	res, ok := repo.App.Session.Get(r.Context(), "reservation").(models.Reservation)
+	if !ok {
+		helpers.ServerError(w, errors.New("cannot get reservation from session"))
+		return
+	}
+	if res.RoomTypeId == 0 {
+		http.Redirect(w, r, "/choose-room", http.StatusSeeOther)
+		return
+	}
+
+	fits, err := repo.allocateCart(r.Context(), &res, repo.cartGuests(r.Context(), res))
+	if err != nil {
+		helpers.ServerError(w, err)
+		return
+	}
+
+	quotes, msg, err := repo.priceCart(r.Context(), &res)
+	if err != nil {
+		helpers.ServerError(w, err)
+		return
+	}
+
+	form := forms.New(nil)
+	if msg != "" {
+		form.Errors.Add("promo_code", msg)
+		res.PromoCode = ""
+	}
 
-func (repo *Repository) Majors(w http.ResponseWriter, r *http.Request) {
-	render.RenderTemplate(w, r, "majors.page.html", &models.TemplateData{})
+	repo.App.Session.Put(r.Context(), "reservation", res)
+
+	repo.renderMakeReservation(w, r, res, quotes, fits, form)
 }
 
-func (repo *Repository) Reservation(w http.ResponseWriter, r *http.Request) {
+// PostReservationPromo applies a promo code to the booking without making
+// the reservation yet, so the guest can see the discounted price first.
+func (repo *Repository) PostReservationPromo(w http.ResponseWriter, r *http.Request) {
 	res, ok := repo.App.Session.Get(r.Context(), "reservation").(models.Reservation)
-	if !ok {
+	if !ok || res.RoomTypeId == 0 {
 		helpers.ServerError(w, errors.New("cannot get reservation from session"))
 		return
 	}
 
-	room, err := repo.DB.GetRoomById(res.RoomId)
+	err := r.ParseForm()
+	if err != nil {
+		helpers.ServerError(w, err)
+		return
+	}
 
+	res.FirstName = r.Form.Get("first_name")
+	res.LastName = r.Form.Get("last_name")
+	res.Email = r.Form.Get("email")
+	res.Phone = r.Form.Get("phone")
+	res.PromoCode = strings.TrimSpace(r.Form.Get("promo_code"))
+
+	fits, err := repo.allocateCart(r.Context(), &res, repo.cartGuests(r.Context(), res))
 	if err != nil {
 		helpers.ServerError(w, err)
 		return
 	}
 
-	res.Room.RoomName = room.RoomName
+	quotes, msg, err := repo.priceCart(r.Context(), &res)
+	if err != nil {
+		helpers.ServerError(w, err)
+		return
+	}
+
+	form := forms.New(r.PostForm)
+	if msg != "" {
+		form.Errors.Add("promo_code", msg)
+		res.PromoCode = ""
+	} else if res.PromoCode != "" {
+		repo.App.Session.Put(r.Context(), "flash", fmt.Sprintf("Promo code %s applied", res.PromoCode))
+	}
 
 	repo.App.Session.Put(r.Context(), "reservation", res)
 
+	repo.renderMakeReservation(w, r, res, quotes, fits, form)
+}
+
+func (repo *Repository) renderMakeReservation(w http.ResponseWriter, r *http.Request, res models.Reservation, quotes []pricing.Quote, fits bool, form *forms.Form) {
 	data := make(map[string]interface{})
 	data["reservation"] = res
-
-	startDate := res.StartDate.Format("2006-01-02")
-	endDate := res.EndDate.Format("2006-01-02")
+	data["rooms"] = res.Rooms()
+	data["quote"] = quotes[0]
+	data["fits"] = fits
 
 	stringMap := make(map[string]string)
-	stringMap["start_date"] = startDate
-	stringMap["end_date"] = endDate
+	stringMap["start_date"] = res.StartDate.Format("2006-01-02")
+	stringMap["end_date"] = res.EndDate.Format("2006-01-02")
 
 	render.RenderTemplate(w, r, "make-reservation.page.html", &models.TemplateData{
-		Form:      forms.New(nil),
+		Form:      form,
 		Data:      data,
 		StringMap: stringMap,
 	})
@@ -98,7 +163,7 @@ func (repo *Repository) Reservation(w http.ResponseWriter, r *http.Request) {
 func (repo *Repository) PostReservation(w http.ResponseWriter, r *http.Request) {
 	reservation, ok := repo.App.Session.Get(r.Context(), "reservation").(models.Reservation)
 
-	if !ok {
+	if !ok || reservation.RoomTypeId == 0 {
 		helpers.ServerError(w, errors.New("Cannot get reservation from session"))
 		return
 	}
@@ -113,6 +178,7 @@ func (repo *Repository) PostReservation(w http.ResponseWriter, r *http.Request)
 	reservation.LastName = r.Form.Get("last_name")
 	reservation.Email = r.Form.Get("email")
 	reservation.Phone = r.Form.Get("phone")
+	reservation.PromoCode = strings.TrimSpace(r.Form.Get("promo_code"))
 
 	form := forms.New(r.PostForm)
 
@@ -120,58 +186,153 @@ func (repo *Repository) PostReservation(w http.ResponseWriter, r *http.Request)
 	form.MinLength("first_name", 3, r)
 	form.IsEmail("email")
 
+	fits, err := repo.allocateCart(r.Context(), &reservation, repo.cartGuests(r.Context(), reservation))
+	if err != nil {
+		helpers.ServerError(w, err)
+		return
+	}
+
+	quotes, promoError, err := repo.priceCart(r.Context(), &reservation)
+	if err != nil {
+		helpers.ServerError(w, err)
+		return
+	}
+	if promoError != "" {
+		form.Errors.Add("promo_code", promoError)
+	}
+
 	if !form.Valid() {
-		data := make(map[string]interface{})
-		data["reservation"] = reservation
+		repo.renderMakeReservation(w, r, reservation, quotes, fits, form)
+		return
+	}
 
-		render.RenderTemplate(w, r, "make-reservation.page.html", &models.TemplateData{
-			Form: form,
-			Data: data,
-		})
+	if !fits {
+		repo.App.Session.Put(r.Context(), "reservation", reservation)
+		repo.App.Session.Put(r.Context(), "error", "Your party does not fit in the rooms chosen, please add another room")
+		http.Redirect(w, r, "/make-reservation", http.StatusSeeOther)
 		return
 	}
 
-	reservationId, err := repo.DB.InsertReservation(reservation)
+	reservation.ConfirmationCode, err = helpers.ConfirmationCode()
 	if err != nil {
 		helpers.ServerError(w, err)
+		return
 	}
 
-	roomRestriction := models.RoomRestriction{
-		StartDate:     reservation.StartDate,
-		EndDate:       reservation.EndDate,
-		RoomId:        reservation.RoomId,
-		ReservationId: reservationId,
-		RestrictionId: 1,
+	for _, line := range reservation.Rooms() {
+		violation, err := repo.stayViolation(r.Context(), line.RoomTypeId, line.StartDate, line.EndDate)
+		if err != nil {
+			helpers.ServerError(w, err)
+			return
+		}
+		if violation != "" {
+			empty, _ := repo.emptyCart(r.Context(), reservation)
+			repo.App.Session.Put(r.Context(), "reservation", empty)
+			repo.App.Session.Put(r.Context(), "error", violation)
+			http.Redirect(w, r, "/search-availability", http.StatusSeeOther)
+			return
+		}
 	}
 
-	err = repo.DB.InsertRoomRestrictions(roomRestriction)
+	reservationId, err := repo.DB.CreateReservation(r.Context(), reservation, models.RestrictionReservation, reservation.HoldToken)
+	if errors.Is(err, repository.ErrRoomUnavailable) {
+		data := make(map[string]interface{})
+		data["reservation"] = reservation
+
+		w.WriteHeader(http.StatusConflict)
+		render.RenderTemplate(w, r, "room-unavailable.page.html", &models.TemplateData{
+			Data: data,
+		})
+		return
+	}
+	if errors.Is(err, repository.ErrPromoCodeUsedUp) {
+		repo.App.Session.Put(r.Context(), "error", fmt.Sprintf("Promo code %s has been used up", reservation.PromoCode))
+		reservation.PromoCode = ""
+		repo.App.Session.Put(r.Context(), "reservation", reservation)
+		http.Redirect(w, r, "/make-reservation", http.StatusSeeOther)
+		return
+	}
 	if err != nil {
-		repo.App.Session.Put(r.Context(), "error", "cannot insert room restriction")
 		helpers.ServerError(w, err)
-		http.Redirect(w, r, "/", http.StatusTemporaryRedirect)
 		return
 	}
+	reservation.ID = reservationId
+	reservation.HoldToken = ""
+
+	if err = repo.syncLedger(r, reservation.ID); err != nil {
+		helpers.ServerError(w, err)
+		return
+	}
+
+	reservation.Status = models.StatusPending
+	for i := range reservation.Lines {
+		reservation.Lines[i].HoldToken = ""
+		reservation.Lines[i].Status = models.StatusPending
+	}
+	repo.App.Session.Remove(r.Context(), "guests")
+
+	repo.App.Session.Put(r.Context(), "reservation", reservation)
+	http.Redirect(w, r, "/payment", http.StatusSeeOther)
+}
+
+// sendConfirmation emails the guest the details of a paid booking with its
+// invoice attached. The booking is paid already, so the email goes out
+// without the invoice when it cannot be made.
+func (repo *Repository) sendConfirmation(ctx context.Context, reservation models.Reservation) {
+	var rooms strings.Builder
+	for _, line := range reservation.Rooms() {
+		fmt.Fprintf(&rooms, "%s from %s to %s: %s <br>",
+			line.RoomType.Name,
+			line.StartDate.Format("2006-01-02"),
+			line.EndDate.Format("2006-01-02"),
+			render.FormatMoney(line.Total))
+	}
+
+	var extras string
+	if reservation.PromoCodeId > 0 {
+		extras = fmt.Sprintf("Promo code %s: -%s <br>",
+			reservation.PromoCode, render.FormatMoney(reservation.BookingDiscount()))
+	}
+	for _, charge := range reservation.BookingCharges() {
+		extras += fmt.Sprintf("%s: %s <br>", charge.Name, render.FormatMoney(charge.Amount))
+	}
+
 	htmlMsg := fmt.Sprintf(`
 		<strong>Reservation Confirmation</strong><br>
 		Dear %s, <br>
-		This is confirm your reservation from %s to %s
+		This is confirm your reservation of: <br>
+		%s
+		%s
+		Total price: <strong>%s</strong> <br>
+		Paid: %s, balance due on arrival: %s <br>
+		Your confirmation code is <strong>%s</strong> <br>
+		You can view, change or cancel your reservation <a href="%s">here</a>
 	`,
 		reservation.FirstName,
-		reservation.StartDate.Format("2006-01-02"),
-		reservation.EndDate.Format("2006-01-02"))
+		rooms.String(),
+		extras,
+		render.FormatMoney(reservation.BookingTotal()),
+		render.FormatMoney(reservation.Paid()),
+		render.FormatMoney(reservation.BalanceDue()),
+		reservation.ConfirmationCode,
+		helpers.ManageBookingURL(reservation.ConfirmationCode))
 
 	msg := models.MailData{
 		To:       reservation.Email,
-		From:     "hotel-booking@mail.com",
+		From:     repo.App.MailFrom,
 		Subject:  "Reservation confirmation",
 		Content:  htmlMsg,
 		Template: "basic.html",
 	}
 
-	repo.App.MailChan <- msg
+	attachment, err := repo.invoiceAttachment(ctx, reservation.ID)
+	if err != nil {
+		repo.App.ErrorLog.Println("invoice:", err)
+	} else {
+		msg.Attachments = append(msg.Attachments, attachment)
+	}
 
-	repo.App.Session.Put(r.Context(), "reservation", reservation)
-	http.Redirect(w, r, "/reservation-summary", http.StatusSeeOther)
+	repo.queueMail(ctx, msg)
 }
 
 func (repo *Repository) ReservationSummary(w http.ResponseWriter, r *http.Request) {
@@ -198,86 +359,143 @@ func (repo *Repository) ReservationSummary(w http.ResponseWriter, r *http.Reques
 }
 
 func (repo *Repository) Availability(w http.ResponseWriter, r *http.Request) {
-	render.RenderTemplate(w, r, "search-availability.page.html", &models.TemplateData{})
+	render.RenderTemplate(w, r, "search-availability.page.html", &models.TemplateData{
+		Form: forms.New(nil),
+	})
 }
 
 func (repo *Repository) PostAvailability(w http.ResponseWriter, r *http.Request) {
-	start := r.Form.Get("start")
-	end := r.Form.Get("end")
-
-	layout := "2006-01-02"
-	startDate, err := time.Parse(layout, start)
-	if err != nil {
-		helpers.ServerError(w, err)
-		return
-	}
-	endDate, err := time.Parse(layout, end)
-	if err != nil {
-		helpers.ServerError(w, err)
+	form := forms.New(r.PostForm)
+	startDate, endDate := form.DateRange("start", "end", repo.App.BookingHorizonDays)
+	guests := guestsFromForm(form)
+	if !form.Valid() {
+		render.RenderTemplate(w, r, "search-availability.page.html", &models.TemplateData{
+			Form: form,
+		})
 		return
 	}
 
-	rooms, err := repo.DB.SearchAvailabilityAllRooms(startDate, endDate)
-
+	res, _ := repo.App.Session.Get(r.Context(), "reservation").(models.Reservation)
+	res, err := repo.emptyCart(r.Context(), res)
 	if err != nil {
 		helpers.ServerError(w, err)
 		return
 	}
 
-	if len(rooms) == 0 {
-		repo.App.Session.Put(r.Context(), "error", "No rom availability")
-		http.Redirect(w, r, "search-availability", http.StatusSeeOther)
-		return
-	}
-
-	data := make(map[string]interface{})
-	data["rooms"] = rooms
-
-	res := models.Reservation{
+	res = models.Reservation{
 		StartDate: startDate,
 		EndDate:   endDate,
+		Adults:    guests.Adults,
+		Children:  guests.Children,
 	}
 
+	repo.App.Session.Put(r.Context(), "guests", guests)
 	repo.App.Session.Put(r.Context(), "reservation", res)
+	repo.App.Session.Remove(r.Context(), "stay")
 
-	render.RenderTemplate(w, r, "choose-room.page.html", &models.TemplateData{
-		Data: data,
-	})
+	repo.renderChooseRoom(w, r, res, guests)
 }
 
 type jsonRes struct {
-	OK        bool   `json:"ok"`
-	Message   string `json:"message"`
-	RoomID    string `json:"room_id"`
-	StartDate string `json:"start_date"`
-	EndDate   string `json:"end_date"`
+	OK         bool                `json:"ok"`
+	Message    string              `json:"message"`
+	Errors     map[string][]string `json:"errors,omitempty"`
+	RoomTypeID string              `json:"room_type_id"`
+	FreeUnits  int                 `json:"free_units"`
+	StartDate  string              `json:"start_date"`
+	EndDate    string              `json:"end_date"`
+	Total      int                 `json:"total"`
+	Price      string              `json:"price"`
+	Nights     []jsonNight         `json:"nights"`
 }
 
-func (repo *Repository) AvailabilityJson(w http.ResponseWriter, r *http.Request) {
+type jsonNight struct {
+	Date  string `json:"date"`
+	Rate  int    `json:"rate"`
+	Price string `json:"price"`
+}
 
+func (repo *Repository) AvailabilityJson(w http.ResponseWriter, r *http.Request) {
 	sd := r.Form.Get("start")
 	ed := r.Form.Get("end")
+	roomTypeId, _ := strconv.Atoi(r.Form.Get("room_type_id"))
 
-	layout := "2006-01-02"
-	startDate, _ := time.Parse(layout, sd)
-	endDate, _ := time.Parse(layout, ed)
-	roomId, _ := strconv.Atoi(r.Form.Get("room_id"))
+	res := jsonRes{
+		StartDate:  sd,
+		EndDate:    ed,
+		RoomTypeID: strconv.Itoa(roomTypeId),
+	}
 
-	available, err := repo.DB.SearchAvailabilityByDatesByRoomId(startDate, endDate, roomId)
+	form := forms.New(r.PostForm)
+	startDate, endDate := form.DateRange("start", "end", repo.App.BookingHorizonDays)
+	guests := guestsFromForm(form)
+	if !form.Valid() {
+		res.Message = dateRangeError(form, "start", "end")
+		if res.Message == "" {
+			res.Message = "Please check the number of guests"
+		}
+		res.Errors = form.Errors
+		repo.writeJSON(w, res)
+		return
+	}
 
+	roomType, err := repo.DB.GetRoomTypeById(r.Context(), roomTypeId)
+	if errors.Is(err, sql.ErrNoRows) {
+		res.Message = "Room not found"
+		repo.writeJSON(w, res)
+		return
+	}
 	if err != nil {
 		helpers.ServerError(w, err)
 		return
 	}
+	if msg := fitViolation(roomType, guests.Adults, guests.Children); msg != "" {
+		res.Message = msg
+		repo.writeJSON(w, res)
+		return
+	}
 
-	res := jsonRes{
-		OK:        available,
-		Message:   "",
-		StartDate: sd,
-		EndDate:   ed,
-		RoomID:    strconv.Itoa(roomId),
+	freeUnits, err := repo.DB.SearchAvailabilityByDatesByRoomTypeId(r.Context(), startDate, endDate, roomTypeId)
+
+	if err != nil {
+		helpers.ServerError(w, err)
+		return
+	}
+
+	res.OK = freeUnits > 0
+	res.FreeUnits = freeUnits
+
+	if res.OK {
+		res.Message, err = repo.stayViolation(r.Context(), roomTypeId, startDate, endDate)
+		if err != nil {
+			helpers.ServerError(w, err)
+			return
+		}
+		res.OK = res.Message == ""
+	}
+
+	if res.OK {
+		quote, err := pricing.QuoteRoomType(r.Context(), repo.DB, roomTypeId, startDate, endDate)
+		if err != nil {
+			helpers.ServerError(w, err)
+			return
+		}
+
+		res.Total = quote.Total
+		res.Price = render.FormatMoney(quote.Total)
+		for _, night := range quote.Nights {
+			res.Nights = append(res.Nights, jsonNight{
+				Date:  night.Date.Format(forms.DateLayout),
+				Rate:  night.Rate,
+				Price: render.FormatMoney(night.Rate),
+			})
+		}
 	}
 
+	repo.writeJSON(w, res)
+}
+
+func (repo *Repository) writeJSON(w http.ResponseWriter, res jsonRes) {
 	out, err := json.MarshalIndent(res, "", "  ")
 	if err != nil {
 		helpers.ServerError(w, err)
@@ -287,50 +505,135 @@ func (repo *Repository) AvailabilityJson(w http.ResponseWriter, r *http.Request)
 	w.Write(out)
 }
 
-func (repo *Repository) ChooseRoom(w http.ResponseWriter, r *http.Request) {
-	roomId, err := strconv.Atoi(chi.URLParam(r, "id"))
+// dateRangeError returns the first problem found with a date range
+func dateRangeError(form *forms.Form, startField, endField string) string {
+	if msg := form.Errors.Get(startField); msg != "" {
+		return msg
+	}
+	return form.Errors.Get(endField)
+}
+
+func (repo *Repository) BookRoom(w http.ResponseWriter, r *http.Request) {
+	roomTypeId, _ := strconv.Atoi(r.URL.Query().Get("id"))
+
+	res, _ := repo.App.Session.Get(r.Context(), "reservation").(models.Reservation)
+
+	roomType, err := repo.DB.GetRoomTypeById(r.Context(), roomTypeId)
+
 	if err != nil {
 		helpers.ServerError(w, err)
 		return
 	}
 
-	res, ok := repo.App.Session.Get(r.Context(), "reservation").(models.Reservation)
-
-	if !ok {
-		helpers.ServerError(w, errors.New("cannot get reservation from session"))
+	form := forms.New(r.URL.Query())
+	startDate, endDate := form.DateRange("s", "e", repo.App.BookingHorizonDays)
+	guests := guestsFromForm(form)
+	if !form.Valid() {
+		msg := dateRangeError(form, "s", "e")
+		if msg == "" {
+			msg = "Please check the number of guests"
+		}
+		repo.App.Session.Put(r.Context(), "error", msg)
+		http.Redirect(w, r, "/rooms/"+roomType.Slug, http.StatusSeeOther)
 		return
 	}
+	guests.Rooms = 1
 
-	res.RoomId = roomId
-	repo.App.Session.Put(r.Context(), "reservation", res)
-	http.Redirect(w, r, "/make-reservation", http.StatusSeeOther)
-}
-
-func (repo *Repository) BookRoom(w http.ResponseWriter, r *http.Request) {
-	roomId, _ := strconv.Atoi(r.URL.Query().Get("id"))
-	sd := r.URL.Query().Get("s")
-	ed := r.URL.Query().Get("e")
-
-	layout := "2006-01-02"
-	startDate, _ := time.Parse(layout, sd)
-	endDate, _ := time.Parse(layout, ed)
-
-	var res models.Reservation
+	if violation := fitViolation(roomType, guests.Adults, guests.Children); violation != "" {
+		repo.App.Session.Put(r.Context(), "error", violation)
+		http.Redirect(w, r, "/rooms/"+roomType.Slug, http.StatusSeeOther)
+		return
+	}
 
-	room, err := repo.DB.GetRoomById(roomId)
+	res, err = repo.emptyCart(r.Context(), res)
+	if err != nil {
+		helpers.ServerError(w, err)
+		return
+	}
+	res = models.Reservation{
+		StartDate: startDate,
+		EndDate:   endDate,
+	}
 
+	violation, err := repo.addToCart(r.Context(), &res, roomTypeId, startDate, endDate)
+	if errors.Is(err, repository.ErrRoomUnavailable) {
+		repo.App.Session.Put(r.Context(), "error", "This room was just taken, please choose other dates")
+		http.Redirect(w, r, "/search-availability", http.StatusSeeOther)
+		return
+	}
 	if err != nil {
 		helpers.ServerError(w, err)
 		return
 	}
-	res.Room.RoomName = room.RoomName
-	res.RoomId = roomId
-	res.StartDate = startDate
-	res.EndDate = endDate
+	if violation != "" {
+		repo.App.Session.Put(r.Context(), "error", violation)
+		http.Redirect(w, r, "/rooms/"+roomType.Slug, http.StatusSeeOther)
+		return
+	}
 
+	if _, err = repo.allocateCart(r.Context(), &res, guests); err != nil {
+		helpers.ServerError(w, err)
+		return
+	}
+
+	repo.App.Session.Put(r.Context(), "guests", guests)
 	repo.App.Session.Put(r.Context(), "reservation", res)
 	http.Redirect(w, r, "/make-reservation", http.StatusSeeOther)
+}
 
+// stayViolation explains why a stay breaks the stay rules of the room type,
+// or returns an empty string when it may be booked.
+func (repo *Repository) stayViolation(ctx context.Context, roomTypeId int, startDate, endDate time.Time) (string, error) {
+	rules, err := repo.DB.StayRulesForPeriod(ctx, roomTypeId, startDate, endDate)
+	if err != nil {
+		return "", err
+	}
+	return models.StayViolation(rules, startDate, endDate), nil
+}
+
+// withoutStayViolations drops the room types the stay rules do not allow for
+// the stay. It also returns why the last one was dropped.
+func (repo *Repository) withoutStayViolations(ctx context.Context, roomTypes []models.RoomTypeAvailability, startDate, endDate time.Time) ([]models.RoomTypeAvailability, string, error) {
+	var bookable []models.RoomTypeAvailability
+	var violation string
+	for _, item := range roomTypes {
+		msg, err := repo.stayViolation(ctx, item.RoomType.ID, startDate, endDate)
+		if err != nil {
+			return nil, "", err
+		}
+		if msg != "" {
+			violation = msg
+			continue
+		}
+		bookable = append(bookable, item)
+	}
+	return bookable, violation, nil
+}
+
+// guestsFromForm reads the party size from the adults, children and rooms
+// fields. Every room needs at least one adult.
+func guestsFromForm(form *forms.Form) models.Guests {
+	guests := models.Guests{
+		Adults:   form.Int("adults", 1, 20, 1),
+		Children: form.Int("children", 0, 20, 0),
+		Rooms:    form.Int("rooms", 1, 10, 1),
+	}
+	if guests.Rooms > guests.Adults {
+		form.Errors.Add("rooms", "Every room needs at least one adult")
+	}
+	return guests
+}
+
+// fitViolation explains why a room of the type cannot sleep the party, or
+// returns an empty string when it can.
+func fitViolation(roomType models.RoomType, adults, children int) string {
+	if adults+children > roomType.Capacity {
+		return fmt.Sprintf("%s sleeps at most %d guests", roomType.Name, roomType.Capacity)
+	}
+	if adults > roomType.AdultCapacity() {
+		return fmt.Sprintf("%s sleeps at most %d adults", roomType.Name, roomType.AdultCapacity())
+	}
+	return ""
 }
 
 func (repo *Repository) ShowLogin(w http.ResponseWriter, r *http.Request) {
@@ -360,7 +663,7 @@ func (repo *Repository) Login(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	id, _, err := repo.DB.Authenticate(email, password)
+	id, _, err := repo.DB.Authenticate(r.Context(), email, password)
 	if err != nil {
 		log.Println(err)
 		repo.App.Session.Put(r.Context(), "error", "Invalid login credentials")
@@ -382,27 +685,52 @@ func (repo *Repository) Logout(w http.ResponseWriter, r *http.Request) {
 }
 
 func (repo *Repository) AdminDashboard(w http.ResponseWriter, r *http.Request) {
-	render.RenderTemplate(w, r, "admin-dashboard.page.html", &models.TemplateData{})
+	today := time.Now().Truncate(24 * time.Hour)
+
+	occupancy, err := repo.DB.OccupancyForDate(r.Context(), today)
+	if err != nil {
+		helpers.ServerError(w, err)
+		return
+	}
+
+	data := make(map[string]interface{})
+	data["occupancy"] = occupancy
+
+	render.RenderTemplate(w, r, "admin-dashboard.page.html", &models.TemplateData{
+		Data: data,
+	})
 }
 
 func (repo *Repository) AdminAllReservations(w http.ResponseWriter, r *http.Request) {
-	reservations, err := repo.DB.AllReservations()
+	status := r.URL.Query().Get("status")
+	if !models.IsValidStatus(status) {
+		status = ""
+	}
+
+	reservations, err := repo.DB.ReservationsByStatus(r.Context(), status)
 	if err != nil {
 		helpers.ServerError(w, err)
+		return
 	}
 
 	data := make(map[string]interface{})
 	data["reservations"] = reservations
+	data["statuses"] = models.ReservationStatuses
+
+	stringMap := make(map[string]string)
+	stringMap["status"] = status
 
 	render.RenderTemplate(w, r, "admin-all-reservations.page.html", &models.TemplateData{
-		Data: data,
+		Data:      data,
+		StringMap: stringMap,
 	})
 }
 
 func (repo *Repository) AdminNewReservations(w http.ResponseWriter, r *http.Request) {
-	reservations, err := repo.DB.AllNewReservations()
+	reservations, err := repo.DB.ReservationsByStatus(r.Context(), models.StatusPending)
 	if err != nil {
 		helpers.ServerError(w, err)
+		return
 	}
 
 	data := make(map[string]interface{})
@@ -426,19 +754,65 @@ func (repo *Repository) AdminShowReservation(w http.ResponseWriter, r *http.Requ
 
 	stringMap["src"] = src
 
-	reservation, err := repo.DB.GetReservationById(id)
+	reservation, err := repo.DB.GetReservationById(r.Context(), id)
 
 	if err != nil {
 		helpers.ServerError(w, err)
 		return
 	}
 
+	statusChanges, err := repo.DB.GetReservationStatusChanges(r.Context(), id)
+	if err != nil {
+		helpers.ServerError(w, err)
+		return
+	}
+
+	policy, penalty, err := repo.cancellationPenalty(r.Context(), reservation)
+	if err != nil {
+		helpers.ServerError(w, err)
+		return
+	}
+
+	rooms, err := repo.DB.FreeRoomsByType(r.Context(), reservation.RoomTypeId, reservation.StartDate, reservation.EndDate)
+	if err != nil {
+		helpers.ServerError(w, err)
+		return
+	}
+
+	intMap := make(map[string]int)
+	intMap["penalty"] = penalty
+
+	reservation.Lines, err = repo.DB.ReservationLines(r.Context(), reservation.ID)
+	if err != nil {
+		helpers.ServerError(w, err)
+		return
+	}
+
+	if err = repo.loadCharges(r.Context(), &reservation); err != nil {
+		helpers.ServerError(w, err)
+		return
+	}
+
+	ledger, err := repo.DB.LedgerEntries(r.Context(), reservation.ID)
+	if err != nil {
+		helpers.ServerError(w, err)
+		return
+	}
+	intMap["refundable"] = reservation.Refundable()
+
 	data := make(map[string]interface{})
 	data["reservation"] = reservation
+	data["lines"] = reservation.Lines
+	data["ledger"] = models.RunningBalance(ledger)
+	data["policy"] = policy
+	data["rooms"] = append([]models.Room{reservation.Room}, rooms...)
+	data["status_changes"] = statusChanges
+	data["next_statuses"] = models.NextStatuses(reservation.Status)
 
 	render.RenderTemplate(w, r, "admin-show-reservation.page.html", &models.TemplateData{
 		Data:      data,
 		StringMap: stringMap,
+		IntMap:    intMap,
 		Form:      forms.New(nil),
 	})
 }
@@ -461,98 +835,290 @@ func (repo *Repository) AdminPostShowReservation(w http.ResponseWriter, r *http.
 
 	stringMap["src"] = src
 
-	reservation, err := repo.DB.GetReservationById(id)
+	reservation, err := repo.DB.GetReservationById(r.Context(), id)
 
 	if err != nil {
 		helpers.ServerError(w, err)
 		return
 	}
 
+	before := reservation
+
 	reservation.FirstName = r.Form.Get("first_name")
 	reservation.LastName = r.Form.Get("last_name")
 	reservation.Email = r.Form.Get("email")
 	reservation.Phone = r.Form.Get("phone")
 
-	err = repo.DB.UpdateReservation(reservation)
+	err = repo.DB.UpdateReservation(r.Context(), reservation)
 
 	if err != nil {
 		helpers.ServerError(w, err)
 		return
 	}
 
+	repo.auditReservation(r, models.AuditActionUpdate, before)
+
+	err = repo.eachLine(r, reservation, func(line models.Reservation) error {
+		line.FirstName = reservation.FirstName
+		line.LastName = reservation.LastName
+		line.Email = reservation.Email
+		line.Phone = reservation.Phone
+		if err := repo.DB.UpdateReservation(r.Context(), line); err != nil {
+			return err
+		}
+		repo.auditReservation(r, models.AuditActionUpdate, line)
+		return nil
+	})
+	if err != nil {
+		helpers.ServerError(w, err)
+		return
+	}
+
 	repo.App.Session.Put(r.Context(), "flash", "Changes saved")
 	http.Redirect(w, r, fmt.Sprintf("/admin/reservations-%s", src), http.StatusSeeOther)
 }
 
-func (repo *Repository) AdminProcessReservation(w http.ResponseWriter, r *http.Request) {
+func (repo *Repository) AdminAssignReservationRoom(w http.ResponseWriter, r *http.Request) {
 	id, _ := strconv.Atoi(chi.URLParam(r, "id"))
 	src := chi.URLParam(r, "src")
+	showURL := fmt.Sprintf("/admin/reservations/%s/%d", src, id)
 
-	_ = repo.DB.UpdateProcessedReservation(id, 1)
-	repo.App.Session.Put(r.Context(), "flash", "Reservation marked as processed")
-	http.Redirect(w, r, fmt.Sprintf("/admin/reservations-%s", src), http.StatusSeeOther)
+	err := r.ParseForm()
+	if err != nil {
+		helpers.ServerError(w, err)
+		return
+	}
+
+	reservation, err := repo.DB.GetReservationById(r.Context(), id)
+	if err != nil {
+		helpers.ServerError(w, err)
+		return
+	}
+
+	roomId, _ := strconv.Atoi(r.Form.Get("room_id"))
+	room, err := repo.DB.GetRoomById(r.Context(), roomId)
+	if err != nil || room.RoomTypeId != reservation.RoomTypeId {
+		repo.App.Session.Put(r.Context(), "error", "Invalid room")
+		http.Redirect(w, r, showURL, http.StatusSeeOther)
+		return
+	}
+
+	if roomId == reservation.RoomId {
+		http.Redirect(w, r, showURL, http.StatusSeeOther)
+		return
+	}
+
+	err = repo.DB.AssignReservationRoom(r.Context(), id, roomId)
+	if errors.Is(err, repository.ErrRoomUnavailable) {
+		repo.App.Session.Put(r.Context(), "error", fmt.Sprintf("%s is not available for these dates", room.RoomName))
+		http.Redirect(w, r, showURL, http.StatusSeeOther)
+		return
+	}
+	if err != nil {
+		helpers.ServerError(w, err)
+		return
+	}
+
+	repo.auditReservation(r, models.AuditActionUpdate, reservation)
+
+	repo.App.Session.Put(r.Context(), "flash", fmt.Sprintf("Reservation moved to %s", room.RoomName))
+	http.Redirect(w, r, showURL, http.StatusSeeOther)
 }
 
-func (repo *Repository) AdminDeleteReservation(w http.ResponseWriter, r *http.Request) {
+func (repo *Repository) AdminUpdateReservationStatus(w http.ResponseWriter, r *http.Request) {
 	id, _ := strconv.Atoi(chi.URLParam(r, "id"))
 	src := chi.URLParam(r, "src")
+	status := chi.URLParam(r, "status")
 
-	_ = repo.DB.DeleteReservation(id)
-	repo.App.Session.Put(r.Context(), "flash", "Reservation deleted")
+	reservation, err := repo.DB.GetReservationById(r.Context(), id)
+	if err != nil {
+		helpers.ServerError(w, err)
+		return
+	}
+
+	// the other rooms of the booking follow the first room
+	rooms, err := repo.bookingRooms(r.Context(), reservation)
+	if err != nil {
+		helpers.ServerError(w, err)
+		return
+	}
+
+	action := models.AuditActionStatus
+	if status == models.StatusCancelled {
+		action = models.AuditActionCancel
+		err = repo.cancelRooms(r.Context(), rooms)
+	} else {
+		err = repo.DB.ChangeRoomsStatus(r.Context(), rooms, status)
+	}
+
+	if errors.Is(err, repository.ErrInvalidStatusTransition) {
+		repo.App.Session.Put(r.Context(), "error", fmt.Sprintf("Reservation cannot be marked as %s", models.StatusLabel(status)))
+		http.Redirect(w, r, fmt.Sprintf("/admin/reservations/%s/%d", src, id), http.StatusSeeOther)
+		return
+	}
+	if err != nil {
+		helpers.ServerError(w, err)
+		return
+	}
+
+	for _, room := range rooms {
+		repo.auditReservation(r, action, room)
+	}
+
+	if status == models.StatusCancelled {
+		if err = repo.syncLedger(r, id); err != nil {
+			helpers.ServerError(w, err)
+			return
+		}
+	}
+
+	repo.App.Session.Put(r.Context(), "flash", fmt.Sprintf("Reservation marked as %s", models.StatusLabel(status)))
 	http.Redirect(w, r, fmt.Sprintf("/admin/reservations-%s", src), http.StatusSeeOther)
 }
 
-func (repo *Repository) AdminReservationsCalendar(w http.ResponseWriter, r *http.Request) {
-	now := time.Now()
-	if r.URL.Query().Get("y") != "" {
-		year, _ := strconv.Atoi(r.URL.Query().Get("y"))
-		month, _ := strconv.Atoi(r.URL.Query().Get("m"))
-		now = time.Date(year, time.Month(month), 1, 0, 0, 0, 0, time.UTC)
+func (repo *Repository) AdminDeleteReservation(w http.ResponseWriter, r *http.Request) {
+	id, _ := strconv.Atoi(chi.URLParam(r, "id"))
+	src := chi.URLParam(r, "src")
+
+	reservation, err := repo.DB.GetReservationById(r.Context(), id)
+	if err != nil {
+		helpers.ServerError(w, err)
+		return
 	}
 
-	data := make(map[string]interface{})
-	data["now"] = now
+	rooms := []models.Reservation{reservation}
+	if reservation.ParentId == 0 {
+		lines, err := repo.activeLines(r.Context(), id)
+		if err != nil {
+			helpers.ServerError(w, err)
+			return
+		}
+		rooms = append(rooms, lines...)
+	}
 
-	next := now.AddDate(0, 1, 0)
-	last := now.AddDate(0, -1, 0)
+	// the other rooms of the booking are deleted along with the first room
+	err = repo.DB.DeleteReservation(r.Context(), id, repo.App.Session.GetInt(r.Context(), "user_id"))
+	if err != nil {
+		helpers.ServerError(w, err)
+		return
+	}
 
-	nextMonth := next.Format("01")
-	nextMonthYear := next.Format("2006")
+	for _, room := range rooms {
+		repo.auditReservation(r, models.AuditActionDelete, room)
+	}
 
-	lastMonth := last.Format("01")
-	lastMonthYear := last.Format("2006")
+	repo.App.Session.Put(r.Context(), "flash", "Reservation moved to trash")
+	http.Redirect(w, r, fmt.Sprintf("/admin/reservations-%s", src), http.StatusSeeOther)
+}
 
-	stringMap := make(map[string]string)
+// bookingRooms returns the reservation and, when it is the first room of a
+// booking, the other rooms in the same status, which change status with it.
+func (repo *Repository) bookingRooms(ctx context.Context, reservation models.Reservation) ([]models.Reservation, error) {
+	rooms := []models.Reservation{reservation}
+	if reservation.ParentId > 0 {
+		return rooms, nil
+	}
 
-	stringMap["next_month"] = nextMonth
-	stringMap["next_month_year"] = nextMonthYear
+	lines, err := repo.activeLines(ctx, reservation.ID)
+	if err != nil {
+		return nil, err
+	}
+	for _, line := range lines {
+		if line.Status == reservation.Status {
+			rooms = append(rooms, line)
+		}
+	}
+	return rooms, nil
+}
 
-	stringMap["last_month"] = lastMonth
-	stringMap["last_month_year"] = lastMonthYear
+// eachLine calls fn for every extra room booked together with the
+// reservation, so changes to the first room of a booking apply to all rooms.
+func (repo *Repository) eachLine(r *http.Request, reservation models.Reservation, fn func(line models.Reservation) error) error {
+	if reservation.ParentId > 0 {
+		return nil
+	}
 
-	stringMap["this_month"] = now.Format("01")
-	stringMap["this_month_year"] = now.Format("2006")
+	lines, err := repo.DB.ReservationLines(r.Context(), reservation.ID)
+	if err != nil {
+		return err
+	}
+	for _, line := range lines {
+		if err := fn(line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
 
-	currentYear, currentMonth, _ := now.Date()
+func (repo *Repository) AdminTrashReservations(w http.ResponseWriter, r *http.Request) {
+	reservations, err := repo.DB.DeletedReservations(r.Context())
+	if err != nil {
+		helpers.ServerError(w, err)
+		return
+	}
 
-	currentLocation := now.Location()
-	firstOfMonth := time.Date(currentYear, currentMonth, 1, 0, 0, 0, 0, currentLocation)
-	lastOfMonth := firstOfMonth.AddDate(0, 1, -1)
+	data := make(map[string]interface{})
+	data["reservations"] = reservations
 
-	intMap := make(map[string]int)
-	intMap["days_in_month"] = lastOfMonth.Day()
+	render.RenderTemplate(w, r, "admin-trash-reservations.page.html", &models.TemplateData{
+		Data: data,
+	})
+}
 
-	rooms, err := repo.DB.AllRooms()
+func (repo *Repository) AdminRestoreReservation(w http.ResponseWriter, r *http.Request) {
+	id, _ := strconv.Atoi(chi.URLParam(r, "id"))
+	src := chi.URLParam(r, "src")
 
+	reservation, err := repo.DB.GetReservationById(r.Context(), id)
 	if err != nil {
 		helpers.ServerError(w, err)
+		return
 	}
 
-	data["rooms"] = rooms
+	err = repo.DB.RestoreReservation(r.Context(), id)
+	if errors.Is(err, repository.ErrRoomUnavailable) {
+		repo.App.Session.Put(r.Context(), "error", "Reservation cannot be restored, the room is no longer available for these dates")
+		http.Redirect(w, r, fmt.Sprintf("/admin/reservations/%s/%d", src, id), http.StatusSeeOther)
+		return
+	}
+	if errors.Is(err, repository.ErrPromoCodeUsedUp) {
+		repo.App.Session.Put(r.Context(), "error", "Reservation cannot be restored, its promo code has reached its usage limit")
+		http.Redirect(w, r, fmt.Sprintf("/admin/reservations/%s/%d", src, id), http.StatusSeeOther)
+		return
+	}
+	if err != nil {
+		helpers.ServerError(w, err)
+		return
+	}
 
-	render.RenderTemplate(w, r, "admin-reservations-calendar.page.html", &models.TemplateData{
-		StringMap: stringMap,
-		Data:      data,
-		IntMap:    intMap,
+	repo.auditReservation(r, models.AuditActionRestore, reservation)
+
+	var unavailable bool
+	err = repo.eachLine(r, reservation, func(line models.Reservation) error {
+		if line.DeletedAt.IsZero() {
+			return nil
+		}
+		err := repo.DB.RestoreReservation(r.Context(), line.ID)
+		if errors.Is(err, repository.ErrRoomUnavailable) {
+			unavailable = true
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		repo.auditReservation(r, models.AuditActionRestore, line)
+		return nil
 	})
+	if err != nil {
+		helpers.ServerError(w, err)
+		return
+	}
+	if unavailable {
+		repo.App.Session.Put(r.Context(), "error", "Reservation restored, but some of its rooms are no longer available for these dates")
+		http.Redirect(w, r, fmt.Sprintf("/admin/reservations/%s/%d", src, id), http.StatusSeeOther)
+		return
+	}
+
+	repo.App.Session.Put(r.Context(), "flash", "Reservation restored")
+	http.Redirect(w, r, fmt.Sprintf("/admin/reservations-%s", src), http.StatusSeeOther)
 }
@@ -0,0 +1,575 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/NhanNT-VNG/hotel-booking/internal/models"
+	"github.com/NhanNT-VNG/hotel-booking/internal/repository"
+)
+
+var theTests = []struct {
+	name               string
+	url                string
+	expectedStatusCode int
+}{
+	{"home", "/", http.StatusOK},
+	{"about", "/about", http.StatusOK},
+	{"contact", "/contact", http.StatusOK},
+	{"rooms", "/rooms", http.StatusOK},
+	{"generals quarters", "/rooms/generals-quarters", http.StatusOK},
+	{"majors suite", "/rooms/majors-suite", http.StatusOK},
+	{"unknown room", "/rooms/presidential-suite", http.StatusNotFound},
+	{"search availability", "/search-availability", http.StatusOK},
+	{"login", "/user/login", http.StatusOK},
+}
+
+func TestHandlers(t *testing.T) {
+	ts := httptest.NewTLSServer(getRoutes())
+	defer ts.Close()
+
+	for _, e := range theTests {
+		resp, err := ts.Client().Get(ts.URL + e.url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+
+		if resp.StatusCode != e.expectedStatusCode {
+			t.Errorf("for %s, expected %d but got %d", e.name, e.expectedStatusCode, resp.StatusCode)
+		}
+	}
+}
+
+var csrfTokenRe = regexp.MustCompile(`name="csrf_token" value="([^"]+)"`)
+
+// guest browses the site with its own session, the way a visitor would.
+type guest struct {
+	t      *testing.T
+	client *http.Client
+	url    string
+}
+
+func newGuest(t *testing.T, ts *httptest.Server) *guest {
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := ts.Client()
+	client.Jar = jar
+	return &guest{t: t, client: client, url: ts.URL}
+}
+
+func (g *guest) read(resp *http.Response, err error) (string, string) {
+	g.t.Helper()
+	if err != nil {
+		g.t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		g.t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		g.t.Fatalf("%s %s: expected %d but got %d", resp.Request.Method, resp.Request.URL.Path, http.StatusOK, resp.StatusCode)
+	}
+	return resp.Request.URL.Path, string(body)
+}
+
+func (g *guest) get(path string) (string, string) {
+	g.t.Helper()
+	return g.read(g.client.Get(g.url + path))
+}
+
+func (g *guest) post(path string, values url.Values) (string, string) {
+	g.t.Helper()
+	_, page := g.get("/search-availability")
+	token := csrfTokenRe.FindStringSubmatch(page)
+	if token == nil {
+		g.t.Fatal("no csrf token on the search page")
+	}
+	values.Set("csrf_token", html.UnescapeString(token[1]))
+	return g.read(g.client.PostForm(g.url+path, values))
+}
+
+func expectPage(t *testing.T, path, page, wantPath string, want ...string) {
+	t.Helper()
+	if path != wantPath {
+		t.Errorf("expected to land on %s but got %s", wantPath, path)
+	}
+	for _, text := range want {
+		if !strings.Contains(page, text) {
+			t.Errorf("%s: expected page to contain %q", path, text)
+		}
+	}
+}
+
+func TestReservationFlow(t *testing.T) {
+	ts := httptest.NewTLSServer(getRoutes())
+	defer ts.Close()
+
+	start := time.Now().AddDate(0, 0, 20).Format("2006-01-02")
+	end := time.Now().AddDate(0, 0, 23).Format("2006-01-02")
+
+	g := newGuest(t, ts)
+
+	path, page := g.post("/search-availability", url.Values{"start": {start}, "end": {end}})
+	expectPage(t, path, page, "/search-availability", "Choose a room", "/choose-room/1", "/choose-room/2")
+
+	path, page = g.get("/choose-room/1")
+	expectPage(t, path, page, "/make-reservation", "Make Reservation", "General&#39;s Quarters")
+
+	path, page = g.post("/make-reservation", url.Values{})
+	expectPage(t, path, page, "/make-reservation", "This field cannot be empty")
+
+	path, page = g.post("/make-reservation", url.Values{
+		"first_name": {"John"},
+		"last_name":  {"Smith"},
+		"email":      {"john@example.com"},
+		"phone":      {"555-555-5555"},
+	})
+	expectPage(t, path, page, "/payment", "Deposit due now", "Please pay by")
+
+	path, page = g.post("/payment", url.Values{"card_name": {"John Smith"}, "card_number": {"4000000000000002"}})
+	expectPage(t, path, page, "/payment", "Your card was declined")
+
+	path, page = g.post("/payment", url.Values{"card_name": {"John Smith"}, "card_number": {"4242424242424242"}})
+	expectPage(t, path, page, "/reservation-summary", "Reservation Summary", "John", start, end)
+
+	// the only General's Quarters is taken for these dates now
+	other := newGuest(t, ts)
+
+	path, page = other.post("/search-availability", url.Values{"start": {start}, "end": {end}})
+	expectPage(t, path, page, "/search-availability", "/choose-room/2")
+	if strings.Contains(page, "/choose-room/1") {
+		t.Error("General's Quarters offered for dates that are already booked")
+	}
+
+	path, page = other.get("/choose-room/1")
+	expectPage(t, path, page, "/search-availability")
+
+	path, page = other.post("/search-availability", url.Values{"start": {end}, "end": {time.Now().AddDate(0, 0, 25).Format("2006-01-02")}})
+	expectPage(t, path, page, "/search-availability", "/choose-room/1")
+}
+
+func TestCartRoomDates(t *testing.T) {
+	ts := httptest.NewTLSServer(getRoutes())
+	defer ts.Close()
+
+	day := func(days int) string {
+		return time.Now().AddDate(0, 0, days).Format("2006-01-02")
+	}
+
+	g := newGuest(t, ts)
+
+	path, page := g.post("/search-availability", url.Values{"start": {day(100)}, "end": {day(103)}, "adults": {"2"}})
+	expectPage(t, path, page, "/search-availability", "/choose-room/1")
+
+	path, page = g.get("/choose-room/1")
+	expectPage(t, path, page, "/make-reservation", "Add another room")
+
+	path, page = g.get("/add-room?" + url.Values{"start": {day(102)}, "end": {day(101)}}.Encode())
+	expectPage(t, path, page, "/make-reservation", "Departure date must be after arrival date")
+
+	path, page = g.get("/add-room?" + url.Values{"start": {day(101)}, "end": {day(105)}}.Encode())
+	expectPage(t, path, page, "/choose-room", "Choose room 2 of 2", day(101)+" to "+day(105), "/choose-room/2")
+
+	path, page = g.get("/choose-room/2")
+	expectPage(t, path, page, "/make-reservation", day(100)+" to "+day(103), day(101)+" to "+day(105))
+
+	path, page = g.post("/make-reservation", url.Values{
+		"first_name": {"Kim"},
+		"last_name":  {"Dates"},
+		"email":      {"kim@example.com"},
+	})
+	expectPage(t, path, page, "/payment")
+
+	unpaid, err := Repo.DB.UnpaidReservations(context.Background(), time.Now().Add(time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, reservation := range unpaid {
+		if reservation.LastName != "Dates" {
+			continue
+		}
+		lines, err := Repo.DB.ReservationLines(context.Background(), reservation.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(lines) != 1 || lines[0].StartDate.Format("2006-01-02") != day(101) || lines[0].EndDate.Format("2006-01-02") != day(105) {
+			t.Errorf("expected the second room to keep its own dates but got %+v", lines)
+		}
+		return
+	}
+	t.Error("the booking was not made")
+}
+
+func TestRoomRestrictionOverlap(t *testing.T) {
+	date := func(days int) time.Time {
+		return time.Now().Truncate(24*time.Hour).AddDate(0, 0, days)
+	}
+
+	var tests = []struct {
+		name      string
+		startDate time.Time
+		endDate   time.Time
+		available bool
+	}{
+		{"same dates", date(10), date(13), false},
+		{"overlaps the start", date(8), date(11), false},
+		{"overlaps the end", date(12), date(15), false},
+		{"inside", date(11), date(12), false},
+		{"around", date(9), date(14), false},
+		{"checks out on arrival day", date(8), date(10), true},
+		{"checks in on departure day", date(13), date(15), true},
+		{"before", date(1), date(3), true},
+		{"after", date(20), date(22), true},
+	}
+
+	for _, e := range tests {
+		db := NewTestRepo(&app).DB
+		ctx := context.Background()
+
+		err := db.InsertRoomRestrictions(ctx, models.RoomRestriction{
+			StartDate:     date(10),
+			EndDate:       date(13),
+			RoomId:        1,
+			RestrictionId: models.RestrictionReservation,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		free, err := db.SearchAvailabilityByDatesByRoomTypeId(ctx, e.startDate, e.endDate, 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if free > 0 != e.available {
+			t.Errorf("%s: expected available to be %t but got %d free rooms", e.name, e.available, free)
+		}
+
+		err = db.InsertRoomRestrictions(ctx, models.RoomRestriction{
+			StartDate:     e.startDate,
+			EndDate:       e.endDate,
+			RoomId:        1,
+			RestrictionId: models.RestrictionReservation,
+		})
+		if e.available && err != nil {
+			t.Errorf("%s: expected the room to be booked but got %v", e.name, err)
+		}
+		if !e.available && !errors.Is(err, repository.ErrRoomUnavailable) {
+			t.Errorf("%s: expected %v but got %v", e.name, repository.ErrRoomUnavailable, err)
+		}
+	}
+}
+
+func TestExpiredHoldFreesRoom(t *testing.T) {
+	db := NewTestRepo(&app).DB
+	ctx := context.Background()
+
+	startDate := time.Now().Truncate(24*time.Hour).AddDate(0, 0, 10)
+	endDate := startDate.AddDate(0, 0, 2)
+
+	hold := models.RoomRestriction{
+		StartDate:     startDate,
+		EndDate:       endDate,
+		RoomId:        1,
+		RestrictionId: models.RestrictionHold,
+		HoldToken:     "expired",
+		ExpiresAt:     time.Now().Add(-time.Minute),
+	}
+	if err := db.InsertRoomRestrictions(ctx, hold); err != nil {
+		t.Fatal(err)
+	}
+
+	free, err := db.SearchAvailabilityByDatesByRoomTypeId(ctx, startDate, endDate, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if free != 1 {
+		t.Errorf("expected an expired hold to free the room but got %d free rooms", free)
+	}
+
+	hold.HoldToken = "active"
+	hold.ExpiresAt = time.Now().Add(time.Minute)
+	if err = db.InsertRoomRestrictions(ctx, hold); err != nil {
+		t.Fatal(err)
+	}
+
+	free, err = db.SearchAvailabilityByDatesByRoomTypeId(ctx, startDate, endDate, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if free != 0 {
+		t.Errorf("expected an active hold to take the room but got %d free rooms", free)
+	}
+}
+
+func TestPromoCodeUses(t *testing.T) {
+	db := NewTestRepo(&app).DB
+	ctx := context.Background()
+
+	promoId, err := db.InsertPromoCode(ctx, models.PromoCode{
+		Code:          "ONCE",
+		Name:          "One use",
+		DiscountType:  models.DiscountPercent,
+		DiscountValue: 10,
+		MaxUses:       1,
+		Active:        true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	book := func(days int) (int, error) {
+		startDate := time.Now().Truncate(24*time.Hour).AddDate(0, 0, days)
+		return db.CreateReservation(ctx, models.Reservation{
+			FirstName:   "John",
+			LastName:    "Smith",
+			Email:       "john@example.com",
+			StartDate:   startDate,
+			EndDate:     startDate.AddDate(0, 0, 2),
+			RoomTypeId:  1,
+			PromoCodeId: promoId,
+			PromoCode:   "ONCE",
+		}, models.RestrictionReservation, "")
+	}
+
+	uses := func() int {
+		promo, err := db.GetPromoCodeById(ctx, promoId)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return promo.Uses
+	}
+
+	reservationId, err := book(10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := uses(); n != 1 {
+		t.Errorf("expected 1 use after booking but got %d", n)
+	}
+
+	if _, err = book(20); !errors.Is(err, repository.ErrPromoCodeUsedUp) {
+		t.Errorf("expected %v but got %v", repository.ErrPromoCodeUsedUp, err)
+	}
+
+	if err = db.CancelReservation(ctx, reservationId, 0); err != nil {
+		t.Fatal(err)
+	}
+	if n := uses(); n != 0 {
+		t.Errorf("expected the use back after cancelling but got %d", n)
+	}
+
+	reservationId, err = book(20)
+	if err != nil {
+		t.Fatalf("expected the code to be usable again but got %v", err)
+	}
+
+	if err = db.DeleteReservation(ctx, reservationId, 1); err != nil {
+		t.Fatal(err)
+	}
+	if n := uses(); n != 0 {
+		t.Errorf("expected the use back after deleting but got %d", n)
+	}
+
+	otherId, err := book(30)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = db.RestoreReservation(ctx, reservationId); !errors.Is(err, repository.ErrPromoCodeUsedUp) {
+		t.Errorf("expected %v restoring past the limit but got %v", repository.ErrPromoCodeUsedUp, err)
+	}
+	if n := uses(); n != 1 {
+		t.Errorf("expected 1 use after the refused restore but got %d", n)
+	}
+
+	if err = db.CancelReservation(ctx, otherId, 0); err != nil {
+		t.Fatal(err)
+	}
+	if err = db.RestoreReservation(ctx, reservationId); err != nil {
+		t.Fatalf("expected the restore to take the free use but got %v", err)
+	}
+	if n := uses(); n != 1 {
+		t.Errorf("expected 1 use after restoring but got %d", n)
+	}
+}
+
+func TestChangeRoomsStatus(t *testing.T) {
+	db := NewTestRepo(&app).DB
+	ctx := context.Background()
+
+	promoId, err := db.InsertPromoCode(ctx, models.PromoCode{
+		Code:          "ROOMS",
+		Name:          "Rooms",
+		DiscountType:  models.DiscountPercent,
+		DiscountValue: 10,
+		Active:        true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	startDate := time.Now().Truncate(24*time.Hour).AddDate(0, 0, 50)
+	room := models.Reservation{StartDate: startDate, EndDate: startDate.AddDate(0, 0, 2), RoomTypeId: 1}
+	book := func(room models.Reservation, lines []models.Reservation) int {
+		reservation := room
+		reservation.FirstName = "John"
+		reservation.LastName = "Smith"
+		reservation.Email = "john@example.com"
+		reservation.PromoCodeId = promoId
+		reservation.Lines = lines
+		id, err := db.CreateReservation(ctx, reservation, models.RestrictionReservation, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		return id
+	}
+
+	line := room
+	line.RoomTypeId = 2
+	reservationId := book(room, []models.Reservation{line})
+	later := room
+	later.StartDate = room.EndDate
+	later.EndDate = room.EndDate.AddDate(0, 0, 2)
+	book(later, nil)
+
+	lines, err := db.ReservationLines(ctx, reservationId)
+	if err != nil || len(lines) != 1 {
+		t.Fatalf("expected one extra room but got %d, %v", len(lines), err)
+	}
+	rooms := []models.Reservation{{ID: reservationId}, {ID: lines[0].ID}}
+
+	statuses := func() []string {
+		var got []string
+		for _, room := range rooms {
+			reservation, err := db.GetReservationById(ctx, room.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got = append(got, reservation.Status)
+		}
+		return got
+	}
+
+	if err = db.CancelReservation(ctx, lines[0].ID, 0); err != nil {
+		t.Fatal(err)
+	}
+	if err = db.ChangeRoomsStatus(ctx, rooms, models.StatusConfirmed); !errors.Is(err, repository.ErrInvalidStatusTransition) {
+		t.Errorf("expected %v but got %v", repository.ErrInvalidStatusTransition, err)
+	}
+	if got := statuses(); got[0] != models.StatusPending {
+		t.Errorf("expected no room to change when one cannot but got %v", got)
+	}
+
+	rooms = rooms[:1]
+	if err = db.ChangeRoomsStatus(ctx, rooms, models.StatusConfirmed); err != nil {
+		t.Fatal(err)
+	}
+	if got := statuses(); got[0] != models.StatusConfirmed {
+		t.Errorf("expected the room to be confirmed but got %v", got)
+	}
+
+	if err = db.DeleteReservation(ctx, reservationId, 1); err != nil {
+		t.Fatal(err)
+	}
+	deleted, err := db.ReservationLines(ctx, reservationId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(deleted) != 1 || deleted[0].DeletedAt.IsZero() {
+		t.Errorf("expected the other room to be deleted with the first but got %+v", deleted)
+	}
+	promo, err := db.GetPromoCodeById(ctx, promoId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if promo.Uses != 1 {
+		t.Errorf("expected the booking to give back one use but %d are left", promo.Uses)
+	}
+}
+
+func TestAdminPostRefund(t *testing.T) {
+	ts := httptest.NewTLSServer(getRoutes())
+	defer ts.Close()
+
+	ctx := context.Background()
+	reservationId, payment := cancelledPaidBooking(t, 40, "Doe")
+
+	var tests = []struct {
+		name     string
+		amount   string
+		message  string
+		refunded int
+		status   string
+	}{
+		{"no amount", "0", "Refund must be a positive amount", 0, models.PaymentCaptured},
+		{"more than the policy allows", "150.00", "Only $100.00 can be refunded under the cancellation policy", 0, models.PaymentCaptured},
+		{"partial refund", "40.00", "Refunded $40.00", 4000, models.PaymentCaptured},
+		{"rest of the refund", "60", "Refunded $60.00", 10000, models.PaymentCaptured},
+		{"nothing left", "0.01", "Only $0.00 can be refunded under the cancellation policy", 10000, models.PaymentCaptured},
+	}
+
+	g := newGuest(t, ts)
+	refundURL := fmt.Sprintf("/admin/reservations/all/%d/refund", reservationId)
+
+	for _, e := range tests {
+		path, page := g.post(refundURL, url.Values{"amount": {e.amount}, "reason": {"Goodwill"}})
+		if path != fmt.Sprintf("/admin/reservations/all/%d", reservationId) {
+			t.Errorf("%s: expected to land on the reservation but got %s", e.name, path)
+		}
+		if !strings.Contains(page, e.message) {
+			t.Errorf("%s: expected page to contain %q", e.name, e.message)
+		}
+
+		refunds, err := Repo.DB.ReservationRefunds(ctx, reservationId)
+		if err != nil {
+			t.Fatal(err)
+		}
+		refunded := 0
+		for _, refund := range refunds {
+			if refund.PaymentId != payment.ID || refund.Reason != "Goodwill" || refund.ProviderRef != payment.ProviderRef || refund.Status != models.RefundSucceeded {
+				t.Errorf("%s: refund not made against the payment: %+v", e.name, refund)
+			}
+			refunded += refund.Amount
+		}
+		if refunded != e.refunded {
+			t.Errorf("%s: expected %d refunded but got %d", e.name, e.refunded, refunded)
+		}
+
+		entries, err := Repo.DB.LedgerEntries(ctx, reservationId)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := models.LedgerSum(entries, models.LedgerRefund); got != e.refunded {
+			t.Errorf("%s: expected %d refunded in the ledger but got %d", e.name, e.refunded, got)
+		}
+
+		payments, err := Repo.DB.ReservationPayments(ctx, reservationId)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(payments) != 1 || payments[0].Status != e.status {
+			t.Errorf("%s: expected the payment to be %s but got %+v", e.name, e.status, payments)
+		}
+	}
+
+	// a refund worked out before the ones above must not be paid as well
+	stale := []models.Refund{{PaymentId: payment.ID, Amount: 100}}
+	if _, err := Repo.DB.InsertPendingRefunds(ctx, reservationId, 4000, stale); !errors.Is(err, repository.ErrRefundsChanged) {
+		t.Errorf("expected %v but got %v", repository.ErrRefundsChanged, err)
+	}
+}
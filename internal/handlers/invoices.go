@@ -0,0 +1,112 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/NhanNT-VNG/hotel-booking/internal/helpers"
+	"github.com/NhanNT-VNG/hotel-booking/internal/invoice"
+	"github.com/NhanNT-VNG/hotel-booking/internal/models"
+	"github.com/NhanNT-VNG/hotel-booking/internal/pricing"
+	"github.com/go-chi/chi/v5"
+)
+
+// renderInvoice numbers the invoice of a booking loaded with loadBooking
+// and renders it as a PDF.
+func (repo *Repository) renderInvoice(ctx context.Context, reservation models.Reservation) (models.Invoice, []byte, error) {
+	inv, err := repo.DB.IssueInvoice(ctx, reservation.ID)
+	if err != nil {
+		return inv, nil, err
+	}
+
+	quotes := make(map[int]pricing.Quote)
+	for _, room := range reservation.Rooms() {
+		if room.Status == models.StatusCancelled {
+			continue
+		}
+		quotes[room.ID], err = pricing.QuoteRoomType(ctx, repo.DB, room.RoomTypeId, room.StartDate, room.EndDate)
+		if err != nil {
+			return inv, nil, err
+		}
+	}
+
+	var buf bytes.Buffer
+	err = invoice.Write(&buf, invoice.Document{
+		Hotel:       repo.App.Hotel,
+		Invoice:     inv,
+		Reservation: reservation,
+		Quotes:      quotes,
+	})
+	return inv, buf.Bytes(), err
+}
+
+func (repo *Repository) writeInvoice(w http.ResponseWriter, r *http.Request, reservation models.Reservation) {
+	inv, pdf, err := repo.renderInvoice(r.Context(), reservation)
+	if err != nil {
+		helpers.ServerError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/pdf")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", invoice.FileName(inv)))
+	w.Header().Set("Content-Length", strconv.Itoa(len(pdf)))
+	w.Write(pdf)
+}
+
+// invoiceAttachment is the invoice of a booking ready to be sent by email.
+func (repo *Repository) invoiceAttachment(ctx context.Context, id int) (models.MailAttachment, error) {
+	reservation, err := repo.loadBooking(ctx, id)
+	if err != nil {
+		return models.MailAttachment{}, err
+	}
+
+	inv, pdf, err := repo.renderInvoice(ctx, reservation)
+	if err != nil {
+		return models.MailAttachment{}, err
+	}
+
+	return models.MailAttachment{
+		Name:        invoice.FileName(inv),
+		ContentType: "application/pdf",
+		Data:        pdf,
+	}, nil
+}
+
+// AdminReservationInvoice downloads the invoice of the booking the
+// reservation belongs to.
+func (repo *Repository) AdminReservationInvoice(w http.ResponseWriter, r *http.Request) {
+	id, _ := strconv.Atoi(chi.URLParam(r, "id"))
+	src := chi.URLParam(r, "src")
+
+	reservation, err := repo.loadBooking(r.Context(), id)
+	if err != nil {
+		helpers.ServerError(w, err)
+		return
+	}
+
+	if reservation.Status == models.StatusPending {
+		repo.App.Session.Put(r.Context(), "error", "An invoice is available once the reservation is paid")
+		http.Redirect(w, r, fmt.Sprintf("/admin/reservations/%s/%d", src, id), http.StatusSeeOther)
+		return
+	}
+
+	repo.writeInvoice(w, r, reservation)
+}
+
+func (repo *Repository) BookingInvoice(w http.ResponseWriter, r *http.Request) {
+	reservation, ok := repo.manageReservation(w, r)
+	if !ok {
+		return
+	}
+
+	if reservation.Status == models.StatusPending {
+		repo.App.Session.Put(r.Context(), "error", "An invoice is available once the reservation is paid")
+		http.Redirect(w, r, fmt.Sprintf("/manage-booking/%s/%s", chi.URLParam(r, "code"), chi.URLParam(r, "signature")), http.StatusSeeOther)
+		return
+	}
+
+	repo.writeInvoice(w, r, reservation)
+}
@@ -0,0 +1,192 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/NhanNT-VNG/hotel-booking/internal/helpers"
+	"github.com/NhanNT-VNG/hotel-booking/internal/models"
+	"github.com/NhanNT-VNG/hotel-booking/internal/render"
+	"github.com/NhanNT-VNG/hotel-booking/internal/repository"
+	"github.com/go-chi/chi/v5"
+)
+
+// syncLedger posts the entries that bring the room charges and cancellation
+// penalties in the ledger of a booking in line with its rooms. It is called
+// after a booking is made, cancelled or changed.
+func (repo *Repository) syncLedger(r *http.Request, id int) error {
+	return repo.postLedgerChanges(r.Context(), id, repo.App.Session.GetInt(r.Context(), "user_id"))
+}
+
+// postLedgerChanges does the work of syncLedger on behalf of the user, or of
+// the system when userId is zero.
+func (repo *Repository) postLedgerChanges(ctx context.Context, id, userId int) error {
+	reservation, err := repo.loadBooking(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	entries, err := repo.DB.LedgerEntries(ctx, reservation.ID)
+	if err != nil {
+		return err
+	}
+
+	charged := models.LedgerSum(entries, models.LedgerCharge)
+	if change := reservation.RoomCharges() - charged; change != 0 {
+		description := "Room charges"
+		if charged != 0 {
+			description = "Room charges changed"
+		}
+		_, err = repo.DB.InsertLedgerEntry(ctx, models.LedgerEntry{
+			ReservationId: reservation.ID,
+			Kind:          models.LedgerCharge,
+			Description:   description,
+			Amount:        change,
+			UserId:        userId,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	penalised := models.LedgerSum(entries, models.LedgerPenalty)
+	if change := reservation.Penalties() - penalised; change != 0 {
+		_, err = repo.DB.InsertLedgerEntry(ctx, models.LedgerEntry{
+			ReservationId: reservation.ID,
+			Kind:          models.LedgerPenalty,
+			Description:   "Cancellation penalty",
+			Amount:        change,
+			UserId:        userId,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ledgerPayment posts a captured payment to the ledger of its booking.
+func (repo *Repository) ledgerPayment(r *http.Request, payment models.Payment) error {
+	description := "Payment"
+	if payment.Kind == models.PaymentDeposit {
+		description = "Deposit"
+	}
+
+	_, err := repo.DB.InsertLedgerEntry(r.Context(), models.LedgerEntry{
+		ReservationId: payment.ReservationId,
+		Kind:          models.LedgerPayment,
+		Description:   fmt.Sprintf("%s (%s %s)", description, payment.Provider, payment.ProviderRef),
+		Amount:        payment.Amount,
+		PaymentId:     payment.ID,
+	})
+	return err
+}
+
+// AdminPostRefund pays money back to the guest through the payment gateway.
+// Only what was paid above the cost of the booking under its cancellation
+// policy can be refunded. The refund is spread over the payments in order.
+func (repo *Repository) AdminPostRefund(w http.ResponseWriter, r *http.Request) {
+	id, _ := strconv.Atoi(chi.URLParam(r, "id"))
+	src := chi.URLParam(r, "src")
+	showURL := fmt.Sprintf("/admin/reservations/%s/%d", src, id)
+
+	err := r.ParseForm()
+	if err != nil {
+		helpers.ServerError(w, err)
+		return
+	}
+
+	reservation, err := repo.loadBooking(r.Context(), id)
+	if err != nil {
+		helpers.ServerError(w, err)
+		return
+	}
+	if reservation.ID != id {
+		repo.App.Session.Put(r.Context(), "error", "Refunds are made on the first room of the booking")
+		http.Redirect(w, r, showURL, http.StatusSeeOther)
+		return
+	}
+
+	amount, err := helpers.ParseMoney(r.Form.Get("amount"))
+	if err != nil || amount == 0 {
+		repo.App.Session.Put(r.Context(), "error", "Refund must be a positive amount")
+		http.Redirect(w, r, showURL, http.StatusSeeOther)
+		return
+	}
+
+	refundable := reservation.Refundable()
+	if amount > refundable {
+		repo.App.Session.Put(r.Context(), "error", fmt.Sprintf("Only %s can be refunded under the cancellation policy", render.FormatMoney(refundable)))
+		http.Redirect(w, r, showURL, http.StatusSeeOther)
+		return
+	}
+
+	reason := strings.TrimSpace(r.Form.Get("reason"))
+	description := "Refund"
+	if reason != "" {
+		description = "Refund: " + reason
+	}
+
+	refunds := reservation.SplitRefund(amount)
+	for i := range refunds {
+		refunds[i].Reason = reason
+		refunds[i].UserId = repo.App.Session.GetInt(r.Context(), "user_id")
+	}
+
+	// the refunds are recorded before any money moves, so a refund made at
+	// the same time or a crash part way through cannot pay the guest twice
+	refunds, err = repo.DB.InsertPendingRefunds(r.Context(), reservation.ID, reservation.Refunded(), refunds)
+	if errors.Is(err, repository.ErrRefundsChanged) {
+		repo.App.Session.Put(r.Context(), "error", "The booking was refunded in the meantime, check the balance and try again")
+		http.Redirect(w, r, showURL, http.StatusSeeOther)
+		return
+	}
+	if err != nil {
+		helpers.ServerError(w, err)
+		return
+	}
+
+	providerRefs := make(map[int]string)
+	for _, payment := range reservation.Payments {
+		providerRefs[payment.ID] = payment.ProviderRef
+	}
+
+	refunded, failed := 0, 0
+	for _, refund := range refunds {
+		result, err := repo.App.Payments.Refund(r.Context(), providerRefs[refund.PaymentId], refund.Amount)
+		if err != nil {
+			repo.App.ErrorLog.Println("refund:", err)
+			if err = repo.DB.FailRefund(r.Context(), refund.ID); err != nil {
+				helpers.ServerError(w, err)
+				return
+			}
+			failed += refund.Amount
+			continue
+		}
+
+		refund.ProviderRef = result.ID
+		if err = repo.DB.CompleteRefund(r.Context(), refund, description); err != nil {
+			helpers.ServerError(w, err)
+			return
+		}
+		refund.Status = models.RefundSucceeded
+
+		repo.recordAudit(r, models.AuditActionCreate, models.AuditEntityRefund, refund.ID, models.Refund{}, refund)
+
+		refunded += refund.Amount
+	}
+
+	if failed > 0 {
+		repo.App.Session.Put(r.Context(), "error", fmt.Sprintf("Refunded %s, the payment provider refused to refund %s", render.FormatMoney(refunded), render.FormatMoney(failed)))
+		http.Redirect(w, r, showURL, http.StatusSeeOther)
+		return
+	}
+
+	repo.App.Session.Put(r.Context(), "flash", fmt.Sprintf("Refunded %s", render.FormatMoney(refunded)))
+	http.Redirect(w, r, showURL, http.StatusSeeOther)
+}
@@ -0,0 +1,77 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/NhanNT-VNG/hotel-booking/internal/helpers"
+	"github.com/NhanNT-VNG/hotel-booking/internal/models"
+	"github.com/NhanNT-VNG/hotel-booking/internal/render"
+	"github.com/go-chi/chi/v5"
+)
+
+// queueMail stores a mail in the outbox and wakes the mail workers. Mail is
+// kept in the outbox until it is sent, so none is lost on restart.
+func (repo *Repository) queueMail(ctx context.Context, msg models.MailData) {
+	_, err := repo.DB.InsertMail(ctx, msg)
+	if err != nil {
+		repo.App.ErrorLog.Println("mail outbox:", err)
+		return
+	}
+
+	repo.wakeMailWorkers()
+}
+
+// wakeMailWorkers tells the mail workers there is mail to send without
+// waiting for them to be idle.
+func (repo *Repository) wakeMailWorkers() {
+	select {
+	case repo.App.MailQueued <- struct{}{}:
+	default:
+	}
+}
+
+func (repo *Repository) AdminMailOutbox(w http.ResponseWriter, r *http.Request) {
+	failed, err := repo.DB.OutboxMailByStatus(r.Context(), models.MailFailed)
+	if err != nil {
+		helpers.ServerError(w, err)
+		return
+	}
+
+	pending, err := repo.DB.OutboxMailByStatus(r.Context(), models.MailPending)
+	if err != nil {
+		helpers.ServerError(w, err)
+		return
+	}
+
+	data := make(map[string]interface{})
+	data["failed"] = failed
+	data["pending"] = pending
+
+	render.RenderTemplate(w, r, "admin-mail-outbox.page.html", &models.TemplateData{
+		Data: data,
+	})
+}
+
+func (repo *Repository) AdminResendMail(w http.ResponseWriter, r *http.Request) {
+	id, _ := strconv.Atoi(chi.URLParam(r, "id"))
+
+	err := repo.DB.ResendMail(r.Context(), id)
+	if errors.Is(err, sql.ErrNoRows) {
+		repo.App.Session.Put(r.Context(), "error", "Only failed mail can be resent")
+		http.Redirect(w, r, "/admin/mail-outbox", http.StatusSeeOther)
+		return
+	}
+	if err != nil {
+		helpers.ServerError(w, err)
+		return
+	}
+
+	repo.wakeMailWorkers()
+
+	repo.App.Session.Put(r.Context(), "flash", "Mail queued to be sent again")
+	http.Redirect(w, r, "/admin/mail-outbox", http.StatusSeeOther)
+}
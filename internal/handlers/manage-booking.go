@@ -0,0 +1,460 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/NhanNT-VNG/hotel-booking/internal/forms"
+	"github.com/NhanNT-VNG/hotel-booking/internal/helpers"
+	"github.com/NhanNT-VNG/hotel-booking/internal/models"
+	"github.com/NhanNT-VNG/hotel-booking/internal/pricing"
+	"github.com/NhanNT-VNG/hotel-booking/internal/render"
+	"github.com/NhanNT-VNG/hotel-booking/internal/repository"
+	"github.com/go-chi/chi/v5"
+)
+
+func (repo *Repository) manageReservation(w http.ResponseWriter, r *http.Request) (models.Reservation, bool) {
+	code := chi.URLParam(r, "code")
+	signature := chi.URLParam(r, "signature")
+
+	if !helpers.VerifySignature(code, signature) {
+		helpers.ClientError(w, http.StatusNotFound)
+		return models.Reservation{}, false
+	}
+
+	reservation, err := repo.DB.GetReservationByCode(r.Context(), code)
+	if errors.Is(err, sql.ErrNoRows) {
+		helpers.ClientError(w, http.StatusNotFound)
+		return reservation, false
+	}
+	if err != nil {
+		helpers.ServerError(w, err)
+		return reservation, false
+	}
+
+	reservation.Lines, err = repo.activeLines(r.Context(), reservation.ID)
+	if err != nil {
+		helpers.ServerError(w, err)
+		return reservation, false
+	}
+
+	if err = repo.loadCharges(r.Context(), &reservation); err != nil {
+		helpers.ServerError(w, err)
+		return reservation, false
+	}
+
+	return reservation, true
+}
+
+// loadCharges reads the taxes and fees stored with a reservation and its
+// lines, and the payments and refunds of the booking.
+func (repo *Repository) loadCharges(ctx context.Context, res *models.Reservation) error {
+	var err error
+
+	res.Charges, err = repo.DB.ReservationCharges(ctx, res.ID)
+	if err != nil {
+		return err
+	}
+	res.Payments, err = repo.DB.ReservationPayments(ctx, res.ID)
+	if err != nil {
+		return err
+	}
+	res.Refunds, err = repo.DB.ReservationRefunds(ctx, res.ID)
+	if err != nil {
+		return err
+	}
+	for i := range res.Lines {
+		res.Lines[i].Charges, err = repo.DB.ReservationCharges(ctx, res.Lines[i].ID)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// loadBooking reads a booking with its rooms, charges, payments and refunds.
+// The id may be that of any room of the booking.
+func (repo *Repository) loadBooking(ctx context.Context, id int) (models.Reservation, error) {
+	reservation, err := repo.DB.GetReservationById(ctx, id)
+	if err != nil {
+		return reservation, err
+	}
+	if reservation.ParentId > 0 {
+		reservation, err = repo.DB.GetReservationById(ctx, reservation.ParentId)
+		if err != nil {
+			return reservation, err
+		}
+	}
+
+	reservation.Lines, err = repo.activeLines(ctx, reservation.ID)
+	if err != nil {
+		return reservation, err
+	}
+	err = repo.loadCharges(ctx, &reservation)
+	return reservation, err
+}
+
+// activeLines returns the extra rooms of a booking that are not deleted.
+func (repo *Repository) activeLines(ctx context.Context, id int) ([]models.Reservation, error) {
+	lines, err := repo.DB.ReservationLines(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	active := lines[:0]
+	for _, line := range lines {
+		if line.DeletedAt.IsZero() {
+			active = append(active, line)
+		}
+	}
+	return active, nil
+}
+
+// bookingRoom is a room of a booking as shown on the manage booking page.
+type bookingRoom struct {
+	models.Reservation
+	CanChange bool
+	Penalty   int
+}
+
+func (repo *Repository) cancellationPolicy(ctx context.Context, reservation models.Reservation) (models.CancellationPolicy, error) {
+	policy, err := repo.DB.GetCancellationPolicyByRoomTypeId(ctx, reservation.RoomTypeId)
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.CancellationPolicy{
+			Name:                 "Default",
+			FreeCancellationDays: repo.App.CancellationDays,
+			PenaltyType:          models.PenaltyNone,
+		}, nil
+	}
+	return policy, err
+}
+
+// changedDiscount works out the promo code discount of a room moved to new
+// dates. Percent codes are recalculated, a fixed discount is kept up to the
+// new price, and nothing is taken off when the code no longer covers the stay.
+func (repo *Repository) changedDiscount(ctx context.Context, reservation models.Reservation, total int, start, end time.Time) (int, error) {
+	if reservation.PromoCodeId == 0 {
+		return 0, nil
+	}
+
+	promo, err := repo.DB.GetPromoCodeById(ctx, reservation.PromoCodeId)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	if promo.StayViolation(reservation.RoomTypeId, start, end) != "" {
+		return 0, nil
+	}
+	if promo.DiscountType == models.DiscountFixed {
+		promo.DiscountValue = reservation.Discount
+	}
+	return promo.Discounts([]int{total}, []bool{true})[0], nil
+}
+
+// reservationAmounts returns the total and first night price of a reservation
+// in cents, as booked. Reservations made before these were stored fall back
+// to current rates.
+func (repo *Repository) reservationAmounts(ctx context.Context, reservation models.Reservation) (int, int, error) {
+	total, firstNight := reservation.Total, reservation.FirstNight
+	if total == 0 || firstNight == 0 {
+		quote, err := pricing.QuoteRoomType(ctx, repo.DB, reservation.RoomTypeId, reservation.StartDate, reservation.EndDate)
+		if err != nil {
+			return 0, 0, err
+		}
+		if total == 0 {
+			total = quote.Total
+		}
+		if firstNight == 0 {
+			firstNight = quote.DiscountedFirstNight(reservation.Discount)
+		}
+	}
+
+	if firstNight > total {
+		firstNight = total
+	}
+	return total, firstNight, nil
+}
+
+func (repo *Repository) cancellationPenalty(ctx context.Context, reservation models.Reservation) (models.CancellationPolicy, int, error) {
+	policy, err := repo.cancellationPolicy(ctx, reservation)
+	if err != nil {
+		return policy, 0, err
+	}
+
+	total, firstNight, err := repo.reservationAmounts(ctx, reservation)
+	if err != nil {
+		return policy, 0, err
+	}
+	return policy, policy.Penalty(reservation.StartDate, time.Now(), total, firstNight), nil
+}
+
+// cancelRooms cancels the rooms together, each charged the penalty of its
+// cancellation policy. No room is cancelled when one of them cannot be.
+func (repo *Repository) cancelRooms(ctx context.Context, rooms []models.Reservation) error {
+	cancelled := make([]models.Reservation, len(rooms))
+	for i, room := range rooms {
+		_, penalty, err := repo.cancellationPenalty(ctx, room)
+		if err != nil {
+			return err
+		}
+		cancelled[i] = room
+		cancelled[i].CancellationPenalty = penalty
+	}
+
+	return repo.DB.ChangeRoomsStatus(ctx, cancelled, models.StatusCancelled)
+}
+
+func isChangeable(reservation models.Reservation) bool {
+	return reservation.Status == models.StatusPending || reservation.Status == models.StatusConfirmed
+}
+
+// canChangeOnline reports whether the guest may still cancel the room or
+// change its dates.
+func canChangeOnline(reservation models.Reservation) bool {
+	return isChangeable(reservation) && time.Now().Before(reservation.StartDate)
+}
+
+// bookingLine finds a room of the booking by its reservation id.
+func bookingLine(reservation models.Reservation, id int) (models.Reservation, bool) {
+	for _, line := range reservation.Rooms() {
+		if line.ID == id {
+			return line, true
+		}
+	}
+	return models.Reservation{}, false
+}
+
+func (repo *Repository) ManageBooking(w http.ResponseWriter, r *http.Request) {
+	reservation, ok := repo.manageReservation(w, r)
+	if !ok {
+		return
+	}
+
+	policy, _, err := repo.cancellationPenalty(r.Context(), reservation)
+	if err != nil {
+		helpers.ServerError(w, err)
+		return
+	}
+
+	var rooms []bookingRoom
+	var penalty int
+	var canChange bool
+	for _, line := range reservation.Rooms() {
+		room := bookingRoom{Reservation: line, CanChange: canChangeOnline(line)}
+		if room.CanChange {
+			_, room.Penalty, err = repo.cancellationPenalty(r.Context(), line)
+			if err != nil {
+				helpers.ServerError(w, err)
+				return
+			}
+			penalty += room.Penalty
+			canChange = true
+		}
+		rooms = append(rooms, room)
+	}
+
+	data := make(map[string]interface{})
+	data["reservation"] = reservation
+	data["rooms"] = rooms
+	data["policy"] = policy
+	data["free_cancellation"] = policy.IsFree(reservation.StartDate, time.Now())
+	data["can_cancel"] = canChange
+	data["can_change"] = canChange
+
+	intMap := make(map[string]int)
+	intMap["penalty"] = penalty
+
+	stringMap := make(map[string]string)
+	stringMap["manage_url"] = r.URL.Path
+	stringMap["cancel_deadline"] = policy.Deadline(reservation.StartDate).Format("2006-01-02")
+
+	render.RenderTemplate(w, r, "manage-booking.page.html", &models.TemplateData{
+		Data:      data,
+		StringMap: stringMap,
+		IntMap:    intMap,
+	})
+}
+
+// PostCancelBooking cancels every room of the booking that can still be
+// cancelled online.
+func (repo *Repository) PostCancelBooking(w http.ResponseWriter, r *http.Request) {
+	reservation, ok := repo.manageReservation(w, r)
+	if !ok {
+		return
+	}
+
+	manageURL := fmt.Sprintf("/manage-booking/%s/%s", chi.URLParam(r, "code"), chi.URLParam(r, "signature"))
+
+	var rooms []models.Reservation
+	for _, line := range reservation.Rooms() {
+		if canChangeOnline(line) {
+			rooms = append(rooms, line)
+		}
+	}
+
+	var err error
+	if len(rooms) > 0 {
+		err = repo.cancelRooms(r.Context(), rooms)
+	}
+	if len(rooms) == 0 || errors.Is(err, repository.ErrInvalidStatusTransition) {
+		repo.App.Session.Put(r.Context(), "error", "This reservation can no longer be cancelled online")
+		http.Redirect(w, r, manageURL, http.StatusSeeOther)
+		return
+	}
+	if err != nil {
+		helpers.ServerError(w, err)
+		return
+	}
+
+	if err = repo.syncLedger(r, reservation.ID); err != nil {
+		helpers.ServerError(w, err)
+		return
+	}
+
+	repo.App.Session.Put(r.Context(), "flash", "Your reservation has been cancelled")
+	http.Redirect(w, r, manageURL, http.StatusSeeOther)
+}
+
+// PostCancelBookingRoom cancels one room of a multi-room booking and keeps
+// the others.
+func (repo *Repository) PostCancelBookingRoom(w http.ResponseWriter, r *http.Request) {
+	reservation, ok := repo.manageReservation(w, r)
+	if !ok {
+		return
+	}
+
+	manageURL := fmt.Sprintf("/manage-booking/%s/%s", chi.URLParam(r, "code"), chi.URLParam(r, "signature"))
+
+	id, _ := strconv.Atoi(chi.URLParam(r, "id"))
+	line, ok := bookingLine(reservation, id)
+	if !ok {
+		helpers.ClientError(w, http.StatusNotFound)
+		return
+	}
+
+	if !canChangeOnline(line) {
+		repo.App.Session.Put(r.Context(), "error", "This room can no longer be cancelled online")
+		http.Redirect(w, r, manageURL, http.StatusSeeOther)
+		return
+	}
+
+	err := repo.cancelRooms(r.Context(), []models.Reservation{line})
+	if errors.Is(err, repository.ErrInvalidStatusTransition) {
+		repo.App.Session.Put(r.Context(), "error", "This room can no longer be cancelled online")
+		http.Redirect(w, r, manageURL, http.StatusSeeOther)
+		return
+	}
+	if err != nil {
+		helpers.ServerError(w, err)
+		return
+	}
+
+	if err = repo.syncLedger(r, reservation.ID); err != nil {
+		helpers.ServerError(w, err)
+		return
+	}
+
+	repo.App.Session.Put(r.Context(), "flash", fmt.Sprintf("%s has been cancelled", line.RoomType.Name))
+	http.Redirect(w, r, manageURL, http.StatusSeeOther)
+}
+
+func (repo *Repository) PostChangeBookingDates(w http.ResponseWriter, r *http.Request) {
+	reservation, ok := repo.manageReservation(w, r)
+	if !ok {
+		return
+	}
+
+	manageURL := fmt.Sprintf("/manage-booking/%s/%s", chi.URLParam(r, "code"), chi.URLParam(r, "signature"))
+
+	err := r.ParseForm()
+	if err != nil {
+		helpers.ServerError(w, err)
+		return
+	}
+
+	if id, err := strconv.Atoi(r.Form.Get("line")); err == nil {
+		line, ok := bookingLine(reservation, id)
+		if !ok {
+			helpers.ClientError(w, http.StatusNotFound)
+			return
+		}
+		reservation = line
+	}
+
+	if !canChangeOnline(reservation) {
+		repo.App.Session.Put(r.Context(), "error", "The dates of this reservation can no longer be changed online")
+		http.Redirect(w, r, manageURL, http.StatusSeeOther)
+		return
+	}
+
+	form := forms.New(r.PostForm)
+	startDate, endDate := form.DateRange("start", "end", repo.App.BookingHorizonDays)
+	if !form.Valid() {
+		repo.App.Session.Put(r.Context(), "error", dateRangeError(form, "start", "end"))
+		http.Redirect(w, r, manageURL, http.StatusSeeOther)
+		return
+	}
+
+	violation, err := repo.stayViolation(r.Context(), reservation.RoomTypeId, startDate, endDate)
+	if err != nil {
+		helpers.ServerError(w, err)
+		return
+	}
+	if violation != "" {
+		repo.App.Session.Put(r.Context(), "error", violation)
+		http.Redirect(w, r, manageURL, http.StatusSeeOther)
+		return
+	}
+
+	quote, err := pricing.QuoteRoomType(r.Context(), repo.DB, reservation.RoomTypeId, startDate, endDate)
+	if err != nil {
+		helpers.ServerError(w, err)
+		return
+	}
+
+	discount, err := repo.changedDiscount(r.Context(), reservation, quote.Total, startDate, endDate)
+	if err != nil {
+		helpers.ServerError(w, err)
+		return
+	}
+
+	catalog, err := repo.DB.AllTaxFees(r.Context())
+	if err != nil {
+		helpers.ServerError(w, err)
+		return
+	}
+
+	reservation.StartDate = startDate
+	reservation.EndDate = endDate
+	reservation.Discount = discount
+	reservation.Total = quote.Total - discount
+	reservation.FirstNight = quote.DiscountedFirstNight(discount)
+	reservation.Charges = pricing.Charges(catalog, reservation)
+
+	err = repo.DB.ChangeReservationDates(r.Context(), reservation)
+	if errors.Is(err, repository.ErrRoomUnavailable) {
+		repo.App.Session.Put(r.Context(), "error", "The room is not available for the selected dates")
+		http.Redirect(w, r, manageURL, http.StatusSeeOther)
+		return
+	}
+	if err != nil {
+		helpers.ServerError(w, err)
+		return
+	}
+
+	if err = repo.syncLedger(r, reservation.ID); err != nil {
+		helpers.ServerError(w, err)
+		return
+	}
+
+	repo.App.Session.Put(r.Context(), "flash", "Your reservation dates have been changed")
+	http.Redirect(w, r, manageURL, http.StatusSeeOther)
+}
@@ -0,0 +1,402 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/NhanNT-VNG/hotel-booking/internal/forms"
+	"github.com/NhanNT-VNG/hotel-booking/internal/helpers"
+	"github.com/NhanNT-VNG/hotel-booking/internal/models"
+	"github.com/NhanNT-VNG/hotel-booking/internal/payments"
+	"github.com/NhanNT-VNG/hotel-booking/internal/render"
+	"github.com/NhanNT-VNG/hotel-booking/internal/repository"
+)
+
+// unpaidReservation returns the booking of the session when it is still
+// waiting for payment. Otherwise it redirects and reports false.
+func (repo *Repository) unpaidReservation(w http.ResponseWriter, r *http.Request) (models.Reservation, models.Reservation, bool) {
+	reservation, ok := repo.App.Session.Get(r.Context(), "reservation").(models.Reservation)
+	if !ok || reservation.ID == 0 {
+		repo.App.Session.Put(r.Context(), "error", "Can't get reservation from session")
+		http.Redirect(w, r, "/", http.StatusSeeOther)
+		return reservation, models.Reservation{}, false
+	}
+
+	current, err := repo.DB.GetReservationById(r.Context(), reservation.ID)
+	if err != nil {
+		helpers.ServerError(w, err)
+		return reservation, current, false
+	}
+
+	switch current.Status {
+	case models.StatusPending:
+		return reservation, current, true
+	case models.StatusCancelled:
+		repo.App.Session.Put(r.Context(), "error", "This reservation has been cancelled")
+		http.Redirect(w, r, "/", http.StatusSeeOther)
+	default:
+		http.Redirect(w, r, "/reservation-summary", http.StatusSeeOther)
+	}
+	return reservation, current, false
+}
+
+// paymentDeadline is when a booking made at the given time is cancelled if
+// it has not been paid.
+func (repo *Repository) paymentDeadline(createdAt time.Time) time.Time {
+	return createdAt.Add(time.Duration(repo.App.PaymentMinutes) * time.Minute)
+}
+
+// CancelUnpaidReservations cancels the bookings that were not paid by their
+// payment deadline, so they stop holding rooms. It returns how many were
+// cancelled.
+func (repo *Repository) CancelUnpaidReservations(ctx context.Context) (int, error) {
+	cutoff := time.Now().Add(-time.Duration(repo.App.PaymentMinutes) * time.Minute)
+	unpaid, err := repo.DB.UnpaidReservations(ctx, cutoff)
+	if err != nil {
+		return 0, err
+	}
+
+	cancelled := 0
+	for _, reservation := range unpaid {
+		ok, err := repo.DB.CancelUnpaidReservation(ctx, reservation.ID)
+		if err != nil {
+			return cancelled, err
+		}
+		if !ok {
+			continue
+		}
+		cancelled++
+
+		if err = repo.postLedgerChanges(ctx, reservation.ID, 0); err != nil {
+			return cancelled, err
+		}
+	}
+	return cancelled, nil
+}
+
+// renderPayment shows the payment form for the booking of the session.
+// current is the booking as stored, which tells when it must be paid by.
+func (repo *Repository) renderPayment(w http.ResponseWriter, r *http.Request, reservation, current models.Reservation, form *forms.Form) {
+	data := make(map[string]interface{})
+	data["reservation"] = reservation
+
+	stringMap := make(map[string]string)
+	stringMap["pay_by"] = repo.paymentDeadline(current.CreatedAt).Format("15:04")
+
+	total := reservation.BookingTotal()
+	intMap := make(map[string]int)
+	intMap["amount_due"] = models.AmountDue(total, repo.App.PaymentMode, repo.App.DepositPercent)
+	intMap["balance"] = total - intMap["amount_due"]
+
+	render.RenderTemplate(w, r, "payment.page.html", &models.TemplateData{
+		Form:      form,
+		Data:      data,
+		IntMap:    intMap,
+		StringMap: stringMap,
+	})
+}
+
+func (repo *Repository) Payment(w http.ResponseWriter, r *http.Request) {
+	reservation, current, ok := repo.unpaidReservation(w, r)
+	if !ok {
+		return
+	}
+
+	repo.renderPayment(w, r, reservation, current, forms.New(nil))
+}
+
+func (repo *Repository) PostPayment(w http.ResponseWriter, r *http.Request) {
+	err := r.ParseForm()
+	if err != nil {
+		helpers.ServerError(w, err)
+		return
+	}
+
+	reservation, current, ok := repo.unpaidReservation(w, r)
+	if !ok {
+		return
+	}
+
+	form := forms.New(r.PostForm)
+	form.Required("card_name", "card_number")
+	if !form.Valid() {
+		repo.renderPayment(w, r, reservation, current, form)
+		return
+	}
+
+	gateway := repo.App.Payments
+	total := reservation.BookingTotal()
+
+	payment := models.Payment{
+		ReservationId: reservation.ID,
+		Provider:      gateway.Name(),
+		Kind:          models.PaymentFull,
+		Amount:        models.AmountDue(total, repo.App.PaymentMode, repo.App.DepositPercent),
+	}
+	if payment.Amount < total {
+		payment.Kind = models.PaymentDeposit
+	}
+
+	// the payment is recorded before the gateway is called, so a second
+	// submit of the form cannot charge the guest twice
+	payment.Status = models.PaymentProcessing
+	payment.ID, err = repo.DB.InsertPayment(r.Context(), payment)
+	if errors.Is(err, repository.ErrPaymentInProgress) {
+		repo.App.Session.Put(r.Context(), "error", "This reservation is already being paid")
+		http.Redirect(w, r, "/payment", http.StatusSeeOther)
+		return
+	}
+	if errors.Is(err, repository.ErrReservationNotPending) {
+		// cancelled or paid since the form was shown
+		http.Redirect(w, r, "/payment", http.StatusSeeOther)
+		return
+	}
+	if err != nil {
+		helpers.ServerError(w, err)
+		return
+	}
+
+	result, err := gateway.Authorize(r.Context(), payment.Amount, r.Form.Get("card_number"), reservation.ConfirmationCode)
+	if err != nil {
+		message := result.Message
+		if !errors.Is(err, payments.ErrDeclined) {
+			message = err.Error()
+		}
+		if err := repo.DB.UpdatePaymentStatus(r.Context(), payment.ID, models.PaymentFailed, message); err != nil {
+			helpers.ServerError(w, err)
+			return
+		}
+		if !errors.Is(err, payments.ErrDeclined) {
+			helpers.ServerError(w, err)
+			return
+		}
+
+		form.Errors.Add("card_number", result.Message)
+		repo.renderPayment(w, r, reservation, current, form)
+		return
+	}
+
+	payment.ProviderRef = result.ID
+	payment.Status = models.PaymentAuthorized
+	err = repo.DB.AuthorizePayment(r.Context(), payment.ID, payment.ProviderRef)
+	if err != nil {
+		helpers.ServerError(w, err)
+		return
+	}
+
+	_, err = gateway.Capture(r.Context(), payment.ProviderRef, payment.Amount)
+	if err != nil {
+		// release the hold on the card so the guest can try again
+		repo.App.ErrorLog.Println("capture:", err)
+		if _, err := gateway.Void(r.Context(), payment.ProviderRef); err != nil {
+			repo.App.ErrorLog.Println("void:", err)
+		}
+
+		err = repo.DB.UpdatePaymentStatus(r.Context(), payment.ID, models.PaymentFailed, "Payment could not be captured")
+		if err != nil {
+			helpers.ServerError(w, err)
+			return
+		}
+
+		repo.App.Session.Put(r.Context(), "error", "We could not take your payment, please try again")
+		http.Redirect(w, r, "/payment", http.StatusSeeOther)
+		return
+	}
+
+	err = repo.confirmBooking(r, current)
+	if err != nil {
+		// the guest must not pay for a booking that was not confirmed
+		repo.App.ErrorLog.Println("confirm booking:", err)
+		if _, err := gateway.Refund(r.Context(), payment.ProviderRef, payment.Amount); err != nil {
+			helpers.ServerError(w, err)
+			return
+		}
+
+		err = repo.DB.UpdatePaymentStatus(r.Context(), payment.ID, models.PaymentFailed, "Reservation could not be confirmed, the payment was refunded")
+		if err != nil {
+			helpers.ServerError(w, err)
+			return
+		}
+
+		repo.App.Session.Put(r.Context(), "error", "We could not confirm your reservation, your payment has been refunded")
+		http.Redirect(w, r, "/", http.StatusSeeOther)
+		return
+	}
+
+	payment.Status = models.PaymentCaptured
+	err = repo.DB.UpdatePaymentStatus(r.Context(), payment.ID, payment.Status, "")
+	if err != nil {
+		helpers.ServerError(w, err)
+		return
+	}
+
+	if err = repo.ledgerPayment(r, payment); err != nil {
+		helpers.ServerError(w, err)
+		return
+	}
+
+	reservation.Status = models.StatusConfirmed
+	for i := range reservation.Lines {
+		reservation.Lines[i].Status = models.StatusConfirmed
+	}
+	reservation.Payments = append(reservation.Payments, payment)
+
+	repo.sendConfirmation(r.Context(), reservation)
+
+	repo.App.Session.Put(r.Context(), "reservation", reservation)
+	repo.App.Session.Put(r.Context(), "flash", "Payment received, your reservation is confirmed")
+	http.Redirect(w, r, "/reservation-summary", http.StatusSeeOther)
+}
+
+// confirmBooking marks a booking that is waiting for payment as confirmed,
+// together with its other rooms.
+func (repo *Repository) confirmBooking(r *http.Request, reservation models.Reservation) error {
+	if reservation.Status != models.StatusPending {
+		return nil
+	}
+
+	rooms, err := repo.bookingRooms(r.Context(), reservation)
+	if err != nil {
+		return err
+	}
+	if err = repo.DB.ChangeRoomsStatus(r.Context(), rooms, models.StatusConfirmed); err != nil {
+		return err
+	}
+
+	for _, room := range rooms {
+		repo.auditReservation(r, models.AuditActionStatus, room)
+	}
+	return nil
+}
+
+var webhookStatuses = map[string]string{
+	payments.EventCaptured: models.PaymentCaptured,
+	payments.EventFailed:   models.PaymentFailed,
+}
+
+// recordProviderRefund records a refund the provider reports, such as one
+// made from its dashboard, the same way as a refund made here. The event
+// amount is the total refunded of the payment, so refunds already recorded
+// here are not recorded twice.
+func (repo *Repository) recordProviderRefund(ctx context.Context, payment models.Payment, event payments.Event) error {
+	reservation, err := repo.loadBooking(ctx, payment.ReservationId)
+	if err != nil {
+		return err
+	}
+
+	total := event.Amount
+	if total <= 0 || total > payment.Amount {
+		total = payment.Amount
+	}
+	for _, refund := range reservation.Refunds {
+		if refund.PaymentId == payment.ID && refund.Status != models.RefundFailed {
+			total -= refund.Amount
+		}
+	}
+	if total <= 0 || (payment.Status != models.PaymentCaptured && payment.Status != models.PaymentRefunded) {
+		return nil
+	}
+
+	refunds, err := repo.DB.InsertPendingRefunds(ctx, reservation.ID, reservation.Refunded(), []models.Refund{{
+		PaymentId:   payment.ID,
+		Amount:      total,
+		Reason:      "Refunded at the payment provider",
+		ProviderRef: payment.ProviderRef,
+	}})
+	if err != nil {
+		return err
+	}
+
+	description := fmt.Sprintf("Refund (%s %s)", payment.Provider, payment.ProviderRef)
+	return repo.DB.CompleteRefund(ctx, refunds[0], description)
+}
+
+// PaymentWebhook applies the payment updates the provider sends. Events
+// about payments this site does not know are acknowledged and ignored.
+func (repo *Repository) PaymentWebhook(w http.ResponseWriter, r *http.Request) {
+	payload, err := io.ReadAll(io.LimitReader(r.Body, 1<<20))
+	if err != nil {
+		helpers.ClientError(w, http.StatusBadRequest)
+		return
+	}
+
+	gateway := repo.App.Payments
+	event, err := gateway.VerifyWebhook(payload, r.Header.Get("X-Payment-Signature"))
+	if err != nil {
+		helpers.ClientError(w, http.StatusBadRequest)
+		return
+	}
+
+	status, ok := webhookStatuses[event.Type]
+	if !ok && event.Type != payments.EventRefunded {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	payment, err := repo.DB.GetPaymentByProviderRef(r.Context(), gateway.Name(), event.PaymentId)
+	if errors.Is(err, sql.ErrNoRows) {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	if err != nil {
+		helpers.ServerError(w, err)
+		return
+	}
+
+	if event.Type == payments.EventRefunded {
+		if err = repo.recordProviderRefund(r.Context(), payment, event); err != nil {
+			helpers.ServerError(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	if payment.Status != status {
+		err = repo.DB.UpdatePaymentStatus(r.Context(), payment.ID, status, event.Message)
+		if err != nil {
+			helpers.ServerError(w, err)
+			return
+		}
+
+		if status == models.PaymentCaptured && payment.Status == models.PaymentAuthorized {
+			if err = repo.ledgerPayment(r, payment); err != nil {
+				helpers.ServerError(w, err)
+				return
+			}
+		}
+	}
+
+	if status == models.PaymentCaptured {
+		reservation, err := repo.DB.GetReservationById(r.Context(), payment.ReservationId)
+		if err != nil {
+			helpers.ServerError(w, err)
+			return
+		}
+
+		if reservation.Status == models.StatusPending {
+			if err = repo.confirmBooking(r, reservation); err != nil {
+				helpers.ServerError(w, err)
+				return
+			}
+
+			reservation.Status = models.StatusConfirmed
+			reservation.Lines, err = repo.activeLines(r.Context(), reservation.ID)
+			if err == nil {
+				err = repo.loadCharges(r.Context(), &reservation)
+			}
+			if err != nil {
+				helpers.ServerError(w, err)
+				return
+			}
+			repo.sendConfirmation(r.Context(), reservation)
+		}
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
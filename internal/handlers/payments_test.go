@@ -0,0 +1,331 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/NhanNT-VNG/hotel-booking/internal/models"
+	"github.com/NhanNT-VNG/hotel-booking/internal/payments"
+	"github.com/NhanNT-VNG/hotel-booking/internal/repository"
+)
+
+// captureHookGateway runs onCapture before capturing, to change the booking
+// while its payment is under way.
+type captureHookGateway struct {
+	*payments.FakeGateway
+	onCapture func()
+}
+
+func (g *captureHookGateway) Capture(ctx context.Context, paymentId string, amount int) (payments.Result, error) {
+	g.onCapture()
+	return g.FakeGateway.Capture(ctx, paymentId, amount)
+}
+
+// refusingGateway declines every refund.
+type refusingGateway struct {
+	payments.PaymentGateway
+}
+
+func (g refusingGateway) Refund(ctx context.Context, paymentId string, amount int) (payments.Result, error) {
+	return payments.Result{Message: "Refunds are not possible"}, payments.ErrDeclined
+}
+
+// cancelledPaidBooking makes a $300.00 booking with $200.00 captured and
+// cancels it with a $100.00 penalty, leaving $100.00 to refund.
+func cancelledPaidBooking(t *testing.T, days int, lastName string) (int, models.Payment) {
+	t.Helper()
+
+	ctx := context.Background()
+	startDate := time.Now().Truncate(24*time.Hour).AddDate(0, 0, days)
+
+	reservationId, err := Repo.DB.CreateReservation(ctx, models.Reservation{
+		FirstName:  "Jane",
+		LastName:   lastName,
+		Email:      "jane@example.com",
+		StartDate:  startDate,
+		EndDate:    startDate.AddDate(0, 0, 2),
+		RoomTypeId: 2,
+		Total:      30000,
+	}, models.RestrictionReservation, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	authorized, err := app.Payments.Authorize(ctx, 20000, "4242424242424242", lastName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = app.Payments.Capture(ctx, authorized.ID, 20000); err != nil {
+		t.Fatal(err)
+	}
+	payment := models.Payment{
+		ReservationId: reservationId,
+		Provider:      app.Payments.Name(),
+		ProviderRef:   authorized.ID,
+		Kind:          models.PaymentDeposit,
+		Status:        models.PaymentProcessing,
+		Amount:        20000,
+	}
+	payment.ID, err = Repo.DB.InsertPayment(ctx, payment)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = Repo.DB.AuthorizePayment(ctx, payment.ID, authorized.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err = Repo.DB.UpdatePaymentStatus(ctx, payment.ID, models.PaymentCaptured, ""); err != nil {
+		t.Fatal(err)
+	}
+	if err = Repo.DB.CancelReservation(ctx, reservationId, 10000); err != nil {
+		t.Fatal(err)
+	}
+	payment.Status = models.PaymentCaptured
+
+	return reservationId, payment
+}
+
+// refundTotals returns what was refunded of a booking by refund status, and
+// the refunds in its ledger.
+func refundTotals(t *testing.T, reservationId int) (map[string]int, int) {
+	t.Helper()
+
+	ctx := context.Background()
+	refunds, err := Repo.DB.ReservationRefunds(ctx, reservationId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	totals := make(map[string]int)
+	for _, refund := range refunds {
+		totals[refund.Status] += refund.Amount
+	}
+
+	entries, err := Repo.DB.LedgerEntries(ctx, reservationId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return totals, models.LedgerSum(entries, models.LedgerRefund)
+}
+
+// pendingReservation books room type 2 as a guest and returns the booking
+// waiting for payment.
+func pendingReservation(t *testing.T, g *guest, days int, lastName string) models.Reservation {
+	t.Helper()
+
+	start := time.Now().AddDate(0, 0, days).Format("2006-01-02")
+	end := time.Now().AddDate(0, 0, days+2).Format("2006-01-02")
+
+	g.post("/search-availability", url.Values{"start": {start}, "end": {end}})
+	g.get("/choose-room/2")
+	path, page := g.post("/make-reservation", url.Values{
+		"first_name": {"Pat"},
+		"last_name":  {lastName},
+		"email":      {"pat@example.com"},
+		"phone":      {"555-555-5555"},
+	})
+	expectPage(t, path, page, "/payment", "Please pay by")
+
+	unpaid, err := Repo.DB.UnpaidReservations(context.Background(), time.Now().Add(time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, reservation := range unpaid {
+		if reservation.LastName == lastName {
+			return reservation
+		}
+	}
+	t.Fatalf("no pending reservation for %s", lastName)
+	return models.Reservation{}
+}
+
+func TestCancelUnpaidReservations(t *testing.T) {
+	ts := httptest.NewTLSServer(getRoutes())
+	defer ts.Close()
+
+	ctx := context.Background()
+	reservation := pendingReservation(t, newGuest(t, ts), 60, "Unpaid")
+
+	if n, err := Repo.CancelUnpaidReservations(ctx); err != nil || n != 0 {
+		t.Fatalf("expected nothing cancelled before the deadline but got %d, %v", n, err)
+	}
+
+	app.PaymentMinutes = -1
+	defer func() { app.PaymentMinutes = 30 }()
+
+	paymentId, err := Repo.DB.InsertPayment(ctx, models.Payment{
+		ReservationId: reservation.ID,
+		Provider:      app.Payments.Name(),
+		Kind:          models.PaymentFull,
+		Status:        models.PaymentProcessing,
+		Amount:        1000,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ok, err := Repo.DB.CancelUnpaidReservation(ctx, reservation.ID); err != nil || ok {
+		t.Errorf("expected a booking being paid to be kept but got %t, %v", ok, err)
+	}
+
+	if err = Repo.DB.UpdatePaymentStatus(ctx, paymentId, models.PaymentFailed, "declined"); err != nil {
+		t.Fatal(err)
+	}
+	n, err := Repo.CancelUnpaidReservations(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n < 1 {
+		t.Errorf("expected the unpaid booking to be cancelled but got %d", n)
+	}
+
+	current, err := Repo.DB.GetReservationById(ctx, reservation.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if current.Status != models.StatusCancelled {
+		t.Errorf("expected the booking to be cancelled but it is %s", current.Status)
+	}
+
+	_, err = Repo.DB.InsertPayment(ctx, models.Payment{
+		ReservationId: reservation.ID,
+		Provider:      app.Payments.Name(),
+		Status:        models.PaymentProcessing,
+		Amount:        1000,
+	})
+	if !errors.Is(err, repository.ErrReservationNotPending) {
+		t.Errorf("expected %v but got %v", repository.ErrReservationNotPending, err)
+	}
+}
+
+func TestPostPaymentRefundsWhenNotConfirmed(t *testing.T) {
+	ts := httptest.NewTLSServer(getRoutes())
+	defer ts.Close()
+
+	ctx := context.Background()
+	g := newGuest(t, ts)
+	reservation := pendingReservation(t, g, 70, "Race")
+
+	gateway := &captureHookGateway{FakeGateway: payments.NewFakeGateway("test-webhook-secret")}
+	gateway.onCapture = func() {
+		if ok, err := Repo.DB.CancelUnpaidReservation(ctx, reservation.ID); err != nil || ok {
+			t.Errorf("expected the booking being paid to be kept but got %t, %v", ok, err)
+		}
+		// an admin cancels the booking while the card is charged
+		if err := Repo.DB.CancelReservation(ctx, reservation.ID, 0); err != nil {
+			t.Fatal(err)
+		}
+	}
+	saved := app.Payments
+	app.Payments = gateway
+	defer func() { app.Payments = saved }()
+
+	path, page := g.post("/payment", url.Values{"card_name": {"Pat Race"}, "card_number": {"4242424242424242"}})
+	expectPage(t, path, page, "/", "We could not confirm your reservation, your payment has been refunded")
+
+	paid, err := Repo.DB.ReservationPayments(ctx, reservation.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(paid) != 1 || paid[0].Status != models.PaymentFailed {
+		t.Fatalf("expected one failed payment but got %+v", paid)
+	}
+	if _, err = gateway.Refund(ctx, paid[0].ProviderRef, 1); !errors.Is(err, payments.ErrInvalidAmount) {
+		t.Errorf("expected the payment to be refunded in full at the gateway but got %v", err)
+	}
+}
+
+func TestAdminPostRefundGatewayRefuses(t *testing.T) {
+	ts := httptest.NewTLSServer(getRoutes())
+	defer ts.Close()
+
+	reservationId, _ := cancelledPaidBooking(t, 80, "Refused")
+	g := newGuest(t, ts)
+	refundURL := fmt.Sprintf("/admin/reservations/all/%d/refund", reservationId)
+
+	saved := app.Payments
+	app.Payments = refusingGateway{saved}
+	_, page := g.post(refundURL, url.Values{"amount": {"40.00"}})
+	app.Payments = saved
+
+	if !strings.Contains(page, "the payment provider refused to refund $40.00") {
+		t.Error("expected the refusal to be reported")
+	}
+	totals, ledger := refundTotals(t, reservationId)
+	if totals[models.RefundFailed] != 4000 || totals[models.RefundSucceeded] != 0 || ledger != 0 {
+		t.Errorf("expected one failed refund and nothing in the ledger but got %v, %d", totals, ledger)
+	}
+
+	// the failed refund does not count against what is left to refund
+	_, page = g.post(refundURL, url.Values{"amount": {"100.00"}})
+	if !strings.Contains(page, "Refunded $100.00") {
+		t.Error("expected the full refund to go through")
+	}
+	totals, ledger = refundTotals(t, reservationId)
+	if totals[models.RefundSucceeded] != 10000 || ledger != 10000 {
+		t.Errorf("expected $100.00 refunded but got %v, %d", totals, ledger)
+	}
+}
+
+func TestPaymentWebhookRecordsRefunds(t *testing.T) {
+	ts := httptest.NewTLSServer(getRoutes())
+	defer ts.Close()
+
+	ctx := context.Background()
+	gateway := app.Payments.(*payments.FakeGateway)
+	reservationId, payment := cancelledPaidBooking(t, 90, "Dashboard")
+
+	_, page := newGuest(t, ts).post(fmt.Sprintf("/admin/reservations/all/%d/refund", reservationId), url.Values{"amount": {"40.00"}})
+	if !strings.Contains(page, "Refunded $40.00") {
+		t.Fatal("expected the refund to go through")
+	}
+
+	send := func(amount int) {
+		payload, err := json.Marshal(payments.Event{Type: payments.EventRefunded, PaymentId: payment.ProviderRef, Amount: amount})
+		if err != nil {
+			t.Fatal(err)
+		}
+		req := httptest.NewRequest("POST", "/payments/webhook", bytes.NewReader(payload))
+		req.Header.Set("X-Payment-Signature", gateway.Sign(payload))
+		rr := httptest.NewRecorder()
+		Repo.PaymentWebhook(rr, req)
+		if rr.Code != http.StatusOK {
+			t.Fatalf("expected %d from the webhook but got %d", http.StatusOK, rr.Code)
+		}
+	}
+
+	var tests = []struct {
+		name     string
+		amount   int
+		refunded int
+		status   string
+	}{
+		{"our own refund", 4000, 4000, models.PaymentCaptured},
+		{"refunded at the provider", 7000, 7000, models.PaymentCaptured},
+		{"sent again", 7000, 7000, models.PaymentCaptured},
+		{"all of the payment", 0, 20000, models.PaymentRefunded},
+	}
+
+	for _, e := range tests {
+		send(e.amount)
+
+		totals, ledger := refundTotals(t, reservationId)
+		if totals[models.RefundSucceeded] != e.refunded || ledger != e.refunded {
+			t.Errorf("%s: expected %d refunded but got %v, %d in the ledger", e.name, e.refunded, totals, ledger)
+		}
+
+		paid, err := Repo.DB.ReservationPayments(ctx, reservationId)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(paid) != 1 || paid[0].Status != e.status {
+			t.Errorf("%s: expected the payment to be %s but got %+v", e.name, e.status, paid)
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+
+	"github.com/NhanNT-VNG/hotel-booking/internal/helpers"
+	"github.com/NhanNT-VNG/hotel-booking/internal/models"
+	"github.com/NhanNT-VNG/hotel-booking/internal/pricing"
+	"github.com/NhanNT-VNG/hotel-booking/internal/render"
+	"github.com/go-chi/chi/v5"
+)
+
+func (repo *Repository) Rooms(w http.ResponseWriter, r *http.Request) {
+	roomTypes, err := repo.DB.AllRoomTypes(r.Context())
+	if err != nil {
+		helpers.ServerError(w, err)
+		return
+	}
+
+	prices := make(map[int]int)
+	for _, roomType := range roomTypes {
+		prices[roomType.ID], err = pricing.PriceFrom(r.Context(), repo.DB, roomType)
+		if err != nil {
+			helpers.ServerError(w, err)
+			return
+		}
+	}
+
+	data := make(map[string]interface{})
+	data["room_types"] = roomTypes
+	data["prices"] = prices
+
+	render.RenderTemplate(w, r, "rooms.page.html", &models.TemplateData{
+		Data: data,
+	})
+}
+
+func (repo *Repository) ShowRoom(w http.ResponseWriter, r *http.Request) {
+	roomType, err := repo.DB.GetRoomTypeBySlug(r.Context(), chi.URLParam(r, "slug"))
+	if errors.Is(err, sql.ErrNoRows) {
+		helpers.ClientError(w, http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		helpers.ServerError(w, err)
+		return
+	}
+
+	priceFrom, err := pricing.PriceFrom(r.Context(), repo.DB, roomType)
+	if err != nil {
+		helpers.ServerError(w, err)
+		return
+	}
+
+	data := make(map[string]interface{})
+	data["room_type"] = roomType
+
+	intMap := make(map[string]int)
+	intMap["price_from"] = priceFrom
+
+	render.RenderTemplate(w, r, "room.page.html", &models.TemplateData{
+		Data:   data,
+		IntMap: intMap,
+	})
+}
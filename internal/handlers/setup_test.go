@@ -0,0 +1,120 @@
+package handlers
+
+import (
+	"encoding/gob"
+	"log"
+	"net/http"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/NhanNT-VNG/hotel-booking/internal/config"
+	"github.com/NhanNT-VNG/hotel-booking/internal/helpers"
+	"github.com/NhanNT-VNG/hotel-booking/internal/models"
+	"github.com/NhanNT-VNG/hotel-booking/internal/payments"
+	"github.com/NhanNT-VNG/hotel-booking/internal/render"
+	"github.com/alexedwards/scs/v2"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/justinas/nosurf"
+)
+
+var app config.AppConfig
+var session *scs.SessionManager
+
+func TestMain(m *testing.M) {
+	gob.Register(models.Reservation{})
+	gob.Register(models.Guests{})
+	gob.Register(models.Room{})
+	gob.Register(models.Restriction{})
+	gob.Register(models.User{})
+
+	// templates are read relative to the repository root
+	if err := os.Chdir("../.."); err != nil {
+		log.Fatal(err)
+	}
+
+	app.InProduction = false
+	app.InfoLog = log.New(os.Stdout, "INFO\t", log.Ldate|log.Ltime)
+	app.ErrorLog = log.New(os.Stdout, "ERROR\t", log.Ldate|log.Ltime|log.Lshortfile)
+
+	session = scs.New()
+	session.Lifetime = 24 * time.Hour
+	session.Cookie.Persist = true
+	session.Cookie.SameSite = http.SameSiteLaxMode
+	session.Cookie.Secure = app.InProduction
+	app.Session = session
+
+	app.HoldMinutes = 15
+	app.SigningKey = "test-signing-key"
+	app.CancellationDays = 2
+	app.BookingHorizonDays = 365
+	app.MailFrom = "hotel@example.com"
+	app.Payments = payments.NewFakeGateway("test-webhook-secret")
+	app.PaymentMode = models.PaymentDeposit
+	app.DepositPercent = 30
+	app.PaymentMinutes = 30
+
+	tc, err := render.CreateTemplateCache()
+	if err != nil {
+		log.Fatal("cannot create template cache:", err)
+	}
+	app.TemplateCache = tc
+	app.UseCache = true
+
+	NewHandlers(NewTestRepo(&app))
+	render.NewTemplates(&app)
+	helpers.NewHelpers(&app)
+
+	os.Exit(m.Run())
+}
+
+func getRoutes() http.Handler {
+	mux := chi.NewRouter()
+
+	mux.Use(middleware.Recoverer)
+	mux.Use(NoSurf)
+	mux.Use(SessionLoad)
+
+	mux.Get("/", Repo.Home)
+	mux.Get("/about", Repo.About)
+	mux.Get("/contact", Repo.Contact)
+	mux.Get("/rooms", Repo.Rooms)
+	mux.Get("/rooms/{slug}", Repo.ShowRoom)
+
+	mux.Get("/search-availability", Repo.Availability)
+	mux.Post("/search-availability", Repo.PostAvailability)
+	mux.Get("/choose-room", Repo.ChooseRooms)
+	mux.Get("/choose-room/{id}", Repo.ChooseRoom)
+	mux.Get("/add-room", Repo.AddCartRoom)
+
+	mux.Get("/make-reservation", Repo.Reservation)
+	mux.Post("/make-reservation", Repo.PostReservation)
+	mux.Get("/payment", Repo.Payment)
+	mux.Post("/payment", Repo.PostPayment)
+	mux.Get("/reservation-summary", Repo.ReservationSummary)
+
+	mux.Get("/user/login", Repo.ShowLogin)
+
+	mux.Route("/admin", func(r chi.Router) {
+		r.Get("/reservations/{src}/{id}", Repo.AdminShowReservation)
+		r.Post("/reservations/{src}/{id}/refund", Repo.AdminPostRefund)
+	})
+
+	return mux
+}
+
+func NoSurf(next http.Handler) http.Handler {
+	csrfHandler := nosurf.New(next)
+	csrfHandler.SetBaseCookie(http.Cookie{
+		HttpOnly: true,
+		Path:     "/",
+		Secure:   app.InProduction,
+		SameSite: http.SameSiteLaxMode,
+	})
+	return csrfHandler
+}
+
+func SessionLoad(next http.Handler) http.Handler {
+	return session.LoadAndSave(next)
+}
@@ -1,9 +1,18 @@
 package helpers
 
 import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"math"
+	"math/big"
 	"net/http"
 	"runtime/debug"
+	"strconv"
+	"strings"
 
 	"github.com/NhanNT-VNG/hotel-booking/internal/config"
 )
@@ -29,3 +38,61 @@ func IsAuthenticated(r *http.Request) bool {
 	exists := app.Session.Exists(r.Context(), "user_id")
 	return exists
 }
+
+func RandomToken() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+const confirmationAlphabet = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+
+func ConfirmationCode() (string, error) {
+	code := make([]byte, 8)
+	for i := range code {
+		n, err := rand.Int(rand.Reader, big.NewInt(int64(len(confirmationAlphabet))))
+		if err != nil {
+			return "", err
+		}
+		code[i] = confirmationAlphabet[n.Int64()]
+	}
+	return string(code), nil
+}
+
+func Sign(value string) string {
+	mac := hmac.New(sha256.New, []byte(app.SigningKey))
+	mac.Write([]byte(value))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func VerifySignature(value, signature string) bool {
+	return hmac.Equal([]byte(Sign(value)), []byte(signature))
+}
+
+func ManageBookingURL(code string) string {
+	return fmt.Sprintf("%s/manage-booking/%s/%s", app.BaseURL, code, Sign(code))
+}
+
+// maxMoney is the largest amount ParseMoney accepts, far above any price
+// but small enough to be held in cents without overflowing.
+const maxMoney = 10000000
+
+// ParseMoney converts an amount such as "120" or "120.50" into cents.
+func ParseMoney(amount string) (int, error) {
+	value, err := strconv.ParseFloat(strings.TrimSpace(amount), 64)
+	if err != nil {
+		return 0, err
+	}
+	if math.IsNaN(value) || math.IsInf(value, 0) {
+		return 0, errors.New("amount must be a number")
+	}
+	if value < 0 {
+		return 0, errors.New("amount cannot be negative")
+	}
+	if value > maxMoney {
+		return 0, errors.New("amount is too large")
+	}
+	return int(math.Round(value * 100)), nil
+}
@@ -0,0 +1,39 @@
+package helpers
+
+import "testing"
+
+func TestParseMoney(t *testing.T) {
+	var tests = []struct {
+		amount   string
+		expected int
+		isValid  bool
+	}{
+		{"120", 12000, true},
+		{"120.50", 12050, true},
+		{" 0.01 ", 1, true},
+		{"19.999", 2000, true},
+		{"0", 0, true},
+		{"10000000", 1000000000, true},
+		{"10000000.01", 0, false},
+		{"1e300", 0, false},
+		{"-5", 0, false},
+		{"NaN", 0, false},
+		{"Inf", 0, false},
+		{"-Inf", 0, false},
+		{"", 0, false},
+		{"ten", 0, false},
+	}
+
+	for _, e := range tests {
+		got, err := ParseMoney(e.amount)
+		if e.isValid && err != nil {
+			t.Errorf("%q: expected no error but got %v", e.amount, err)
+		}
+		if !e.isValid && err == nil {
+			t.Errorf("%q: expected an error but got %d", e.amount, got)
+		}
+		if got != e.expected {
+			t.Errorf("%q: expected %d but got %d", e.amount, e.expected, got)
+		}
+	}
+}
@@ -0,0 +1,184 @@
+package invoice
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/NhanNT-VNG/hotel-booking/internal/models"
+	"github.com/NhanNT-VNG/hotel-booking/internal/pricing"
+	"github.com/NhanNT-VNG/hotel-booking/internal/render"
+	"github.com/jung-kurt/gofpdf"
+)
+
+const dateLayout = "January 2, 2006"
+
+// Line is a row of an invoice. Amount is in cents for the whole row.
+type Line struct {
+	Description string
+	Amount      int
+}
+
+// Document is a booking as printed on its invoice. The reservation must have
+// its lines, charges, payments and refunds loaded. Quotes hold the current
+// prices of the rooms by reservation id.
+type Document struct {
+	Hotel       models.Hotel
+	Invoice     models.Invoice
+	Reservation models.Reservation
+	Quotes      map[int]pricing.Quote
+}
+
+func FileName(invoice models.Invoice) string {
+	return invoice.Code() + ".pdf"
+}
+
+// Lines itemises the rooms of the booking. Nights are listed one by one when
+// the current rates still add up to the price the room was booked at, and
+// as a single row otherwise.
+func (d Document) Lines() []Line {
+	var lines []Line
+
+	for _, room := range d.Reservation.Rooms() {
+		name := room.RoomType.Name
+		stay := fmt.Sprintf("%s to %s", room.StartDate.Format(dateLayout), room.EndDate.Format(dateLayout))
+
+		if room.Status == models.StatusCancelled {
+			lines = append(lines, Line{
+				Description: fmt.Sprintf("%s, %s, cancellation penalty", name, stay),
+				Amount:      room.CancellationPenalty,
+			})
+			continue
+		}
+
+		quote, ok := d.Quotes[room.ID]
+		if ok && quote.Total == room.Total+room.Discount {
+			for _, night := range quote.Nights {
+				description := fmt.Sprintf("%s, night of %s", name, night.Date.Format(dateLayout))
+				if night.RateName != "" {
+					description += fmt.Sprintf(" (%s)", night.RateName)
+				}
+				lines = append(lines, Line{Description: description, Amount: night.Rate})
+			}
+		} else {
+			nights := int(room.EndDate.Sub(room.StartDate).Hours() / 24)
+			lines = append(lines, Line{
+				Description: fmt.Sprintf("%s, %d nights, %s", name, nights, stay),
+				Amount:      room.Total + room.Discount,
+			})
+		}
+
+		if room.Discount > 0 {
+			lines = append(lines, Line{
+				Description: fmt.Sprintf("Promo code %s", d.Reservation.PromoCode),
+				Amount:      -room.Discount,
+			})
+		}
+
+		for _, charge := range room.Charges {
+			description := charge.Name
+			if charge.Quantity > 1 {
+				description += fmt.Sprintf(" × %d", charge.Quantity)
+			}
+			lines = append(lines, Line{Description: description, Amount: charge.Amount})
+		}
+	}
+
+	return lines
+}
+
+// PaymentLines lists the money received for the booking and paid back.
+func (d Document) PaymentLines() []Line {
+	var lines []Line
+
+	for _, payment := range d.Reservation.Payments {
+		if payment.Status != models.PaymentCaptured && payment.Status != models.PaymentRefunded {
+			continue
+		}
+		description := "Payment"
+		if payment.Kind == models.PaymentDeposit {
+			description = "Deposit"
+		}
+		lines = append(lines, Line{
+			Description: fmt.Sprintf("%s received %s", description, payment.CreatedAt.Format(dateLayout)),
+			Amount:      payment.Amount,
+		})
+	}
+
+	for _, refund := range d.Reservation.Refunds {
+		if refund.Status != models.RefundSucceeded {
+			continue
+		}
+		lines = append(lines, Line{
+			Description: fmt.Sprintf("Refund %s", refund.CreatedAt.Format(dateLayout)),
+			Amount:      -refund.Amount,
+		})
+	}
+
+	return lines
+}
+
+// Write renders the invoice as a PDF.
+func Write(w io.Writer, d Document) error {
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	tr := pdf.UnicodeTranslatorFromDescriptor("")
+	res := d.Reservation
+
+	pdf.SetTitle(d.Invoice.Code(), true)
+	pdf.SetAuthor(d.Hotel.Name, true)
+	pdf.AddPage()
+
+	pdf.SetFont("Helvetica", "B", 16)
+	pdf.CellFormat(0, 8, tr(d.Hotel.Name), "", 1, "L", false, 0, "")
+	pdf.SetFont("Helvetica", "", 10)
+	for _, detail := range []string{d.Hotel.Address, d.Hotel.Phone, d.Hotel.Email} {
+		if detail != "" {
+			pdf.CellFormat(0, 5, tr(detail), "", 1, "L", false, 0, "")
+		}
+	}
+	if d.Hotel.TaxId != "" {
+		pdf.CellFormat(0, 5, tr("Tax ID: "+d.Hotel.TaxId), "", 1, "L", false, 0, "")
+	}
+	pdf.Ln(8)
+
+	pdf.SetFont("Helvetica", "B", 14)
+	pdf.CellFormat(0, 8, "Invoice "+d.Invoice.Code(), "", 1, "L", false, 0, "")
+	pdf.SetFont("Helvetica", "", 10)
+
+	details := [][2]string{
+		{"Invoice date", d.Invoice.IssuedAt.Format(dateLayout)},
+		{"Confirmation code", res.ConfirmationCode},
+		{"Billed to", fmt.Sprintf("%s %s", res.FirstName, res.LastName)},
+		{"Email", res.Email},
+	}
+	for _, detail := range details {
+		pdf.CellFormat(40, 5, detail[0]+":", "", 0, "L", false, 0, "")
+		pdf.CellFormat(0, 5, tr(detail[1]), "", 1, "L", false, 0, "")
+	}
+	pdf.Ln(6)
+
+	table := func(title string, lines []Line, totalLabel string, total int) {
+		pdf.SetFont("Helvetica", "B", 10)
+		pdf.CellFormat(150, 7, title, "B", 0, "L", false, 0, "")
+		pdf.CellFormat(30, 7, "Amount", "B", 1, "R", false, 0, "")
+		pdf.SetFont("Helvetica", "", 10)
+		for _, line := range lines {
+			pdf.CellFormat(150, 6, tr(line.Description), "", 0, "L", false, 0, "")
+			pdf.CellFormat(30, 6, render.FormatMoney(line.Amount), "", 1, "R", false, 0, "")
+		}
+		pdf.SetFont("Helvetica", "B", 10)
+		pdf.CellFormat(150, 7, totalLabel, "T", 0, "R", false, 0, "")
+		pdf.CellFormat(30, 7, render.FormatMoney(total), "T", 1, "R", false, 0, "")
+		pdf.Ln(6)
+	}
+
+	table("Description", d.Lines(), "Total", res.BookingTotal())
+	if payments := d.PaymentLines(); len(payments) > 0 {
+		table("Payments", payments, "Paid", res.Paid())
+	}
+
+	pdf.SetFont("Helvetica", "B", 12)
+	pdf.CellFormat(150, 8, "Balance due", "", 0, "R", false, 0, "")
+	pdf.CellFormat(30, 8, render.FormatMoney(res.BalanceDue()), "", 1, "R", false, 0, "")
+
+	return pdf.Output(w)
+}
@@ -0,0 +1,51 @@
+package mailer
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"time"
+
+	"github.com/NhanNT-VNG/hotel-booking/internal/models"
+)
+
+// FileMailer writes mail to a maildir as .eml files instead of sending it,
+// so it can be read in a mail client during development. Each mail is
+// written to tmp and moved to new once complete.
+type FileMailer struct {
+	dir  string
+	sent uint64
+}
+
+func NewFileMailer(dir string) (*FileMailer, error) {
+	for _, sub := range []string{"tmp", "new", "cur"} {
+		err := os.MkdirAll(filepath.Join(dir, sub), 0755)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return &FileMailer{dir: dir}, nil
+}
+
+func (f *FileMailer) Name() string {
+	return TransportFile
+}
+
+func (f *FileMailer) Send(ctx context.Context, m models.MailData) error {
+	email, err := newMessage(m)
+	if err != nil {
+		return err
+	}
+
+	name := fmt.Sprintf("%d.%d_%d.eml", time.Now().UnixNano(), os.Getpid(), atomic.AddUint64(&f.sent, 1))
+	tmp := filepath.Join(f.dir, "tmp", name)
+
+	err = ioutil.WriteFile(tmp, []byte(email.GetMessage()), 0644)
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp, filepath.Join(f.dir, "new", name))
+}
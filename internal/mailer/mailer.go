@@ -0,0 +1,82 @@
+package mailer
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	"github.com/NhanNT-VNG/hotel-booking/internal/models"
+	mail "github.com/xhit/go-simple-mail/v2"
+)
+
+// Transports a Mailer can be selected by.
+const (
+	TransportSMTP   = "smtp"
+	TransportFile   = "file"
+	TransportMemory = "memory"
+)
+
+// TemplateDir holds the templates a mail's content is wrapped in.
+var TemplateDir = "./email-templates"
+
+// Mailer delivers mail taken from the outbox.
+type Mailer interface {
+	Name() string
+	Send(ctx context.Context, m models.MailData) error
+}
+
+// Config selects and sets up the transport mail is sent with. Dir is where
+// the file transport writes mail.
+type Config struct {
+	Transport string
+	SMTP      SMTPConfig
+	Dir       string
+}
+
+func New(config Config) (Mailer, error) {
+	switch config.Transport {
+	case TransportSMTP, "":
+		s, err := NewSMTPMailer(config.SMTP)
+		if err != nil {
+			return nil, err
+		}
+		return s, nil
+	case TransportFile:
+		return NewFileMailer(config.Dir)
+	case TransportMemory:
+		return NewMemoryMailer(), nil
+	default:
+		return nil, fmt.Errorf("unknown mail transport %q", config.Transport)
+	}
+}
+
+// newMessage builds the email of a mail, wrapping its content in its
+// template.
+func newMessage(m models.MailData) (*mail.Email, error) {
+	email := mail.NewMSG()
+	email.SetFrom(m.From).AddTo(m.To).SetSubject(m.Subject)
+
+	if m.Template == "" {
+		email.SetBody(mail.TextHTML, m.Content)
+	} else {
+		data, err := ioutil.ReadFile(filepath.Join(TemplateDir, m.Template))
+		if err != nil {
+			return nil, err
+		}
+		mailTemplate := string(data)
+		msgToSend := strings.Replace(mailTemplate, "[%body%]", m.Content, 1)
+		email.SetBody(mail.TextHTML, msgToSend)
+	}
+
+	for _, attachment := range m.Attachments {
+		email.Attach(&mail.File{
+			Name:     attachment.Name,
+			MimeType: attachment.ContentType,
+			Data:     attachment.Data,
+		})
+	}
+
+	return email, email.GetError()
+}
@@ -0,0 +1,50 @@
+package mailer
+
+import (
+	"context"
+	"sync"
+
+	"github.com/NhanNT-VNG/hotel-booking/internal/models"
+)
+
+// MemoryMailer keeps the mail it is given, for tests.
+type MemoryMailer struct {
+	mu   sync.Mutex
+	sent []models.MailData
+	err  error
+}
+
+func NewMemoryMailer() *MemoryMailer {
+	return &MemoryMailer{}
+}
+
+func (mm *MemoryMailer) Name() string {
+	return TransportMemory
+}
+
+func (mm *MemoryMailer) Send(ctx context.Context, m models.MailData) error {
+	mm.mu.Lock()
+	defer mm.mu.Unlock()
+
+	if mm.err != nil {
+		return mm.err
+	}
+	mm.sent = append(mm.sent, m)
+	return nil
+}
+
+// Sent returns the mail sent so far, oldest first.
+func (mm *MemoryMailer) Sent() []models.MailData {
+	mm.mu.Lock()
+	defer mm.mu.Unlock()
+
+	return append([]models.MailData(nil), mm.sent...)
+}
+
+// Fail makes sending fail with err until it is called with nil.
+func (mm *MemoryMailer) Fail(err error) {
+	mm.mu.Lock()
+	defer mm.mu.Unlock()
+
+	mm.err = err
+}
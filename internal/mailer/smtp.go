@@ -0,0 +1,85 @@
+package mailer
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/NhanNT-VNG/hotel-booking/internal/models"
+	mail "github.com/xhit/go-simple-mail/v2"
+)
+
+// SMTP encryptions.
+const (
+	EncryptionNone     = "none"
+	EncryptionSSLTLS   = "ssltls"
+	EncryptionSTARTTLS = "starttls"
+)
+
+// SMTPConfig is the server mail is sent through. Username and Password are
+// only used when Username is set.
+type SMTPConfig struct {
+	Host       string
+	Port       int
+	Username   string
+	Password   string
+	Encryption string
+}
+
+// SMTPMailer sends each mail over a new connection to an SMTP server.
+type SMTPMailer struct {
+	config     SMTPConfig
+	encryption mail.Encryption
+}
+
+// NewSMTPMailer returns an error for an unknown encryption, so a typo in the
+// settings stops the site from starting rather than every mail from sending.
+func NewSMTPMailer(config SMTPConfig) (*SMTPMailer, error) {
+	s := &SMTPMailer{config: config}
+
+	switch config.Encryption {
+	case EncryptionNone, "":
+		s.encryption = mail.EncryptionNone
+	case EncryptionSSLTLS:
+		s.encryption = mail.EncryptionSSLTLS
+	case EncryptionSTARTTLS:
+		s.encryption = mail.EncryptionSTARTTLS
+	default:
+		return nil, fmt.Errorf("unknown SMTP encryption %q", config.Encryption)
+	}
+
+	return s, nil
+}
+
+func (s *SMTPMailer) Name() string {
+	return TransportSMTP
+}
+
+func (s *SMTPMailer) Send(ctx context.Context, m models.MailData) error {
+	server := mail.NewSMTPClient()
+	server.Host = s.config.Host
+	server.Port = s.config.Port
+	server.KeepAlive = false
+	server.ConnectTimeout = 10 * time.Second
+	server.SendTimeout = 10 * time.Second
+	server.Encryption = s.encryption
+
+	if s.config.Username == "" {
+		server.Authentication = mail.AuthNone
+	} else {
+		server.Authentication = mail.AuthPlain
+		server.Username = s.config.Username
+		server.Password = s.config.Password
+	}
+
+	email, err := newMessage(m)
+	if err != nil {
+		return err
+	}
+
+	client, err := server.Connect()
+	if err != nil {
+		return err
+	}
+	return email.Send(client)
+}
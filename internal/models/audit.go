@@ -0,0 +1,101 @@
+package models
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+)
+
+const (
+	AuditActionCreate  = "create"
+	AuditActionUpdate  = "update"
+	AuditActionStatus  = "status"
+	AuditActionCancel  = "cancel"
+	AuditActionDelete  = "delete"
+	AuditActionRestore = "restore"
+)
+
+const (
+	AuditEntityReservation        = "reservation"
+	AuditEntityRoom               = "room"
+	AuditEntityRoomType           = "room_type"
+	AuditEntityRoomRate           = "room_rate"
+	AuditEntityCancellationPolicy = "cancellation_policy"
+	AuditEntityRestriction        = "restriction"
+	AuditEntityStayRule           = "stay_rule"
+	AuditEntityPromoCode          = "promo_code"
+	AuditEntityTaxFee             = "tax_fee"
+	AuditEntityRefund             = "refund"
+)
+
+type AuditEvent struct {
+	ID         int
+	UserId     int
+	Action     string
+	EntityType string
+	EntityId   int
+	Changes    []FieldChange
+	CreatedAt  time.Time
+	User       User
+}
+
+type FieldChange struct {
+	Field  string `json:"field"`
+	Before string `json:"before"`
+	After  string `json:"after"`
+}
+
+// Diff compares the exported scalar fields of two values of the same struct
+// type. Nested structs other than time.Time, slices other than []string and
+// bookkeeping timestamps are skipped.
+func Diff(before, after interface{}) []FieldChange {
+	var changes []FieldChange
+
+	b := reflect.Indirect(reflect.ValueOf(before))
+	a := reflect.Indirect(reflect.ValueOf(after))
+	if b.Kind() != reflect.Struct || b.Type() != a.Type() {
+		return changes
+	}
+
+	for i := 0; i < b.NumField(); i++ {
+		field := b.Type().Field(i)
+		if field.PkgPath != "" || field.Name == "CreatedAt" || field.Name == "UpdatedAt" {
+			continue
+		}
+
+		switch b.Field(i).Interface().(type) {
+		case time.Time, []string:
+		default:
+			kind := field.Type.Kind()
+			if kind == reflect.Struct || kind == reflect.Slice || kind == reflect.Map {
+				continue
+			}
+		}
+
+		beforeValue := auditValue(b.Field(i).Interface())
+		afterValue := auditValue(a.Field(i).Interface())
+		if beforeValue != afterValue {
+			changes = append(changes, FieldChange{
+				Field:  field.Name,
+				Before: beforeValue,
+				After:  afterValue,
+			})
+		}
+	}
+
+	return changes
+}
+
+func auditValue(value interface{}) string {
+	if t, ok := value.(time.Time); ok {
+		if t.IsZero() {
+			return ""
+		}
+		return t.Format("2006-01-02 15:04")
+	}
+	if values, ok := value.([]string); ok {
+		return strings.Join(values, ", ")
+	}
+	return fmt.Sprint(value)
+}
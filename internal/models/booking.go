@@ -0,0 +1,118 @@
+package models
+
+// Rooms returns every room line of a booking, the reservation itself first.
+func (r Reservation) Rooms() []Reservation {
+	first := r
+	first.Lines = nil
+	return append([]Reservation{first}, r.Lines...)
+}
+
+// BookingTotal is what the guest owes for all rooms of the booking, taxes and
+// fees included. Cancelled rooms only count their cancellation penalty.
+func (r Reservation) BookingTotal() int {
+	return r.RoomCharges() + r.Penalties()
+}
+
+// RoomCharges is the price of the rooms of the booking that are not
+// cancelled, taxes and fees included.
+func (r Reservation) RoomCharges() int {
+	total := 0
+	for _, line := range r.Rooms() {
+		if line.Status != StatusCancelled {
+			total += line.Total + line.ChargesTotal()
+		}
+	}
+	return total
+}
+
+// Penalties is what the cancelled rooms of the booking cost.
+func (r Reservation) Penalties() int {
+	total := 0
+	for _, line := range r.Rooms() {
+		if line.Status == StatusCancelled {
+			total += line.CancellationPenalty
+		}
+	}
+	return total
+}
+
+// AllocateGuests spreads the party over rooms of the given types, one adult
+// per room first and then filling rooms in order. It reports false when the
+// rooms cannot sleep the whole party.
+func AllocateGuests(roomTypes []RoomType, g Guests) ([]Guests, bool) {
+	shares := make([]Guests, len(roomTypes))
+	if len(roomTypes) == 0 || len(roomTypes) > g.Adults {
+		return shares, false
+	}
+
+	adults, children := g.Adults, g.Children
+	for i := range shares {
+		shares[i] = Guests{Adults: 1, Rooms: 1}
+		adults--
+	}
+	for i, roomType := range roomTypes {
+		n := min(adults, roomType.AdultCapacity()-shares[i].Adults)
+		shares[i].Adults += n
+		adults -= n
+	}
+	for i, roomType := range roomTypes {
+		n := min(children, roomType.Capacity-shares[i].Total())
+		shares[i].Children += n
+		children -= n
+	}
+
+	return shares, adults == 0 && children == 0
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+// BookingDiscount is the promo code discount over the rooms of the booking
+// that are not cancelled.
+func (r Reservation) BookingDiscount() int {
+	discount := 0
+	for _, line := range r.Rooms() {
+		if line.Status != StatusCancelled {
+			discount += line.Discount
+		}
+	}
+	return discount
+}
+
+// ChargesTotal is the sum of the taxes and fees of this room.
+func (r Reservation) ChargesTotal() int {
+	total := 0
+	for _, charge := range r.Charges {
+		total += charge.Amount
+	}
+	return total
+}
+
+// BookingCharges adds up the taxes and fees of the rooms that are not
+// cancelled, one line per tax or fee, in catalog order.
+func (r Reservation) BookingCharges() []ReservationCharge {
+	var charges []ReservationCharge
+	for _, line := range r.Rooms() {
+		if line.Status == StatusCancelled {
+			continue
+		}
+		for _, charge := range line.Charges {
+			found := false
+			for i := range charges {
+				if charges[i].Name == charge.Name && charges[i].Kind == charge.Kind {
+					charges[i].Quantity += charge.Quantity
+					charges[i].Amount += charge.Amount
+					found = true
+				}
+			}
+			if !found {
+				charges = append(charges, charge)
+			}
+		}
+	}
+	return charges
+}
@@ -0,0 +1,65 @@
+package models
+
+import "time"
+
+const (
+	PenaltyNone          = "none"
+	PenaltyPercent       = "percent"
+	PenaltyFirstNight    = "first_night"
+	PenaltyNonRefundable = "non_refundable"
+)
+
+var PenaltyTypes = []string{
+	PenaltyNone,
+	PenaltyPercent,
+	PenaltyFirstNight,
+	PenaltyNonRefundable,
+}
+
+type CancellationPolicy struct {
+	ID                   int
+	Name                 string
+	FreeCancellationDays int
+	PenaltyType          string
+	PenaltyPercent       int
+	CreatedAt            time.Time
+	UpdatedAt            time.Time
+}
+
+func IsValidPenaltyType(penaltyType string) bool {
+	for _, t := range PenaltyTypes {
+		if t == penaltyType {
+			return true
+		}
+	}
+	return false
+}
+
+func (p CancellationPolicy) Deadline(startDate time.Time) time.Time {
+	return startDate.AddDate(0, 0, -p.FreeCancellationDays)
+}
+
+func (p CancellationPolicy) IsFree(startDate, at time.Time) bool {
+	if p.PenaltyType == PenaltyNonRefundable {
+		return false
+	}
+	return at.Before(p.Deadline(startDate))
+}
+
+// Penalty returns the amount in cents charged when cancelling at the given
+// time, based on the reservation total and the price of its first night.
+func (p CancellationPolicy) Penalty(startDate, at time.Time, total, firstNight int) int {
+	if p.IsFree(startDate, at) {
+		return 0
+	}
+
+	switch p.PenaltyType {
+	case PenaltyPercent:
+		return total * p.PenaltyPercent / 100
+	case PenaltyFirstNight:
+		return firstNight
+	case PenaltyNonRefundable:
+		return total
+	}
+	return 0
+}
@@ -0,0 +1,101 @@
+package models
+
+import "sort"
+
+// maxCombinationRooms limits how many rooms SuggestCombinations puts together.
+const maxCombinationRooms = 4
+
+// Guests is the party a guest searches availability for.
+type Guests struct {
+	Adults   int
+	Children int
+	Rooms    int
+}
+
+// Total is the number of people in the party.
+func (g Guests) Total() int {
+	return g.Adults + g.Children
+}
+
+// PerRoom returns the adults and guests the fullest room has to sleep when
+// the party is spread evenly over the rooms.
+func (g Guests) PerRoom() (adults, guests int) {
+	rooms := g.Rooms
+	if rooms < 1 {
+		rooms = 1
+	}
+	return (g.Adults + rooms - 1) / rooms, (g.Total() + rooms - 1) / rooms
+}
+
+// RoomCombination is a set of rooms, possibly of different types, that
+// sleeps a party together. Total is the price of all rooms for the stay.
+type RoomCombination struct {
+	RoomTypes []RoomType
+	Total     int
+}
+
+func (c RoomCombination) capacity() (adults, guests int) {
+	for _, roomType := range c.RoomTypes {
+		adults += roomType.AdultCapacity()
+		guests += roomType.Capacity
+	}
+	return adults, guests
+}
+
+func (c RoomCombination) mixed() bool {
+	for _, roomType := range c.RoomTypes {
+		if roomType.ID != c.RoomTypes[0].ID {
+			return true
+		}
+	}
+	return false
+}
+
+// SuggestCombinations looks for ways to spread the party over rooms of
+// different types, or over more rooms than asked for when the party does not
+// fit otherwise. Every room needs at least one adult. Only the combinations
+// with the fewest rooms are returned, those with the least spare beds first.
+func SuggestCombinations(available []RoomTypeAvailability, g Guests) []RoomCombination {
+	var found []RoomCombination
+
+	rooms := g.Rooms
+	if rooms < 1 {
+		rooms = 1
+	}
+
+	for k := rooms; k <= g.Adults && k <= maxCombinationRooms && len(found) == 0; k++ {
+		var walk func(from int, picked []RoomType)
+		walk = func(from int, picked []RoomType) {
+			if len(picked) == k {
+				combination := RoomCombination{RoomTypes: append([]RoomType(nil), picked...)}
+				adults, guests := combination.capacity()
+				if adults >= g.Adults && guests >= g.Total() && (k != rooms || combination.mixed()) {
+					found = append(found, combination)
+				}
+				return
+			}
+			for i := from; i < len(available); i++ {
+				used := 0
+				for _, roomType := range picked {
+					if roomType.ID == available[i].RoomType.ID {
+						used++
+					}
+				}
+				if used < available[i].FreeUnits {
+					walk(i, append(picked, available[i].RoomType))
+				}
+			}
+		}
+		walk(0, nil)
+	}
+
+	sort.SliceStable(found, func(i, j int) bool {
+		_, a := found[i].capacity()
+		_, b := found[j].capacity()
+		return a < b
+	})
+	if len(found) > 3 {
+		found = found[:3]
+	}
+	return found
+}
@@ -0,0 +1,28 @@
+package models
+
+import (
+	"fmt"
+	"time"
+)
+
+// Hotel holds the details printed on invoices.
+type Hotel struct {
+	Name    string
+	Address string
+	Email   string
+	Phone   string
+	TaxId   string
+}
+
+// Invoice numbers a booking for accounting. Numbers are given out in order
+// and a booking keeps its number when the invoice is printed again.
+type Invoice struct {
+	ID            int
+	Number        int
+	ReservationId int
+	IssuedAt      time.Time
+}
+
+func (i Invoice) Code() string {
+	return fmt.Sprintf("INV-%06d", i.Number)
+}
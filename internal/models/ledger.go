@@ -0,0 +1,79 @@
+package models
+
+import "time"
+
+// Kinds of ledger entries. Charges and penalties add to what the guest owes,
+// payments take off it and refunds add back what was paid out.
+const (
+	LedgerCharge  = "charge"
+	LedgerPenalty = "penalty"
+	LedgerPayment = "payment"
+	LedgerRefund  = "refund"
+)
+
+// LedgerEntry is a movement of money on a booking. Amount is in cents and
+// may be negative for charges that were lowered. Balance is worked out when
+// the ledger is read and is not stored.
+type LedgerEntry struct {
+	ID            int
+	ReservationId int
+	Kind          string
+	Description   string
+	Amount        int
+	PaymentId     int
+	RefundId      int
+	UserId        int
+	CreatedAt     time.Time
+	Balance       int
+}
+
+// Effect is how the entry changes what the guest owes.
+func (e LedgerEntry) Effect() int {
+	if e.Kind == LedgerPayment {
+		return -e.Amount
+	}
+	return e.Amount
+}
+
+// Refund statuses. A refund is recorded as pending before the gateway is
+// asked to pay it, and only a succeeded refund has a ledger entry.
+const (
+	RefundPending   = "pending"
+	RefundSucceeded = "succeeded"
+	RefundFailed    = "failed"
+)
+
+// Refund is money returned to the guest against a payment.
+type Refund struct {
+	ID            int
+	ReservationId int
+	PaymentId     int
+	Amount        int
+	Reason        string
+	ProviderRef   string
+	Status        string
+	UserId        int
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+}
+
+// RunningBalance fills in the balance after each entry of a ledger in order.
+func RunningBalance(entries []LedgerEntry) []LedgerEntry {
+	balance := 0
+	for i := range entries {
+		balance += entries[i].Effect()
+		entries[i].Balance = balance
+	}
+	return entries
+}
+
+// LedgerSum adds up the entries of one kind.
+func LedgerSum(entries []LedgerEntry, kind string) int {
+	sum := 0
+	for _, entry := range entries {
+		if entry.Kind == kind {
+			sum += entry.Amount
+		}
+	}
+	return sum
+}
@@ -0,0 +1,82 @@
+package models
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestRunningBalance(t *testing.T) {
+	var tests = []struct {
+		name     string
+		entries  []LedgerEntry
+		balances []int
+	}{
+		{"empty", nil, nil},
+		{
+			name: "paid in full",
+			entries: []LedgerEntry{
+				{Kind: LedgerCharge, Amount: 33000},
+				{Kind: LedgerPayment, Amount: 33000},
+			},
+			balances: []int{33000, 0},
+		},
+		{
+			name: "deposit then cheaper dates",
+			entries: []LedgerEntry{
+				{Kind: LedgerCharge, Amount: 33000},
+				{Kind: LedgerPayment, Amount: 9900},
+				{Kind: LedgerCharge, Amount: -11000},
+			},
+			balances: []int{33000, 23100, 12100},
+		},
+		{
+			name: "cancelled and refunded",
+			entries: []LedgerEntry{
+				{Kind: LedgerCharge, Amount: 33000},
+				{Kind: LedgerPayment, Amount: 33000},
+				{Kind: LedgerCharge, Amount: -33000},
+				{Kind: LedgerPenalty, Amount: 10000},
+				{Kind: LedgerRefund, Amount: 15000},
+				{Kind: LedgerRefund, Amount: 8000},
+			},
+			balances: []int{33000, 0, -33000, -23000, -8000, 0},
+		},
+	}
+
+	for _, e := range tests {
+		var balances []int
+		for _, entry := range RunningBalance(e.entries) {
+			balances = append(balances, entry.Balance)
+		}
+		if !reflect.DeepEqual(balances, e.balances) {
+			t.Errorf("%s: expected balances %v but got %v", e.name, e.balances, balances)
+		}
+	}
+}
+
+func TestLedgerSum(t *testing.T) {
+	entries := []LedgerEntry{
+		{Kind: LedgerCharge, Amount: 33000},
+		{Kind: LedgerPayment, Amount: 33000},
+		{Kind: LedgerCharge, Amount: -33000},
+		{Kind: LedgerPenalty, Amount: 10000},
+		{Kind: LedgerRefund, Amount: 15000},
+		{Kind: LedgerRefund, Amount: 8000},
+	}
+
+	var tests = []struct {
+		kind     string
+		expected int
+	}{
+		{LedgerCharge, 0},
+		{LedgerPenalty, 10000},
+		{LedgerPayment, 33000},
+		{LedgerRefund, 23000},
+	}
+
+	for _, e := range tests {
+		if got := LedgerSum(entries, e.kind); got != e.expected {
+			t.Errorf("%s: expected %d but got %d", e.kind, e.expected, got)
+		}
+	}
+}
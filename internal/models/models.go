@@ -16,19 +16,52 @@ type User struct {
 }
 
 type Room struct {
-	ID        int
-	RoomName  string
-	CreatedAt time.Time
-	UpdatedAt time.Time
+	ID         int
+	RoomName   string
+	RoomTypeId int
+	Active     bool
+	SortOrder  int
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+	RoomType   RoomType
 }
 
+// Restriction types the application relies on. Other types can be added by
+// admins and are treated like owner blocks.
+const (
+	RestrictionReservation = 1
+	RestrictionOwnerBlock  = 2
+	RestrictionHold        = 3
+	RestrictionMaintenance = 4
+)
+
 type Restriction struct {
 	ID              int
 	RestrictionName string
+	Color           string
+	CountsAsSold    bool
 	CreatedAt       time.Time
 	UpdatedAt       time.Time
 }
 
+func (r Restriction) IsBuiltIn() bool {
+	return r.ID >= RestrictionReservation && r.ID <= RestrictionMaintenance
+}
+
+// Occupancy counts active rooms for a single night.
+type Occupancy struct {
+	Rooms        int
+	Sold         int
+	OutOfService int
+}
+
+func (o Occupancy) Percent() int {
+	if o.Rooms == 0 {
+		return 0
+	}
+	return o.Sold * 100 / o.Rooms
+}
+
 type Reservation struct {
 	ID        int
 	RoomId    int
@@ -41,7 +74,42 @@ type Reservation struct {
 	CreatedAt time.Time
 	UpdatedAt time.Time
 	Room      Room
-	Processed int
+	Status    string
+
+	RoomTypeId int
+	RoomType   RoomType
+	Adults     int
+	Children   int
+
+	// ParentId links the extra rooms of a multi-room booking to the
+	// reservation that holds the confirmation code. Lines are those rooms.
+	ParentId  int
+	Lines     []Reservation
+	HoldToken string
+
+	ConfirmationCode    string
+	CancellationPenalty int
+	CancelledAt         time.Time
+	DeletedAt           time.Time
+	DeletedBy           int
+	Total               int
+
+	// FirstNight is the price of the first night as booked, after its share
+	// of the discount. It is what first night cancellation penalties charge.
+	FirstNight int
+
+	// Total is after Discount. PromoCode is the code the guest entered.
+	PromoCodeId int
+	PromoCode   string
+	Discount    int
+
+	// Charges are the taxes and fees due on top of Total.
+	Charges []ReservationCharge
+
+	// Payments and Refunds are for the whole booking and kept on the
+	// reservation that holds the confirmation code.
+	Payments []Payment
+	Refunds  []Refund
 }
 
 type RoomRestriction struct {
@@ -51,6 +119,8 @@ type RoomRestriction struct {
 	RestrictionId int
 	StartDate     time.Time
 	EndDate       time.Time
+	HoldToken     string
+	ExpiresAt     time.Time
 	CreatedAt     time.Time
 	UpdatedAt     time.Time
 	Room          Room
@@ -59,9 +129,16 @@ type RoomRestriction struct {
 }
 
 type MailData struct {
-	To       string
-	From     string
-	Subject  string
-	Content  string
-	Template string
+	To          string
+	From        string
+	Subject     string
+	Content     string
+	Template    string
+	Attachments []MailAttachment
+}
+
+type MailAttachment struct {
+	Name        string
+	ContentType string
+	Data        []byte
 }
@@ -0,0 +1,25 @@
+package models
+
+import "time"
+
+// Outbox mail statuses. Pending mail is picked up by the mail workers and
+// failed mail ran out of attempts and waits for an admin to resend it.
+const (
+	MailPending = "pending"
+	MailSent    = "sent"
+	MailFailed  = "failed"
+)
+
+// OutboxMail is a mail kept in the outbox until it is sent. NextAttemptAt is
+// when a worker may pick it up and LastError is why the last attempt failed.
+type OutboxMail struct {
+	ID            int
+	Mail          MailData
+	Status        string
+	Attempts      int
+	LastError     string
+	NextAttemptAt time.Time
+	SentAt        time.Time
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+}
@@ -0,0 +1,120 @@
+package models
+
+import "time"
+
+// Payment modes a booking can be paid in at checkout.
+const (
+	PaymentDeposit = "deposit"
+	PaymentFull    = "full"
+)
+
+// A payment is processing from when the guest submits it until the gateway
+// has authorized or refused it. A booking has at most one payment that is
+// processing, authorized or captured.
+const (
+	PaymentProcessing = "processing"
+	PaymentAuthorized = "authorized"
+	PaymentCaptured   = "captured"
+	PaymentFailed     = "failed"
+	PaymentRefunded   = "refunded"
+)
+
+// Payment is a charge taken for a booking through a payment gateway.
+// ProviderRef is the gateway's reference and Message explains a failure.
+type Payment struct {
+	ID            int
+	ReservationId int
+	Provider      string
+	ProviderRef   string
+	Kind          string
+	Status        string
+	Amount        int
+	Message       string
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+}
+
+// AmountDue is what the guest pays at checkout for a booking of the given
+// total: all of it, or the deposit percentage of it rounded to the cent.
+func AmountDue(total int, mode string, depositPercent int) int {
+	if mode != PaymentDeposit || depositPercent <= 0 || depositPercent >= 100 {
+		return total
+	}
+	return (total*depositPercent + 50) / 100
+}
+
+// Paid is the amount captured for the booking less what was refunded.
+func (r Reservation) Paid() int {
+	paid := 0
+	for _, payment := range r.Payments {
+		if payment.Status == PaymentCaptured || payment.Status == PaymentRefunded {
+			paid += payment.Amount
+		}
+	}
+	return paid - r.Refunded()
+}
+
+// Refunded is the amount returned to the guest, counting refunds still
+// waiting on the gateway.
+func (r Reservation) Refunded() int {
+	refunded := 0
+	for _, refund := range r.Refunds {
+		if refund.Status == RefundFailed {
+			continue
+		}
+		refunded += refund.Amount
+	}
+	return refunded
+}
+
+// BalanceDue is what is left to pay for the booking. It is negative when the
+// guest paid more than the booking now costs.
+func (r Reservation) BalanceDue() int {
+	return r.BookingTotal() - r.Paid()
+}
+
+// Refundable is what can be paid back without going below what the booking
+// costs under its cancellation policy.
+func (r Reservation) Refundable() int {
+	if balance := r.BalanceDue(); balance < 0 {
+		return -balance
+	}
+	return 0
+}
+
+// RefundableAmount is what is left to refund of a payment.
+func (p Payment) RefundableAmount(refunds []Refund) int {
+	if p.Status != PaymentCaptured && p.Status != PaymentRefunded {
+		return 0
+	}
+	left := p.Amount
+	for _, refund := range refunds {
+		if refund.PaymentId == p.ID && refund.Status != RefundFailed {
+			left -= refund.Amount
+		}
+	}
+	return left
+}
+
+// SplitRefund spreads a refund over the payments of the booking in order,
+// taking from each what is left to refund of it. The parts add up to less
+// than the amount when the payments do not cover it.
+func (r Reservation) SplitRefund(amount int) []Refund {
+	var parts []Refund
+	for _, payment := range r.Payments {
+		if amount <= 0 {
+			break
+		}
+		part := min(payment.RefundableAmount(r.Refunds), amount)
+		if part <= 0 {
+			continue
+		}
+		parts = append(parts, Refund{
+			ReservationId: r.ID,
+			PaymentId:     payment.ID,
+			Amount:        part,
+		})
+		amount -= part
+	}
+	return parts
+}
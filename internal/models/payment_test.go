@@ -0,0 +1,150 @@
+package models
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestAmountDue(t *testing.T) {
+	var tests = []struct {
+		name           string
+		total          int
+		mode           string
+		depositPercent int
+		expected       int
+	}{
+		{"full", 33000, PaymentFull, 30, 33000},
+		{"deposit", 33000, PaymentDeposit, 30, 9900},
+		{"deposit rounded to the cent", 33333, PaymentDeposit, 30, 10000},
+		{"no deposit percent", 33000, PaymentDeposit, 0, 33000},
+		{"deposit of everything", 33000, PaymentDeposit, 100, 33000},
+	}
+
+	for _, e := range tests {
+		if got := AmountDue(e.total, e.mode, e.depositPercent); got != e.expected {
+			t.Errorf("%s: expected %d but got %d", e.name, e.expected, got)
+		}
+	}
+}
+
+func TestReservationRefundable(t *testing.T) {
+	booking := func(total, penalty int, payments []Payment, refunds []Refund) Reservation {
+		res := Reservation{
+			ID:       1,
+			Status:   StatusConfirmed,
+			Total:    total,
+			Charges:  []ReservationCharge{{Amount: 3000}},
+			Payments: payments,
+			Refunds:  refunds,
+		}
+		if penalty >= 0 {
+			res.Status = StatusCancelled
+			res.CancellationPenalty = penalty
+		}
+		return res
+	}
+	paid := func(status string, amount int) []Payment {
+		return []Payment{{ID: 1, Status: status, Amount: amount}}
+	}
+
+	var tests = []struct {
+		name       string
+		res        Reservation
+		paid       int
+		balance    int
+		refundable int
+	}{
+		{"unpaid", booking(30000, -1, nil, nil), 0, 33000, 0},
+		{"deposit paid", booking(30000, -1, paid(PaymentCaptured, 9900), nil), 9900, 23100, 0},
+		{"paid in full", booking(30000, -1, paid(PaymentCaptured, 33000), nil), 33000, 0, 0},
+		{"stay shortened", booking(20000, -1, paid(PaymentCaptured, 33000), nil), 33000, -10000, 10000},
+		{"cancelled with penalty", booking(30000, 10000, paid(PaymentCaptured, 33000), nil), 33000, -23000, 23000},
+		{"partly refunded", booking(30000, 10000, paid(PaymentCaptured, 33000), []Refund{{PaymentId: 1, Amount: 5000}}), 28000, -18000, 18000},
+		{"fully refunded", booking(30000, 0, paid(PaymentRefunded, 33000), []Refund{{PaymentId: 1, Amount: 33000}}), 0, 0, 0},
+		{"refund pending", booking(30000, 10000, paid(PaymentCaptured, 33000), []Refund{{PaymentId: 1, Amount: 5000, Status: RefundPending}}), 28000, -18000, 18000},
+		{"refund failed", booking(30000, 10000, paid(PaymentCaptured, 33000), []Refund{{PaymentId: 1, Amount: 5000, Status: RefundFailed}}), 33000, -23000, 23000},
+		{"only authorized", booking(30000, 0, paid(PaymentAuthorized, 33000), nil), 0, 0, 0},
+		{"failed payment", booking(30000, -1, paid(PaymentFailed, 33000), nil), 0, 33000, 0},
+	}
+
+	for _, e := range tests {
+		if got := e.res.Paid(); got != e.paid {
+			t.Errorf("%s: expected paid %d but got %d", e.name, e.paid, got)
+		}
+		if got := e.res.BalanceDue(); got != e.balance {
+			t.Errorf("%s: expected balance due %d but got %d", e.name, e.balance, got)
+		}
+		if got := e.res.Refundable(); got != e.refundable {
+			t.Errorf("%s: expected refundable %d but got %d", e.name, e.refundable, got)
+		}
+	}
+}
+
+func TestPaymentRefundableAmount(t *testing.T) {
+	refunds := []Refund{
+		{PaymentId: 1, Amount: 4000},
+		{PaymentId: 2, Amount: 1000},
+		{PaymentId: 1, Amount: 1500},
+		{PaymentId: 1, Amount: 2000, Status: RefundFailed},
+	}
+
+	var tests = []struct {
+		name     string
+		payment  Payment
+		refunds  []Refund
+		expected int
+	}{
+		{"not refunded", Payment{ID: 1, Status: PaymentCaptured, Amount: 10000}, nil, 10000},
+		{"partly refunded", Payment{ID: 1, Status: PaymentCaptured, Amount: 10000}, refunds, 4500},
+		{"refunds of other payments", Payment{ID: 3, Status: PaymentCaptured, Amount: 10000}, refunds, 10000},
+		{"fully refunded", Payment{ID: 1, Status: PaymentRefunded, Amount: 5500}, refunds, 0},
+		{"authorized", Payment{ID: 1, Status: PaymentAuthorized, Amount: 10000}, nil, 0},
+		{"processing", Payment{ID: 1, Status: PaymentProcessing, Amount: 10000}, nil, 0},
+		{"failed", Payment{ID: 1, Status: PaymentFailed, Amount: 10000}, nil, 0},
+	}
+
+	for _, e := range tests {
+		if got := e.payment.RefundableAmount(e.refunds); got != e.expected {
+			t.Errorf("%s: expected %d but got %d", e.name, e.expected, got)
+		}
+	}
+}
+
+func TestReservationSplitRefund(t *testing.T) {
+	payments := []Payment{
+		{ID: 1, Status: PaymentFailed, Amount: 5000},
+		{ID: 2, Status: PaymentCaptured, Amount: 10000},
+		{ID: 3, Status: PaymentCaptured, Amount: 20000},
+	}
+
+	var tests = []struct {
+		name     string
+		refunds  []Refund
+		amount   int
+		expected []Refund
+	}{
+		{"from the first payment", nil, 4000, []Refund{{ReservationId: 7, PaymentId: 2, Amount: 4000}}},
+		{"all of the first payment", nil, 10000, []Refund{{ReservationId: 7, PaymentId: 2, Amount: 10000}}},
+		{"over two payments", nil, 15000, []Refund{
+			{ReservationId: 7, PaymentId: 2, Amount: 10000},
+			{ReservationId: 7, PaymentId: 3, Amount: 5000},
+		}},
+		{"after a partial refund", []Refund{{PaymentId: 2, Amount: 6000}}, 15000, []Refund{
+			{ReservationId: 7, PaymentId: 2, Amount: 4000},
+			{ReservationId: 7, PaymentId: 3, Amount: 11000},
+		}},
+		{"first payment refunded", []Refund{{PaymentId: 2, Amount: 10000}}, 5000, []Refund{{ReservationId: 7, PaymentId: 3, Amount: 5000}}},
+		{"more than was paid", nil, 40000, []Refund{
+			{ReservationId: 7, PaymentId: 2, Amount: 10000},
+			{ReservationId: 7, PaymentId: 3, Amount: 20000},
+		}},
+		{"nothing", nil, 0, nil},
+	}
+
+	for _, e := range tests {
+		res := Reservation{ID: 7, Payments: payments, Refunds: e.refunds}
+		if got := res.SplitRefund(e.amount); !reflect.DeepEqual(got, e.expected) {
+			t.Errorf("%s: expected %+v but got %+v", e.name, e.expected, got)
+		}
+	}
+}
@@ -0,0 +1,126 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	DiscountPercent = "percent"
+	DiscountFixed   = "fixed"
+)
+
+var DiscountTypes = []string{
+	DiscountPercent,
+	DiscountFixed,
+}
+
+func IsValidDiscountType(discountType string) bool {
+	for _, t := range DiscountTypes {
+		if t == discountType {
+			return true
+		}
+	}
+	return false
+}
+
+// PromoCode takes a discount off bookings. DiscountValue is a percentage for
+// percent codes and an amount in cents off the whole booking for fixed codes.
+// Zero dates, MinNights, MaxUses and an empty RoomTypeIds disable a check.
+// The stay window covers nights, the booking window the day the booking is made.
+type PromoCode struct {
+	ID            int
+	Code          string
+	Name          string
+	DiscountType  string
+	DiscountValue int
+	StayStart     time.Time
+	StayEnd       time.Time
+	BookStart     time.Time
+	BookEnd       time.Time
+	MinNights     int
+	MaxUses       int
+	Uses          int
+	RoomTypeIds   []int
+	Active        bool
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+}
+
+// NormalizePromoCode makes codes case insensitive.
+func NormalizePromoCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+func (p PromoCode) AppliesToRoomType(roomTypeId int) bool {
+	if len(p.RoomTypeIds) == 0 {
+		return true
+	}
+	for _, id := range p.RoomTypeIds {
+		if id == roomTypeId {
+			return true
+		}
+	}
+	return false
+}
+
+// UsableAt explains why the code cannot be used for a booking made at the
+// given time, or returns an empty string when it can.
+func (p PromoCode) UsableAt(at time.Time) string {
+	layout := "January 2, 2006"
+	day := at.Truncate(24 * time.Hour)
+
+	switch {
+	case !p.Active:
+		return fmt.Sprintf("Promo code %s is not valid", p.Code)
+	case !p.BookStart.IsZero() && day.Before(p.BookStart):
+		return fmt.Sprintf("Promo code %s can only be used from %s", p.Code, p.BookStart.Format(layout))
+	case !p.BookEnd.IsZero() && day.After(p.BookEnd):
+		return fmt.Sprintf("Promo code %s has expired", p.Code)
+	case p.MaxUses > 0 && p.Uses >= p.MaxUses:
+		return fmt.Sprintf("Promo code %s has been used up", p.Code)
+	}
+	return ""
+}
+
+// StayViolation explains why the code does not apply to a stay in a room of
+// the given type, or returns an empty string when it does.
+func (p PromoCode) StayViolation(roomTypeId int, start, end time.Time) string {
+	layout := "January 2, 2006"
+	nights := int(end.Sub(start).Hours() / 24)
+
+	switch {
+	case !p.AppliesToRoomType(roomTypeId):
+		return fmt.Sprintf("Promo code %s does not apply to this room", p.Code)
+	case !p.StayStart.IsZero() && start.Before(p.StayStart):
+		return fmt.Sprintf("Promo code %s is only valid for stays from %s", p.Code, p.StayStart.Format(layout))
+	case !p.StayEnd.IsZero() && end.After(p.StayEnd.AddDate(0, 0, 1)):
+		return fmt.Sprintf("Promo code %s is only valid for stays until %s", p.Code, p.StayEnd.Format(layout))
+	case p.MinNights > 0 && nights < p.MinNights:
+		return fmt.Sprintf("Promo code %s requires a stay of at least %d nights", p.Code, p.MinNights)
+	}
+	return ""
+}
+
+// Discounts spreads the discount over the rooms of a booking. Only rooms
+// marked eligible get a discount and no room is discounted below zero. A
+// fixed amount is taken off the eligible rooms in order until it is used up.
+func (p PromoCode) Discounts(totals []int, eligible []bool) []int {
+	discounts := make([]int, len(totals))
+	remaining := p.DiscountValue
+
+	for i, total := range totals {
+		if !eligible[i] {
+			continue
+		}
+		switch p.DiscountType {
+		case DiscountPercent:
+			discounts[i] = total * p.DiscountValue / 100
+		case DiscountFixed:
+			discounts[i] = min(remaining, total)
+			remaining -= discounts[i]
+		}
+	}
+	return discounts
+}
@@ -0,0 +1,95 @@
+package models
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func date(month time.Month, day int) time.Time {
+	return time.Date(2026, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+func TestPromoCodeDiscounts(t *testing.T) {
+	var tests = []struct {
+		name          string
+		discountType  string
+		discountValue int
+		totals        []int
+		eligible      []bool
+		expected      []int
+	}{
+		{"percent", DiscountPercent, 10, []int{10000, 15000}, []bool{true, true}, []int{1000, 1500}},
+		{"percent rounds down", DiscountPercent, 10, []int{9999}, []bool{true}, []int{999}},
+		{"percent of eligible rooms only", DiscountPercent, 20, []int{10000, 15000}, []bool{false, true}, []int{0, 3000}},
+		{"whole price", DiscountPercent, 100, []int{10000, 15000}, []bool{true, true}, []int{10000, 15000}},
+		{"fixed on the first room", DiscountFixed, 5000, []int{10000, 15000}, []bool{true, true}, []int{5000, 0}},
+		{"fixed spread over rooms", DiscountFixed, 12000, []int{10000, 15000}, []bool{true, true}, []int{10000, 2000}},
+		{"fixed capped at the total", DiscountFixed, 30000, []int{10000, 15000}, []bool{true, true}, []int{10000, 15000}},
+		{"fixed skips ineligible rooms", DiscountFixed, 5000, []int{10000, 15000}, []bool{false, true}, []int{0, 5000}},
+		{"nothing eligible", DiscountFixed, 5000, []int{10000}, []bool{false}, []int{0}},
+	}
+
+	for _, e := range tests {
+		promo := PromoCode{DiscountType: e.discountType, DiscountValue: e.discountValue}
+		if got := promo.Discounts(e.totals, e.eligible); !reflect.DeepEqual(got, e.expected) {
+			t.Errorf("%s: expected %v but got %v", e.name, e.expected, got)
+		}
+	}
+}
+
+func TestPromoCodeUsableAt(t *testing.T) {
+	var tests = []struct {
+		name     string
+		promo    PromoCode
+		at       time.Time
+		expected string
+	}{
+		{"usable", PromoCode{Code: "FALL", Active: true}, date(10, 17), ""},
+		{"inactive", PromoCode{Code: "FALL"}, date(10, 17), "Promo code FALL is not valid"},
+		{"before booking window", PromoCode{Code: "FALL", Active: true, BookStart: date(11, 1)}, date(10, 17), "Promo code FALL can only be used from November 1, 2026"},
+		{"first day of booking window", PromoCode{Code: "FALL", Active: true, BookStart: date(10, 17)}, date(10, 17).Add(9 * time.Hour), ""},
+		{"last day of booking window", PromoCode{Code: "FALL", Active: true, BookEnd: date(10, 17)}, date(10, 17).Add(23 * time.Hour), ""},
+		{"after booking window", PromoCode{Code: "FALL", Active: true, BookEnd: date(10, 16)}, date(10, 17), "Promo code FALL has expired"},
+		{"uses left", PromoCode{Code: "FALL", Active: true, MaxUses: 3, Uses: 2}, date(10, 17), ""},
+		{"used up", PromoCode{Code: "FALL", Active: true, MaxUses: 3, Uses: 3}, date(10, 17), "Promo code FALL has been used up"},
+		{"no use limit", PromoCode{Code: "FALL", Active: true, Uses: 500}, date(10, 17), ""},
+	}
+
+	for _, e := range tests {
+		if got := e.promo.UsableAt(e.at); got != e.expected {
+			t.Errorf("%s: expected %q but got %q", e.name, e.expected, got)
+		}
+	}
+}
+
+func TestPromoCodeStayViolation(t *testing.T) {
+	var tests = []struct {
+		name     string
+		promo    PromoCode
+		start    time.Time
+		end      time.Time
+		expected string
+	}{
+		{"any stay", PromoCode{Code: "FALL"}, date(11, 2), date(11, 4), ""},
+		{"room type", PromoCode{Code: "FALL", RoomTypeIds: []int{1}}, date(11, 2), date(11, 4), ""},
+		{"other room type", PromoCode{Code: "FALL", RoomTypeIds: []int{2, 3}}, date(11, 2), date(11, 4), "Promo code FALL does not apply to this room"},
+		{"before stay window", PromoCode{Code: "FALL", StayStart: date(11, 3)}, date(11, 2), date(11, 4), "Promo code FALL is only valid for stays from November 3, 2026"},
+		{"last night in stay window", PromoCode{Code: "FALL", StayEnd: date(11, 3)}, date(11, 2), date(11, 4), ""},
+		{"after stay window", PromoCode{Code: "FALL", StayEnd: date(11, 2)}, date(11, 2), date(11, 4), "Promo code FALL is only valid for stays until November 2, 2026"},
+		{"enough nights", PromoCode{Code: "FALL", MinNights: 2}, date(11, 2), date(11, 4), ""},
+		{"too few nights", PromoCode{Code: "FALL", MinNights: 3}, date(11, 2), date(11, 4), "Promo code FALL requires a stay of at least 3 nights"},
+	}
+
+	for _, e := range tests {
+		if got := e.promo.StayViolation(1, e.start, e.end); got != e.expected {
+			t.Errorf("%s: expected %q but got %q", e.name, e.expected, got)
+		}
+	}
+}
+
+func TestNormalizePromoCode(t *testing.T) {
+	if got := NormalizePromoCode("  fall10 "); got != "FALL10" {
+		t.Errorf("expected FALL10 but got %q", got)
+	}
+}
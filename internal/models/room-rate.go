@@ -0,0 +1,48 @@
+package models
+
+import "time"
+
+// RoomRate overrides the base nightly rate of a room type for the nights between
+// StartDate and EndDate inclusive. Weekdays is a bit mask built with
+// WeekdayMask; zero means the override applies to every night in the range.
+type RoomRate struct {
+	ID         int
+	RoomTypeId int
+	Name       string
+	StartDate  time.Time
+	EndDate    time.Time
+	Weekdays   int
+	Rate       int
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+	RoomType   RoomType
+}
+
+var Weekdays = []time.Weekday{
+	time.Monday,
+	time.Tuesday,
+	time.Wednesday,
+	time.Thursday,
+	time.Friday,
+	time.Saturday,
+	time.Sunday,
+}
+
+func WeekdayMask(days ...time.Weekday) int {
+	mask := 0
+	for _, day := range days {
+		mask |= 1 << uint(day)
+	}
+	return mask
+}
+
+func (r RoomRate) HasWeekday(day time.Weekday) bool {
+	return r.Weekdays&WeekdayMask(day) != 0
+}
+
+func (r RoomRate) AppliesTo(night time.Time) bool {
+	if night.Before(r.StartDate) || night.After(r.EndDate) {
+		return false
+	}
+	return r.Weekdays == 0 || r.HasWeekday(night.Weekday())
+}
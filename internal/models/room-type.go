@@ -0,0 +1,59 @@
+package models
+
+import (
+	"regexp"
+	"strings"
+	"time"
+)
+
+// RoomType is what guests book. Physical rooms belong to a type and one of
+// them is assigned to the reservation.
+type RoomType struct {
+	ID                   int
+	Name                 string
+	Slug                 string
+	Description          string
+	Capacity             int
+	MaxAdults            int
+	Amenities            []string
+	Photos               []string
+	BaseRate             int
+	CancellationPolicyId int
+	CreatedAt            time.Time
+	UpdatedAt            time.Time
+}
+
+// AdultCapacity is the number of adults a room of this type sleeps.
+func (t RoomType) AdultCapacity() int {
+	if t.MaxAdults > 0 && t.MaxAdults < t.Capacity {
+		return t.MaxAdults
+	}
+	return t.Capacity
+}
+
+// Fits reports whether one room of this type sleeps the given number of
+// adults and guests in total.
+func (t RoomType) Fits(adults, guests int) bool {
+	return guests <= t.Capacity && adults <= t.AdultCapacity()
+}
+
+type RoomTypeAvailability struct {
+	RoomType  RoomType
+	FreeUnits int
+}
+
+var (
+	slugPattern  = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+	nonSlugChars = regexp.MustCompile(`[^a-z0-9]+`)
+)
+
+// IsSlug reports whether s can be used in a /rooms/{slug} URL.
+func IsSlug(s string) bool {
+	return slugPattern.MatchString(s)
+}
+
+// Slugify turns a room type name into a URL slug, e.g. "Major's Suite"
+// becomes "major-s-suite".
+func Slugify(name string) string {
+	return strings.Trim(nonSlugChars.ReplaceAllString(strings.ToLower(name), "-"), "-")
+}
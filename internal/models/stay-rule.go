@@ -0,0 +1,83 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// StayRule restricts stays of a room type arriving between StartDate and
+// EndDate inclusive. Zero values disable a check; ArrivalWeekdays is a mask
+// built with WeekdayMask. Closed to departure applies to departures in the
+// range instead.
+type StayRule struct {
+	ID                int
+	RoomTypeId        int
+	Name              string
+	StartDate         time.Time
+	EndDate           time.Time
+	MinNights         int
+	MaxNights         int
+	ClosedToArrival   bool
+	ClosedToDeparture bool
+	ArrivalWeekdays   int
+	CreatedAt         time.Time
+	UpdatedAt         time.Time
+	RoomType          RoomType
+}
+
+func (s StayRule) covers(day time.Time) bool {
+	return !day.Before(s.StartDate) && !day.After(s.EndDate)
+}
+
+func (s StayRule) HasArrivalWeekday(day time.Weekday) bool {
+	return s.ArrivalWeekdays&WeekdayMask(day) != 0
+}
+
+func (s StayRule) arrivalDays() string {
+	var names []string
+	for _, day := range Weekdays {
+		if s.HasArrivalWeekday(day) {
+			names = append(names, day.String())
+		}
+	}
+	return strings.Join(names, ", ")
+}
+
+// Violation explains why a stay from start to end breaks the rule, or returns
+// an empty string when it does not.
+func (s StayRule) Violation(start, end time.Time) string {
+	layout := "January 2"
+	nights := int(end.Sub(start).Hours() / 24)
+
+	if s.covers(start) {
+		switch {
+		case s.ClosedToArrival:
+			return fmt.Sprintf("Arrivals are not possible on %s", start.Format(layout))
+		case s.ArrivalWeekdays != 0 && !s.HasArrivalWeekday(start.Weekday()):
+			return fmt.Sprintf("Arrivals between %s and %s are only possible on %s",
+				s.StartDate.Format(layout), s.EndDate.Format(layout), s.arrivalDays())
+		case s.MinNights > 0 && nights < s.MinNights:
+			return fmt.Sprintf("Stays arriving between %s and %s must be at least %d nights",
+				s.StartDate.Format(layout), s.EndDate.Format(layout), s.MinNights)
+		case s.MaxNights > 0 && nights > s.MaxNights:
+			return fmt.Sprintf("Stays arriving between %s and %s can be at most %d nights",
+				s.StartDate.Format(layout), s.EndDate.Format(layout), s.MaxNights)
+		}
+	}
+
+	if s.ClosedToDeparture && s.covers(end) {
+		return fmt.Sprintf("Departures are not possible on %s", end.Format(layout))
+	}
+	return ""
+}
+
+// StayViolation returns the first rule violation of a stay, if any.
+func StayViolation(rules []StayRule, start, end time.Time) string {
+	for _, rule := range rules {
+		if msg := rule.Violation(start, end); msg != "" {
+			return msg
+		}
+	}
+	return ""
+}
@@ -0,0 +1,77 @@
+package models
+
+import "time"
+
+const (
+	FeePerPersonNight = "per_person_night"
+	FeePercent        = "percent"
+	FeePerStay        = "per_stay"
+)
+
+var FeeKinds = []string{
+	FeePerPersonNight,
+	FeePercent,
+	FeePerStay,
+}
+
+func IsValidFeeKind(kind string) bool {
+	for _, k := range FeeKinds {
+		if k == kind {
+			return true
+		}
+	}
+	return false
+}
+
+// TaxFee is an entry of the tax and fee catalog charged on top of the room
+// price. Amount is in cents per person and night or per stay, and in
+// hundredths of a percent of the room price for percent taxes.
+type TaxFee struct {
+	ID             int
+	Name           string
+	Kind           string
+	Amount         int
+	ExemptChildren bool
+	Active         bool
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+}
+
+// ReservationCharge is a tax or fee line stored with a reservation. Amount
+// is the total in cents for Quantity units.
+type ReservationCharge struct {
+	ID            int
+	ReservationId int
+	TaxFeeId      int
+	Name          string
+	Kind          string
+	Quantity      int
+	Amount        int
+}
+
+// Charge works out what the tax or fee adds to a room, or returns false when
+// it adds nothing.
+func (t TaxFee) Charge(res Reservation) (ReservationCharge, bool) {
+	charge := ReservationCharge{
+		TaxFeeId: t.ID,
+		Name:     t.Name,
+		Kind:     t.Kind,
+		Quantity: 1,
+	}
+
+	switch t.Kind {
+	case FeePerPersonNight:
+		people := res.Adults + res.Children
+		if t.ExemptChildren {
+			people = res.Adults
+		}
+		nights := int(res.EndDate.Sub(res.StartDate).Hours() / 24)
+		charge.Quantity = people * nights
+		charge.Amount = charge.Quantity * t.Amount
+	case FeePercent:
+		charge.Amount = (res.Total*t.Amount + 5000) / 10000
+	case FeePerStay:
+		charge.Amount = t.Amount
+	}
+	return charge, charge.Amount > 0
+}
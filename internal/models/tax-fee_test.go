@@ -0,0 +1,55 @@
+package models
+
+import "testing"
+
+func TestTaxFeeCharge(t *testing.T) {
+	stay := Reservation{
+		StartDate: date(11, 2),
+		EndDate:   date(11, 5),
+		Adults:    2,
+		Children:  1,
+		Total:     30000,
+	}
+
+	withTotal := func(total int) Reservation {
+		res := stay
+		res.Total = total
+		return res
+	}
+
+	var tests = []struct {
+		name     string
+		fee      TaxFee
+		res      Reservation
+		quantity int
+		amount   int
+		charged  bool
+	}{
+		{"per person night", TaxFee{Kind: FeePerPersonNight, Amount: 250}, stay, 9, 2250, true},
+		{"children exempt", TaxFee{Kind: FeePerPersonNight, Amount: 250, ExemptChildren: true}, stay, 6, 1500, true},
+		{"no nights", TaxFee{Kind: FeePerPersonNight, Amount: 250}, Reservation{StartDate: date(11, 2), EndDate: date(11, 2), Adults: 2}, 0, 0, false},
+		{"percent", TaxFee{Kind: FeePercent, Amount: 1000}, stay, 1, 3000, true},
+		{"fraction of a percent", TaxFee{Kind: FeePercent, Amount: 825}, stay, 1, 2475, true},
+		{"percent rounds half up", TaxFee{Kind: FeePercent, Amount: 500}, withTotal(10010), 1, 501, true},
+		{"percent rounds down", TaxFee{Kind: FeePercent, Amount: 500}, withTotal(10009), 1, 500, true},
+		{"percent of nothing", TaxFee{Kind: FeePercent, Amount: 1000}, withTotal(0), 1, 0, false},
+		{"per stay", TaxFee{Kind: FeePerStay, Amount: 1500}, stay, 1, 1500, true},
+		{"unknown kind", TaxFee{Kind: "per_pet", Amount: 1500}, stay, 1, 0, false},
+	}
+
+	for _, e := range tests {
+		e.fee.ID = 4
+		e.fee.Name = "City tax"
+
+		charge, charged := e.fee.Charge(e.res)
+		if charged != e.charged {
+			t.Errorf("%s: expected charged to be %t but got %t", e.name, e.charged, charged)
+		}
+		if charge.Quantity != e.quantity || charge.Amount != e.amount {
+			t.Errorf("%s: expected %d for %d units but got %d for %d units", e.name, e.amount, e.quantity, charge.Amount, charge.Quantity)
+		}
+		if charge.TaxFeeId != 4 || charge.Name != "City tax" || charge.Kind != e.fee.Kind {
+			t.Errorf("%s: charge does not describe the fee: %+v", e.name, charge)
+		}
+	}
+}
@@ -0,0 +1,142 @@
+package payments
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// Cards the fake gateway refuses. Any other card number is accepted.
+// FakeCardCaptureFails is authorized but cannot be captured.
+const (
+	FakeCardDeclined          = "4000000000000002"
+	FakeCardInsufficientFunds = "4000000000009995"
+	FakeCardCaptureFails      = "4000000000000259"
+)
+
+type fakePayment struct {
+	authorized  int
+	captured    int
+	refunded    int
+	failCapture bool
+}
+
+// FakeGateway is an in-memory provider for development and tests. It keeps
+// payments for the life of the process and signs webhooks with an HMAC of the
+// payload.
+type FakeGateway struct {
+	secret []byte
+
+	mu       sync.Mutex
+	payments map[string]*fakePayment
+	lastId   int
+}
+
+func NewFakeGateway(secret string) *FakeGateway {
+	return &FakeGateway{
+		secret:   []byte(secret),
+		payments: make(map[string]*fakePayment),
+	}
+}
+
+func (g *FakeGateway) Name() string {
+	return "fake"
+}
+
+func (g *FakeGateway) Authorize(ctx context.Context, amount int, source, reference string) (Result, error) {
+	if amount <= 0 {
+		return Result{}, ErrInvalidAmount
+	}
+
+	card := strings.NewReplacer(" ", "", "-", "").Replace(source)
+	switch card {
+	case FakeCardDeclined:
+		return Result{Amount: amount, Message: "Your card was declined"}, ErrDeclined
+	case FakeCardInsufficientFunds:
+		return Result{Amount: amount, Message: "Your card has insufficient funds"}, ErrDeclined
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.lastId++
+	id := fmt.Sprintf("fake_%d", g.lastId)
+	g.payments[id] = &fakePayment{authorized: amount, failCapture: card == FakeCardCaptureFails}
+
+	return Result{ID: id, Amount: amount}, nil
+}
+
+func (g *FakeGateway) Capture(ctx context.Context, paymentId string, amount int) (Result, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	payment, ok := g.payments[paymentId]
+	if !ok {
+		return Result{}, ErrUnknownPayment
+	}
+	if amount <= 0 || payment.captured+amount > payment.authorized {
+		return Result{}, ErrInvalidAmount
+	}
+	if payment.failCapture {
+		return Result{ID: paymentId, Message: "The payment could not be captured"}, ErrDeclined
+	}
+
+	payment.captured += amount
+	return Result{ID: paymentId, Amount: amount}, nil
+}
+
+func (g *FakeGateway) Void(ctx context.Context, paymentId string) (Result, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	payment, ok := g.payments[paymentId]
+	if !ok {
+		return Result{}, ErrUnknownPayment
+	}
+
+	released := payment.authorized - payment.captured
+	payment.authorized = payment.captured
+	return Result{ID: paymentId, Amount: released}, nil
+}
+
+func (g *FakeGateway) Refund(ctx context.Context, paymentId string, amount int) (Result, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	payment, ok := g.payments[paymentId]
+	if !ok {
+		return Result{}, ErrUnknownPayment
+	}
+	if amount <= 0 || payment.refunded+amount > payment.captured {
+		return Result{}, ErrInvalidAmount
+	}
+
+	payment.refunded += amount
+	return Result{ID: paymentId, Amount: amount}, nil
+}
+
+// Sign returns the signature the fake provider sends with a webhook payload.
+func (g *FakeGateway) Sign(payload []byte) string {
+	mac := hmac.New(sha256.New, g.secret)
+	mac.Write(payload)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func (g *FakeGateway) VerifyWebhook(payload []byte, signature string) (Event, error) {
+	var event Event
+
+	if !hmac.Equal([]byte(g.Sign(payload)), []byte(signature)) {
+		return event, ErrInvalidSignature
+	}
+
+	err := json.Unmarshal(payload, &event)
+	if err != nil {
+		return event, err
+	}
+	return event, nil
+}
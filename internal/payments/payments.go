@@ -0,0 +1,52 @@
+package payments
+
+import (
+	"context"
+	"errors"
+)
+
+var (
+	ErrDeclined         = errors.New("payment was declined")
+	ErrUnknownPayment   = errors.New("payment not found")
+	ErrInvalidAmount    = errors.New("amount is not valid for this payment")
+	ErrInvalidSignature = errors.New("webhook signature is not valid")
+)
+
+// Event types sent by providers to the webhook.
+const (
+	EventCaptured = "payment.captured"
+	EventFailed   = "payment.failed"
+	EventRefunded = "payment.refunded"
+)
+
+// Result describes a payment at the provider after a call. ID is the
+// provider's reference for the payment and Message explains a decline.
+type Result struct {
+	ID      string
+	Amount  int
+	Message string
+}
+
+// Event is a notification the provider sends about a payment. For a refund
+// Amount is the total refunded of the payment so far, or zero when all of it
+// was refunded.
+type Event struct {
+	Type      string `json:"type"`
+	PaymentId string `json:"payment_id"`
+	Amount    int    `json:"amount"`
+	Message   string `json:"message"`
+}
+
+// PaymentGateway takes payments through a provider. Amounts are in cents.
+// Authorize reserves the amount on the card or token given as source, and
+// returns ErrDeclined with the reason in the result when the provider refuses
+// it. Capture collects up to the authorized amount, Void releases what was
+// authorized and not captured, and Refund returns up to the captured amount.
+type PaymentGateway interface {
+	Name() string
+	Authorize(ctx context.Context, amount int, source, reference string) (Result, error)
+	Capture(ctx context.Context, paymentId string, amount int) (Result, error)
+	Void(ctx context.Context, paymentId string) (Result, error)
+	Refund(ctx context.Context, paymentId string, amount int) (Result, error)
+	VerifyWebhook(payload []byte, signature string) (Event, error)
+}
@@ -0,0 +1,19 @@
+package pricing
+
+import "github.com/NhanNT-VNG/hotel-booking/internal/models"
+
+// Charges layers the active taxes and fees of the catalog on a room. The
+// room Total must already be final, discounts included, as percent taxes are
+// charged on it.
+func Charges(catalog []models.TaxFee, res models.Reservation) []models.ReservationCharge {
+	var charges []models.ReservationCharge
+	for _, fee := range catalog {
+		if !fee.Active {
+			continue
+		}
+		if charge, ok := fee.Charge(res); ok {
+			charges = append(charges, charge)
+		}
+	}
+	return charges
+}
@@ -0,0 +1,60 @@
+package pricing
+
+import (
+	"testing"
+
+	"github.com/NhanNT-VNG/hotel-booking/internal/models"
+)
+
+func TestCharges(t *testing.T) {
+	catalog := []models.TaxFee{
+		{ID: 1, Name: "VAT", Kind: models.FeePercent, Amount: 1000, Active: true},
+		{ID: 2, Name: "City tax", Kind: models.FeePerPersonNight, Amount: 200, ExemptChildren: true, Active: true},
+		{ID: 3, Name: "Old resort fee", Kind: models.FeePerStay, Amount: 2500},
+		{ID: 4, Name: "Cleaning", Kind: models.FeePerStay, Amount: 1500, Active: true},
+	}
+	res := models.Reservation{
+		StartDate: day(11, 2),
+		EndDate:   day(11, 4),
+		Adults:    2,
+		Children:  2,
+		Total:     20000,
+	}
+
+	var tests = []struct {
+		name     string
+		res      models.Reservation
+		expected []models.ReservationCharge
+	}{
+		{
+			name: "active fees",
+			res:  res,
+			expected: []models.ReservationCharge{
+				{TaxFeeId: 1, Name: "VAT", Kind: models.FeePercent, Quantity: 1, Amount: 2000},
+				{TaxFeeId: 2, Name: "City tax", Kind: models.FeePerPersonNight, Quantity: 4, Amount: 800},
+				{TaxFeeId: 4, Name: "Cleaning", Kind: models.FeePerStay, Quantity: 1, Amount: 1500},
+			},
+		},
+		{
+			name: "fully discounted room",
+			res:  models.Reservation{StartDate: res.StartDate, EndDate: res.EndDate, Adults: 1},
+			expected: []models.ReservationCharge{
+				{TaxFeeId: 2, Name: "City tax", Kind: models.FeePerPersonNight, Quantity: 2, Amount: 400},
+				{TaxFeeId: 4, Name: "Cleaning", Kind: models.FeePerStay, Quantity: 1, Amount: 1500},
+			},
+		},
+	}
+
+	for _, e := range tests {
+		charges := Charges(catalog, e.res)
+		if len(charges) != len(e.expected) {
+			t.Errorf("%s: expected %d charges but got %d: %+v", e.name, len(e.expected), len(charges), charges)
+			continue
+		}
+		for i, charge := range charges {
+			if charge != e.expected[i] {
+				t.Errorf("%s: expected %+v but got %+v", e.name, e.expected[i], charge)
+			}
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package pricing
+
+import (
+	"context"
+	"time"
+
+	"github.com/NhanNT-VNG/hotel-booking/internal/models"
+	"github.com/NhanNT-VNG/hotel-booking/internal/repository"
+)
+
+type Night struct {
+	Date     time.Time
+	Rate     int
+	RateName string
+}
+
+// Quote is the price of a stay in cents, broken down per night.
+type Quote struct {
+	RoomTypeId int
+	StartDate  time.Time
+	EndDate    time.Time
+	Nights     []Night
+	Total      int
+}
+
+func (q Quote) FirstNight() int {
+	if len(q.Nights) == 0 {
+		return 0
+	}
+	return q.Nights[0].Rate
+}
+
+// DiscountedFirstNight is the first night less its share of a discount taken
+// off the whole stay, rounded to the cent.
+func (q Quote) DiscountedFirstNight(discount int) int {
+	first := q.FirstNight()
+	if q.Total <= 0 || discount <= 0 {
+		return first
+	}
+	return first - (first*discount+q.Total/2)/q.Total
+}
+
+// Calculate prices every night from start up to, but not including, end.
+// When several overrides apply to the same night the most recently created wins.
+func Calculate(roomType models.RoomType, rates []models.RoomRate, start, end time.Time) Quote {
+	quote := Quote{
+		RoomTypeId: roomType.ID,
+		StartDate:  start,
+		EndDate:    end,
+	}
+
+	for night := start; night.Before(end); night = night.AddDate(0, 0, 1) {
+		price := Night{
+			Date: night,
+			Rate: roomType.BaseRate,
+		}
+
+		overrideId := 0
+		for _, rate := range rates {
+			if rate.RoomTypeId == roomType.ID && rate.ID > overrideId && rate.AppliesTo(night) {
+				overrideId = rate.ID
+				price.Rate = rate.Rate
+				price.RateName = rate.Name
+			}
+		}
+
+		quote.Nights = append(quote.Nights, price)
+		quote.Total += price.Rate
+	}
+
+	return quote
+}
+
+func QuoteRoomType(ctx context.Context, db repository.DatabaseRepo, roomTypeId int, start, end time.Time) (Quote, error) {
+	roomType, err := db.GetRoomTypeById(ctx, roomTypeId)
+	if err != nil {
+		return Quote{}, err
+	}
+
+	rates, err := db.RoomRatesForPeriod(ctx, roomTypeId, start, end)
+	if err != nil {
+		return Quote{}, err
+	}
+
+	return Calculate(roomType, rates, start, end), nil
+}
+
+// LowestRate is the cheapest night in the quote, used for "from" prices.
+func (q Quote) LowestRate() int {
+	lowest := 0
+	for i, night := range q.Nights {
+		if i == 0 || night.Rate < lowest {
+			lowest = night.Rate
+		}
+	}
+	return lowest
+}
+
+// PriceFrom returns the lowest nightly rate of a room type over the next year.
+func PriceFrom(ctx context.Context, db repository.DatabaseRepo, roomType models.RoomType) (int, error) {
+	start := time.Now().Truncate(24 * time.Hour)
+	end := start.AddDate(1, 0, 0)
+
+	rates, err := db.RoomRatesForPeriod(ctx, roomType.ID, start, end)
+	if err != nil {
+		return 0, err
+	}
+
+	return Calculate(roomType, rates, start, end).LowestRate(), nil
+}
@@ -0,0 +1,152 @@
+package pricing
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/NhanNT-VNG/hotel-booking/internal/models"
+)
+
+func day(month time.Month, d int) time.Time {
+	return time.Date(2026, month, d, 0, 0, 0, 0, time.UTC)
+}
+
+func TestCalculate(t *testing.T) {
+	roomType := models.RoomType{ID: 1, BaseRate: 10000}
+	weekend := models.WeekdayMask(time.Saturday, time.Sunday)
+
+	var tests = []struct {
+		name   string
+		rates  []models.RoomRate
+		start  time.Time
+		end    time.Time
+		nights []int
+		names  []string
+		total  int
+	}{
+		{
+			name:  "base rate",
+			start: day(11, 2), end: day(11, 5),
+			nights: []int{10000, 10000, 10000},
+			names:  []string{"", "", ""},
+			total:  30000,
+		},
+		{
+			name:  "override on one night",
+			rates: []models.RoomRate{{ID: 1, RoomTypeId: 1, Name: "Festival", StartDate: day(11, 3), EndDate: day(11, 3), Rate: 12000}},
+			start: day(11, 2), end: day(11, 5),
+			nights: []int{10000, 12000, 10000},
+			names:  []string{"", "Festival", ""},
+			total:  32000,
+		},
+		{
+			name:  "override end date is included",
+			rates: []models.RoomRate{{ID: 1, RoomTypeId: 1, Name: "Low", StartDate: day(10, 1), EndDate: day(11, 3), Rate: 8000}},
+			start: day(11, 2), end: day(11, 5),
+			nights: []int{8000, 8000, 10000},
+			names:  []string{"Low", "Low", ""},
+			total:  26000,
+		},
+		{
+			name: "latest override wins",
+			rates: []models.RoomRate{
+				{ID: 7, RoomTypeId: 1, Name: "Late", StartDate: day(11, 3), EndDate: day(11, 4), Rate: 15000},
+				{ID: 3, RoomTypeId: 1, Name: "Early", StartDate: day(11, 1), EndDate: day(11, 30), Rate: 9000},
+			},
+			start: day(11, 2), end: day(11, 5),
+			nights: []int{9000, 15000, 15000},
+			names:  []string{"Early", "Late", "Late"},
+			total:  39000,
+		},
+		{
+			name:  "other room types are ignored",
+			rates: []models.RoomRate{{ID: 1, RoomTypeId: 2, Name: "Suite", StartDate: day(11, 1), EndDate: day(11, 30), Rate: 20000}},
+			start: day(11, 2), end: day(11, 4),
+			nights: []int{10000, 10000},
+			names:  []string{"", ""},
+			total:  20000,
+		},
+		{
+			name:  "weekday rate",
+			rates: []models.RoomRate{{ID: 1, RoomTypeId: 1, Name: "Weekend", StartDate: day(11, 1), EndDate: day(11, 30), Weekdays: weekend, Rate: 13000}},
+			start: day(11, 6), end: day(11, 9),
+			nights: []int{10000, 13000, 13000},
+			names:  []string{"", "Weekend", "Weekend"},
+			total:  36000,
+		},
+		{
+			name:  "no nights",
+			start: day(11, 2), end: day(11, 2),
+			total: 0,
+		},
+	}
+
+	for _, e := range tests {
+		quote := Calculate(roomType, e.rates, e.start, e.end)
+
+		var nights []int
+		var names []string
+		for i, night := range quote.Nights {
+			if want := e.start.AddDate(0, 0, i); !night.Date.Equal(want) {
+				t.Errorf("%s: expected night %d on %s but got %s", e.name, i, want.Format("2006-01-02"), night.Date.Format("2006-01-02"))
+			}
+			nights = append(nights, night.Rate)
+			names = append(names, night.RateName)
+		}
+
+		if !reflect.DeepEqual(nights, e.nights) {
+			t.Errorf("%s: expected nightly rates %v but got %v", e.name, e.nights, nights)
+		}
+		if !reflect.DeepEqual(names, e.names) {
+			t.Errorf("%s: expected rate names %q but got %q", e.name, e.names, names)
+		}
+		if quote.Total != e.total {
+			t.Errorf("%s: expected total %d but got %d", e.name, e.total, quote.Total)
+		}
+	}
+}
+
+func TestQuoteRates(t *testing.T) {
+	quote := Quote{
+		Nights: []Night{{Rate: 12000}, {Rate: 9000}, {Rate: 11000}},
+		Total:  32000,
+	}
+
+	if first := quote.FirstNight(); first != 12000 {
+		t.Errorf("expected first night 12000 but got %d", first)
+	}
+	if lowest := quote.LowestRate(); lowest != 9000 {
+		t.Errorf("expected lowest rate 9000 but got %d", lowest)
+	}
+	if first := (Quote{}).FirstNight(); first != 0 {
+		t.Errorf("expected no first night on an empty quote but got %d", first)
+	}
+}
+
+func TestDiscountedFirstNight(t *testing.T) {
+	quote := Quote{
+		Nights: []Night{{Rate: 10000}, {Rate: 12000}, {Rate: 10000}},
+		Total:  32000,
+	}
+
+	var tests = []struct {
+		name     string
+		quote    Quote
+		discount int
+		expected int
+	}{
+		{"no discount", quote, 0, 10000},
+		{"negative discount", quote, -500, 10000},
+		{"ten percent", quote, 3200, 9000},
+		{"rounded to the cent", quote, 1000, 9687},
+		{"whole stay", quote, 32000, 0},
+		{"empty quote", Quote{}, 1000, 0},
+	}
+
+	for _, e := range tests {
+		if got := e.quote.DiscountedFirstNight(e.discount); got != e.expected {
+			t.Errorf("%s: expected %d but got %d", e.name, e.expected, got)
+		}
+	}
+}
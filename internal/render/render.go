@@ -15,10 +15,13 @@ import (
 )
 
 var functions = template.FuncMap{
-	"humanDate":  HumanDate,
-	"formatDate": FormatDate,
-	"iterate":    Iterate,
-	"add":        Add,
+	"humanDate":    HumanDate,
+	"formatDate":   FormatDate,
+	"iterate":      Iterate,
+	"add":          Add,
+	"statusLabel":  models.StatusLabel,
+	"formatMoney":  FormatMoney,
+	"formatAmount": FormatAmount,
 }
 var app *config.AppConfig
 
@@ -34,6 +37,25 @@ func FormatDate(t time.Time, layout string) string {
 	return t.Format(layout)
 }
 
+func FormatMoney(cents int) string {
+	sign := ""
+	if cents < 0 {
+		sign = "-"
+		cents = -cents
+	}
+	return fmt.Sprintf("%s$%d.%02d", sign, cents/100, cents%100)
+}
+
+// FormatAmount renders cents as a plain decimal suitable for form inputs.
+func FormatAmount(cents int) string {
+	sign := ""
+	if cents < 0 {
+		sign = "-"
+		cents = -cents
+	}
+	return fmt.Sprintf("%s%d.%02d", sign, cents/100, cents%100)
+}
+
 func Iterate(count int) []int {
 	var items []int
 	for i := 0; i < count; i++ {
@@ -2,8 +2,10 @@ package dbrepo
 
 import (
 	"database/sql"
+	"sync"
 
 	"github.com/NhanNT-VNG/hotel-booking/internal/config"
+	"github.com/NhanNT-VNG/hotel-booking/internal/models"
 	"github.com/NhanNT-VNG/hotel-booking/internal/repository"
 )
 
@@ -12,9 +14,44 @@ type postgresDBRepo struct {
 	DB  *sql.DB
 }
 
+type testDBRepo struct {
+	App *config.AppConfig
+
+	mu               sync.Mutex
+	users            []models.User
+	roomTypes        []models.RoomType
+	rooms            []models.Room
+	restrictions     []models.Restriction
+	reservations     []models.Reservation
+	roomRestrictions []models.RoomRestriction
+	statusChanges    []models.ReservationStatusChange
+	policies         []models.CancellationPolicy
+	auditEvents      []models.AuditEvent
+	roomRates        []models.RoomRate
+	stayRules        []models.StayRule
+	promoCodes       []models.PromoCode
+	taxFees          []models.TaxFee
+	charges          []models.ReservationCharge
+	payments         []models.Payment
+	refunds          []models.Refund
+	ledger           []models.LedgerEntry
+	invoices         []models.Invoice
+	outbox           []models.OutboxMail
+	lastIds          map[string]int
+}
+
 func NewPostgresRepo(conn *sql.DB, app *config.AppConfig) repository.DatabaseRepo {
 	return &postgresDBRepo{
 		App: app,
 		DB:  conn,
 	}
 }
+
+func NewTestingRepo(app *config.AppConfig) repository.DatabaseRepo {
+	repo := &testDBRepo{
+		App:     app,
+		lastIds: make(map[string]int),
+	}
+	repo.seed()
+	return repo
+}
@@ -0,0 +1,91 @@
+package dbrepo
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"github.com/NhanNT-VNG/hotel-booking/internal/models"
+)
+
+func (m *postgresDBRepo) InsertAuditEvent(ctx context.Context, event models.AuditEvent) error {
+	ctx, cancel := context.WithTimeout(ctx, m.timeout())
+	defer cancel()
+
+	changes, err := json.Marshal(event.Changes)
+	if err != nil {
+		return err
+	}
+
+	var userId sql.NullInt64
+	if event.UserId > 0 {
+		userId = sql.NullInt64{Int64: int64(event.UserId), Valid: true}
+	}
+
+	query := `insert into audit_events (user_id, action, entity_type, entity_id, changes, created_at, updated_at)
+		values ($1, $2, $3, $4, $5, $6, $7)`
+
+	_, err = m.DB.ExecContext(ctx, query,
+		userId,
+		event.Action,
+		event.EntityType,
+		event.EntityId,
+		string(changes),
+		time.Now(),
+		time.Now(),
+	)
+	return err
+}
+
+func (m *postgresDBRepo) AuditEventsByEntity(ctx context.Context, entityType string, entityId int) ([]models.AuditEvent, error) {
+	ctx, cancel := context.WithTimeout(ctx, m.timeout())
+	defer cancel()
+
+	var events []models.AuditEvent
+
+	query := `
+		select a.id, coalesce(a.user_id, 0), a.action, a.entity_type, a.entity_id, a.changes, a.created_at,
+		coalesce(u.first_name, ''), coalesce(u.last_name, ''), coalesce(u.email, '')
+		from audit_events a
+		left join users u on u.id = a.user_id
+		where a.entity_type = $1 and a.entity_id = $2
+		order by a.created_at desc, a.id desc
+	`
+	rows, err := m.DB.QueryContext(ctx, query, entityType, entityId)
+	if err != nil {
+		return events, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var event models.AuditEvent
+		var changes []byte
+		err := rows.Scan(
+			&event.ID,
+			&event.UserId,
+			&event.Action,
+			&event.EntityType,
+			&event.EntityId,
+			&changes,
+			&event.CreatedAt,
+			&event.User.FirstName,
+			&event.User.LastName,
+			&event.User.Email,
+		)
+		if err != nil {
+			return events, err
+		}
+
+		if err = json.Unmarshal(changes, &event.Changes); err != nil {
+			return events, err
+		}
+		event.User.ID = event.UserId
+		events = append(events, event)
+	}
+
+	if err = rows.Err(); err != nil {
+		return events, err
+	}
+	return events, nil
+}
@@ -0,0 +1,63 @@
+package dbrepo
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/NhanNT-VNG/hotel-booking/internal/models"
+)
+
+// IssueInvoice returns the invoice of a booking, numbering a new one when the
+// booking has none yet. The table is locked so numbers have no gaps.
+func (m *postgresDBRepo) IssueInvoice(ctx context.Context, reservationId int) (models.Invoice, error) {
+	ctx, cancel := context.WithTimeout(ctx, m.timeout())
+	defer cancel()
+
+	invoice := models.Invoice{ReservationId: reservationId}
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return invoice, err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, `lock table invoices in exclusive mode`)
+	if err != nil {
+		return invoice, err
+	}
+
+	err = tx.QueryRowContext(ctx,
+		`select id, number, issued_at from invoices where reservation_id = $1`,
+		reservationId,
+	).Scan(&invoice.ID, &invoice.Number, &invoice.IssuedAt)
+	if err == nil {
+		return invoice, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return invoice, err
+	}
+
+	invoice.IssuedAt = time.Now()
+
+	query := `
+		insert into invoices (number, reservation_id, issued_at, created_at, updated_at)
+		select coalesce(max(number), 0) + 1, $1, $2, $3, $4 from invoices
+		returning id, number
+	`
+	err = tx.QueryRowContext(ctx, query,
+		reservationId,
+		invoice.IssuedAt,
+		time.Now(),
+		time.Now(),
+	).Scan(&invoice.ID, &invoice.Number)
+	if err != nil {
+		return invoice, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return invoice, err
+	}
+	return invoice, nil
+}
@@ -0,0 +1,280 @@
+package dbrepo
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/NhanNT-VNG/hotel-booking/internal/models"
+	"github.com/NhanNT-VNG/hotel-booking/internal/repository"
+)
+
+// nullId stores a zero id as NULL.
+func nullId(id int) interface{} {
+	if id == 0 {
+		return nil
+	}
+	return id
+}
+
+func (m *postgresDBRepo) ReservationRefunds(ctx context.Context, reservationId int) ([]models.Refund, error) {
+	ctx, cancel := context.WithTimeout(ctx, m.timeout())
+	defer cancel()
+
+	var refunds []models.Refund
+
+	query := `
+		select id, reservation_id, payment_id, amount, reason, provider_ref, status,
+			coalesce(user_id, 0), created_at, updated_at
+		from refunds
+		where reservation_id = $1
+		order by id
+	`
+	rows, err := m.DB.QueryContext(ctx, query, reservationId)
+	if err != nil {
+		return refunds, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var refund models.Refund
+		err := rows.Scan(
+			&refund.ID,
+			&refund.ReservationId,
+			&refund.PaymentId,
+			&refund.Amount,
+			&refund.Reason,
+			&refund.ProviderRef,
+			&refund.Status,
+			&refund.UserId,
+			&refund.CreatedAt,
+			&refund.UpdatedAt,
+		)
+		if err != nil {
+			return refunds, err
+		}
+		refunds = append(refunds, refund)
+	}
+
+	if err = rows.Err(); err != nil {
+		return refunds, err
+	}
+	return refunds, nil
+}
+
+// InsertPendingRefunds records refunds before the gateway is asked to pay
+// them. The booking is locked and refunded must still be what was refunded
+// of it, so two refunds made at the same time cannot both be paid.
+func (m *postgresDBRepo) InsertPendingRefunds(ctx context.Context, reservationId, refunded int, refunds []models.Refund) ([]models.Refund, error) {
+	ctx, cancel := context.WithTimeout(ctx, m.timeout())
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, `select id from reservations where id = $1 for update`, reservationId)
+	if err != nil {
+		return nil, err
+	}
+
+	var current int
+	err = tx.QueryRowContext(ctx, `
+		select coalesce(sum(amount), 0) from refunds
+		where reservation_id = $1 and status <> $2`,
+		reservationId,
+		models.RefundFailed,
+	).Scan(&current)
+	if err != nil {
+		return nil, err
+	}
+	if current != refunded {
+		return nil, repository.ErrRefundsChanged
+	}
+
+	query := `insert into refunds(
+		reservation_id, payment_id, amount, reason, provider_ref, status, user_id, created_at, updated_at)
+		values ($1, $2, $3, $4, $5, $6, $7, $8, $9) returning id`
+
+	for i := range refunds {
+		refunds[i].ReservationId = reservationId
+		refunds[i].Status = models.RefundPending
+		refunds[i].CreatedAt = time.Now()
+		refunds[i].UpdatedAt = time.Now()
+
+		err = tx.QueryRowContext(ctx, query,
+			reservationId,
+			refunds[i].PaymentId,
+			refunds[i].Amount,
+			refunds[i].Reason,
+			refunds[i].ProviderRef,
+			refunds[i].Status,
+			nullId(refunds[i].UserId),
+			refunds[i].CreatedAt,
+			refunds[i].UpdatedAt,
+		).Scan(&refunds[i].ID)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, err
+	}
+	return refunds, nil
+}
+
+// CompleteRefund marks a pending refund as paid, writes its ledger entry, and
+// marks the payment as refunded once all of it has been paid back.
+func (m *postgresDBRepo) CompleteRefund(ctx context.Context, refund models.Refund, description string) error {
+	ctx, cancel := context.WithTimeout(ctx, m.timeout())
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, `
+		update refunds set status = $1, provider_ref = $2, updated_at = $3
+		where id = $4 and status = $5`,
+		models.RefundSucceeded,
+		refund.ProviderRef,
+		time.Now(),
+		refund.ID,
+		models.RefundPending,
+	)
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return sql.ErrNoRows
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		insert into ledger_entries(
+			reservation_id, kind, description, amount, payment_id, refund_id, user_id, created_at, updated_at)
+		values ($1, $2, $3, $4, $5, $6, $7, $8, $9)`,
+		refund.ReservationId,
+		models.LedgerRefund,
+		description,
+		refund.Amount,
+		refund.PaymentId,
+		refund.ID,
+		nullId(refund.UserId),
+		time.Now(),
+		time.Now(),
+	)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		update payments set status = $1, updated_at = $2
+		where id = $3 and amount <= (
+			select coalesce(sum(amount), 0) from refunds where payment_id = $3 and status = $4)`,
+		models.PaymentRefunded,
+		time.Now(),
+		refund.PaymentId,
+		models.RefundSucceeded,
+	)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// FailRefund marks a pending refund the gateway refused, so it no longer
+// counts against what can be refunded.
+func (m *postgresDBRepo) FailRefund(ctx context.Context, id int) error {
+	ctx, cancel := context.WithTimeout(ctx, m.timeout())
+	defer cancel()
+
+	_, err := m.DB.ExecContext(ctx, `
+		update refunds set status = $1, updated_at = $2
+		where id = $3 and status = $4`,
+		models.RefundFailed,
+		time.Now(),
+		id,
+		models.RefundPending,
+	)
+	return err
+}
+
+func (m *postgresDBRepo) LedgerEntries(ctx context.Context, reservationId int) ([]models.LedgerEntry, error) {
+	ctx, cancel := context.WithTimeout(ctx, m.timeout())
+	defer cancel()
+
+	var entries []models.LedgerEntry
+
+	query := `
+		select id, reservation_id, kind, description, amount, coalesce(payment_id, 0),
+			coalesce(refund_id, 0), coalesce(user_id, 0), created_at
+		from ledger_entries
+		where reservation_id = $1
+		order by id
+	`
+	rows, err := m.DB.QueryContext(ctx, query, reservationId)
+	if err != nil {
+		return entries, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var entry models.LedgerEntry
+		err := rows.Scan(
+			&entry.ID,
+			&entry.ReservationId,
+			&entry.Kind,
+			&entry.Description,
+			&entry.Amount,
+			&entry.PaymentId,
+			&entry.RefundId,
+			&entry.UserId,
+			&entry.CreatedAt,
+		)
+		if err != nil {
+			return entries, err
+		}
+		entries = append(entries, entry)
+	}
+
+	if err = rows.Err(); err != nil {
+		return entries, err
+	}
+	return entries, nil
+}
+
+func (m *postgresDBRepo) InsertLedgerEntry(ctx context.Context, entry models.LedgerEntry) (int, error) {
+	ctx, cancel := context.WithTimeout(ctx, m.timeout())
+	defer cancel()
+
+	query := `insert into ledger_entries(
+		reservation_id, kind, description, amount, payment_id, refund_id, user_id, created_at, updated_at)
+		values ($1, $2, $3, $4, $5, $6, $7, $8, $9) returning id`
+
+	var id int
+
+	err := m.DB.QueryRowContext(ctx, query,
+		entry.ReservationId,
+		entry.Kind,
+		entry.Description,
+		entry.Amount,
+		nullId(entry.PaymentId),
+		nullId(entry.RefundId),
+		nullId(entry.UserId),
+		time.Now(),
+		time.Now(),
+	).Scan(&id)
+
+	if err != nil {
+		return 0, err
+	}
+	return id, nil
+}
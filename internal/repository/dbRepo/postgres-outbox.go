@@ -0,0 +1,186 @@
+package dbrepo
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"github.com/NhanNT-VNG/hotel-booking/internal/models"
+)
+
+const outboxColumns = `
+	id, to_address, from_address, subject, content, template, attachments,
+	status, attempts, last_error, next_attempt_at, sent_at, created_at, updated_at`
+
+func scanOutboxMail(row rowScanner, mail *models.OutboxMail) error {
+	var attachments []byte
+	var sentAt sql.NullTime
+
+	err := row.Scan(
+		&mail.ID,
+		&mail.Mail.To,
+		&mail.Mail.From,
+		&mail.Mail.Subject,
+		&mail.Mail.Content,
+		&mail.Mail.Template,
+		&attachments,
+		&mail.Status,
+		&mail.Attempts,
+		&mail.LastError,
+		&mail.NextAttemptAt,
+		&sentAt,
+		&mail.CreatedAt,
+		&mail.UpdatedAt,
+	)
+	if err != nil {
+		return err
+	}
+
+	mail.SentAt = sentAt.Time
+	return json.Unmarshal(attachments, &mail.Mail.Attachments)
+}
+
+// InsertMail adds a mail to the outbox to be sent right away.
+func (m *postgresDBRepo) InsertMail(ctx context.Context, mail models.MailData) (int, error) {
+	ctx, cancel := context.WithTimeout(ctx, m.timeout())
+	defer cancel()
+
+	attachments := mail.Attachments
+	if attachments == nil {
+		attachments = []models.MailAttachment{}
+	}
+	encoded, err := json.Marshal(attachments)
+	if err != nil {
+		return 0, err
+	}
+
+	query := `insert into mail_outbox(
+		to_address, from_address, subject, content, template, attachments,
+		status, next_attempt_at, created_at, updated_at)
+		values ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10) returning id`
+
+	var id int
+
+	err = m.DB.QueryRowContext(ctx, query,
+		mail.To,
+		mail.From,
+		mail.Subject,
+		mail.Content,
+		mail.Template,
+		encoded,
+		models.MailPending,
+		time.Now(),
+		time.Now(),
+		time.Now(),
+	).Scan(&id)
+
+	if err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+// ClaimMail takes the next pending mail that is due and counts the attempt.
+// The mail is not due again until the lease is over, so it is picked up
+// again if the worker sending it dies. It returns sql.ErrNoRows when no mail
+// is due.
+func (m *postgresDBRepo) ClaimMail(ctx context.Context, lease time.Duration) (models.OutboxMail, error) {
+	ctx, cancel := context.WithTimeout(ctx, m.timeout())
+	defer cancel()
+
+	var mail models.OutboxMail
+
+	query := `
+		update mail_outbox set attempts = attempts + 1, next_attempt_at = $1, updated_at = $2
+		where id = (
+			select id from mail_outbox
+			where status = $3 and next_attempt_at <= $2
+			order by next_attempt_at, id
+			limit 1
+			for update skip locked
+		)
+		returning ` + outboxColumns
+
+	now := time.Now()
+	err := scanOutboxMail(m.DB.QueryRowContext(ctx, query, now.Add(lease), now, models.MailPending), &mail)
+	if err != nil {
+		return mail, err
+	}
+	return mail, nil
+}
+
+func (m *postgresDBRepo) UpdateOutboxMail(ctx context.Context, mail models.OutboxMail) error {
+	ctx, cancel := context.WithTimeout(ctx, m.timeout())
+	defer cancel()
+
+	query := `update mail_outbox set
+		status = $1, attempts = $2, last_error = $3, next_attempt_at = $4, sent_at = $5, updated_at = $6
+		where id = $7`
+
+	_, err := m.DB.ExecContext(ctx, query,
+		mail.Status,
+		mail.Attempts,
+		mail.LastError,
+		mail.NextAttemptAt,
+		nullDate(mail.SentAt),
+		time.Now(),
+		mail.ID,
+	)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (m *postgresDBRepo) OutboxMailByStatus(ctx context.Context, status string) ([]models.OutboxMail, error) {
+	ctx, cancel := context.WithTimeout(ctx, m.timeout())
+	defer cancel()
+
+	var mails []models.OutboxMail
+
+	query := `select ` + outboxColumns + ` from mail_outbox where status = $1 order by updated_at desc, id desc`
+	rows, err := m.DB.QueryContext(ctx, query, status)
+	if err != nil {
+		return mails, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var mail models.OutboxMail
+		if err := scanOutboxMail(rows, &mail); err != nil {
+			return mails, err
+		}
+		mails = append(mails, mail)
+	}
+
+	if err = rows.Err(); err != nil {
+		return mails, err
+	}
+	return mails, nil
+}
+
+// ResendMail puts a failed mail back in the outbox with a fresh set of
+// attempts. It returns sql.ErrNoRows when the mail has not failed.
+func (m *postgresDBRepo) ResendMail(ctx context.Context, id int) error {
+	ctx, cancel := context.WithTimeout(ctx, m.timeout())
+	defer cancel()
+
+	query := `update mail_outbox set status = $1, attempts = 0, next_attempt_at = $2, updated_at = $2
+		where id = $3 and status = $4`
+
+	result, err := m.DB.ExecContext(ctx, query, models.MailPending, time.Now(), id, models.MailFailed)
+	if err != nil {
+		return err
+	}
+
+	updated, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if updated == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
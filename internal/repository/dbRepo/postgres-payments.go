@@ -0,0 +1,283 @@
+package dbrepo
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/NhanNT-VNG/hotel-booking/internal/models"
+	"github.com/NhanNT-VNG/hotel-booking/internal/repository"
+	"github.com/jackc/pgconn"
+)
+
+const paymentColumns = `
+	id, reservation_id, provider, provider_ref, kind, status, amount, message, created_at, updated_at`
+
+func scanPayment(row rowScanner, payment *models.Payment) error {
+	return row.Scan(
+		&payment.ID,
+		&payment.ReservationId,
+		&payment.Provider,
+		&payment.ProviderRef,
+		&payment.Kind,
+		&payment.Status,
+		&payment.Amount,
+		&payment.Message,
+		&payment.CreatedAt,
+		&payment.UpdatedAt,
+	)
+}
+
+func translatePaymentError(err error) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23505" && pgErr.ConstraintName == "payments_active_reservation_idx" {
+		return repository.ErrPaymentInProgress
+	}
+	return err
+}
+
+func (m *postgresDBRepo) ReservationPayments(ctx context.Context, reservationId int) ([]models.Payment, error) {
+	ctx, cancel := context.WithTimeout(ctx, m.timeout())
+	defer cancel()
+
+	var payments []models.Payment
+
+	query := `select ` + paymentColumns + ` from payments where reservation_id = $1 order by id`
+	rows, err := m.DB.QueryContext(ctx, query, reservationId)
+	if err != nil {
+		return payments, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var payment models.Payment
+		if err := scanPayment(rows, &payment); err != nil {
+			return payments, err
+		}
+		payments = append(payments, payment)
+	}
+
+	if err = rows.Err(); err != nil {
+		return payments, err
+	}
+	return payments, nil
+}
+
+func (m *postgresDBRepo) GetPaymentByProviderRef(ctx context.Context, provider, ref string) (models.Payment, error) {
+	ctx, cancel := context.WithTimeout(ctx, m.timeout())
+	defer cancel()
+
+	var payment models.Payment
+
+	query := `select ` + paymentColumns + ` from payments where provider = $1 and provider_ref = $2`
+	err := scanPayment(m.DB.QueryRowContext(ctx, query, provider, ref), &payment)
+	if err != nil {
+		return payment, err
+	}
+	return payment, nil
+}
+
+// InsertPayment returns repository.ErrPaymentInProgress when the booking
+// already has a payment that is processing, authorized or captured, and
+// repository.ErrReservationNotPending when it is no longer waiting for
+// payment. The booking is locked, so it cannot be cancelled for being unpaid
+// while the payment is recorded.
+func (m *postgresDBRepo) InsertPayment(ctx context.Context, payment models.Payment) (int, error) {
+	ctx, cancel := context.WithTimeout(ctx, m.timeout())
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	var status string
+	err = tx.QueryRowContext(ctx,
+		`select status from reservations where id = $1 and deleted_at is null for update`,
+		payment.ReservationId).Scan(&status)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, repository.ErrReservationNotPending
+	}
+	if err != nil {
+		return 0, err
+	}
+	if status != models.StatusPending {
+		return 0, repository.ErrReservationNotPending
+	}
+
+	query := `insert into payments(
+		reservation_id, provider, provider_ref, kind, status, amount, message, created_at, updated_at)
+		values ($1, $2, $3, $4, $5, $6, $7, $8, $9) returning id`
+
+	var id int
+
+	err = tx.QueryRowContext(ctx, query,
+		payment.ReservationId,
+		payment.Provider,
+		payment.ProviderRef,
+		payment.Kind,
+		payment.Status,
+		payment.Amount,
+		payment.Message,
+		time.Now(),
+		time.Now(),
+	).Scan(&id)
+
+	if err != nil {
+		return 0, translatePaymentError(err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return 0, translatePaymentError(err)
+	}
+	return id, nil
+}
+
+func (m *postgresDBRepo) UpdatePaymentStatus(ctx context.Context, id int, status, message string) error {
+	ctx, cancel := context.WithTimeout(ctx, m.timeout())
+	defer cancel()
+
+	query := `update payments set status = $1, message = $2, updated_at = $3 where id = $4`
+	_, err := m.DB.ExecContext(ctx, query, status, message, time.Now(), id)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (m *postgresDBRepo) AuthorizePayment(ctx context.Context, id int, providerRef string) error {
+	ctx, cancel := context.WithTimeout(ctx, m.timeout())
+	defer cancel()
+
+	query := `update payments set status = $1, provider_ref = $2, updated_at = $3 where id = $4`
+	_, err := m.DB.ExecContext(ctx, query, models.PaymentAuthorized, providerRef, time.Now(), id)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// UnpaidReservations returns the bookings made before the given time that are
+// still waiting for payment and have no payment under way.
+func (m *postgresDBRepo) UnpaidReservations(ctx context.Context, createdBefore time.Time) ([]models.Reservation, error) {
+	ctx, cancel := context.WithTimeout(ctx, m.timeout())
+	defer cancel()
+
+	var reservationList []models.Reservation
+
+	query := `
+		select ` + reservationColumns + `
+		from reservations r
+		left join rooms rm on rm.id = r.room_id
+		left join room_types rt on rt.id = r.room_type_id
+		where r.deleted_at is null and r.parent_id is null and r.status = $1 and r.created_at < $2
+		and not exists (
+			select 1 from payments p where p.reservation_id = r.id and p.status in ($3, $4, $5)
+		)
+		order by r.created_at
+	`
+	rows, err := m.DB.QueryContext(ctx, query,
+		models.StatusPending,
+		createdBefore,
+		models.PaymentProcessing,
+		models.PaymentAuthorized,
+		models.PaymentCaptured,
+	)
+	if err != nil {
+		return reservationList, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var reservation models.Reservation
+		if err := scanReservation(rows, &reservation); err != nil {
+			return reservationList, err
+		}
+		reservationList = append(reservationList, reservation)
+	}
+
+	if err = rows.Err(); err != nil {
+		return reservationList, err
+	}
+	return reservationList, nil
+}
+
+// CancelUnpaidReservation cancels a booking that is still waiting for
+// payment, together with its other rooms, in one transaction. The booking is
+// locked and checked again first, so a payment that started since it was
+// found unpaid keeps it. It reports whether the booking was cancelled.
+func (m *postgresDBRepo) CancelUnpaidReservation(ctx context.Context, id int) (bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, m.timeout())
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	var status string
+	err = tx.QueryRowContext(ctx,
+		`select status from reservations where id = $1 and parent_id is null and deleted_at is null for update`,
+		id).Scan(&status)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if status != models.StatusPending {
+		return false, nil
+	}
+
+	var paying bool
+	err = tx.QueryRowContext(ctx, `
+		select exists (select 1 from payments where reservation_id = $1 and status in ($2, $3, $4))`,
+		id,
+		models.PaymentProcessing,
+		models.PaymentAuthorized,
+		models.PaymentCaptured,
+	).Scan(&paying)
+	if err != nil || paying {
+		return false, err
+	}
+
+	rows, err := tx.QueryContext(ctx, `
+		select id from reservations
+		where parent_id = $1 and deleted_at is null and status = $2
+		order by id`,
+		id, models.StatusPending)
+	if err != nil {
+		return false, err
+	}
+
+	var lineIds []int
+	for rows.Next() {
+		var lineId int
+		if err := rows.Scan(&lineId); err != nil {
+			rows.Close()
+			return false, err
+		}
+		lineIds = append(lineIds, lineId)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return false, err
+	}
+
+	// the first room goes last, so the promo code use is given back once
+	for _, roomId := range append(lineIds, id) {
+		if err = m.cancelTx(ctx, tx, roomId, 0); err != nil {
+			return false, err
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return false, err
+	}
+	return true, nil
+}
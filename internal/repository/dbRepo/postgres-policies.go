@@ -0,0 +1,180 @@
+package dbrepo
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/NhanNT-VNG/hotel-booking/internal/models"
+)
+
+func (m *postgresDBRepo) AllCancellationPolicies(ctx context.Context) ([]models.CancellationPolicy, error) {
+	ctx, cancel := context.WithTimeout(ctx, m.timeout())
+	defer cancel()
+
+	var policies []models.CancellationPolicy
+
+	query := `
+		select id, name, free_cancellation_days, penalty_type, penalty_percent, created_at, updated_at
+		from cancellation_policies
+		order by name
+	`
+	rows, err := m.DB.QueryContext(ctx, query)
+	if err != nil {
+		return policies, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var policy models.CancellationPolicy
+		err := rows.Scan(
+			&policy.ID,
+			&policy.Name,
+			&policy.FreeCancellationDays,
+			&policy.PenaltyType,
+			&policy.PenaltyPercent,
+			&policy.CreatedAt,
+			&policy.UpdatedAt,
+		)
+
+		if err != nil {
+			return policies, err
+		}
+		policies = append(policies, policy)
+	}
+
+	if err = rows.Err(); err != nil {
+		return policies, err
+	}
+	return policies, nil
+}
+
+func (m *postgresDBRepo) GetCancellationPolicyById(ctx context.Context, id int) (models.CancellationPolicy, error) {
+	ctx, cancel := context.WithTimeout(ctx, m.timeout())
+	defer cancel()
+
+	var policy models.CancellationPolicy
+
+	query := `
+		select id, name, free_cancellation_days, penalty_type, penalty_percent, created_at, updated_at
+		from cancellation_policies
+		where id = $1
+	`
+	err := m.DB.QueryRowContext(ctx, query, id).Scan(
+		&policy.ID,
+		&policy.Name,
+		&policy.FreeCancellationDays,
+		&policy.PenaltyType,
+		&policy.PenaltyPercent,
+		&policy.CreatedAt,
+		&policy.UpdatedAt,
+	)
+
+	if err != nil {
+		return policy, err
+	}
+	return policy, nil
+}
+
+func (m *postgresDBRepo) GetCancellationPolicyByRoomTypeId(ctx context.Context, roomTypeId int) (models.CancellationPolicy, error) {
+	ctx, cancel := context.WithTimeout(ctx, m.timeout())
+	defer cancel()
+
+	var policy models.CancellationPolicy
+
+	query := `
+		select
+			cp.id, cp.name, cp.free_cancellation_days, cp.penalty_type,
+			cp.penalty_percent, cp.created_at, cp.updated_at
+		from room_types rt
+		join cancellation_policies cp on cp.id = rt.cancellation_policy_id
+		where rt.id = $1
+	`
+	err := m.DB.QueryRowContext(ctx, query, roomTypeId).Scan(
+		&policy.ID,
+		&policy.Name,
+		&policy.FreeCancellationDays,
+		&policy.PenaltyType,
+		&policy.PenaltyPercent,
+		&policy.CreatedAt,
+		&policy.UpdatedAt,
+	)
+
+	if err != nil {
+		return policy, err
+	}
+	return policy, nil
+}
+
+func (m *postgresDBRepo) InsertCancellationPolicy(ctx context.Context, policy models.CancellationPolicy) (int, error) {
+	ctx, cancel := context.WithTimeout(ctx, m.timeout())
+	defer cancel()
+
+	query := `insert into cancellation_policies(
+		name, free_cancellation_days, penalty_type, penalty_percent, created_at, updated_at)
+		values ($1, $2, $3, $4, $5, $6) returning id`
+
+	var id int
+
+	err := m.DB.QueryRowContext(ctx, query,
+		policy.Name,
+		policy.FreeCancellationDays,
+		policy.PenaltyType,
+		policy.PenaltyPercent,
+		time.Now(),
+		time.Now(),
+	).Scan(&id)
+
+	if err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+func (m *postgresDBRepo) UpdateCancellationPolicy(ctx context.Context, policy models.CancellationPolicy) error {
+	ctx, cancel := context.WithTimeout(ctx, m.timeout())
+	defer cancel()
+
+	query := `
+		update cancellation_policies
+		set
+			name = $1,
+			free_cancellation_days = $2,
+			penalty_type = $3,
+			penalty_percent = $4,
+			updated_at = $5
+		where id = $6`
+
+	_, err := m.DB.ExecContext(ctx, query,
+		policy.Name,
+		policy.FreeCancellationDays,
+		policy.PenaltyType,
+		policy.PenaltyPercent,
+		time.Now(),
+		policy.ID,
+	)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (m *postgresDBRepo) UpdateRoomTypeCancellationPolicy(ctx context.Context, roomTypeId, policyId int) error {
+	ctx, cancel := context.WithTimeout(ctx, m.timeout())
+	defer cancel()
+
+	var id sql.NullInt64
+	if policyId > 0 {
+		id = sql.NullInt64{Int64: int64(policyId), Valid: true}
+	}
+
+	_, err := m.DB.ExecContext(ctx,
+		`update room_types set cancellation_policy_id = $1, updated_at = $2 where id = $3`,
+		id, time.Now(), roomTypeId)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
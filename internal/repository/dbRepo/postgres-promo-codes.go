@@ -0,0 +1,217 @@
+package dbrepo
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/NhanNT-VNG/hotel-booking/internal/models"
+	"github.com/NhanNT-VNG/hotel-booking/internal/repository"
+	"github.com/jackc/pgconn"
+)
+
+const promoCodeColumns = `
+	id, code, name, discount_type, discount_value, stay_start, stay_end, book_start, book_end,
+	min_nights, max_uses, uses, room_type_ids, active, created_at, updated_at`
+
+func scanPromoCode(row rowScanner, promo *models.PromoCode) error {
+	var stayStart, stayEnd, bookStart, bookEnd sql.NullTime
+	var roomTypeIds []byte
+
+	err := row.Scan(
+		&promo.ID,
+		&promo.Code,
+		&promo.Name,
+		&promo.DiscountType,
+		&promo.DiscountValue,
+		&stayStart,
+		&stayEnd,
+		&bookStart,
+		&bookEnd,
+		&promo.MinNights,
+		&promo.MaxUses,
+		&promo.Uses,
+		&roomTypeIds,
+		&promo.Active,
+		&promo.CreatedAt,
+		&promo.UpdatedAt,
+	)
+	if err != nil {
+		return err
+	}
+
+	promo.StayStart = stayStart.Time
+	promo.StayEnd = stayEnd.Time
+	promo.BookStart = bookStart.Time
+	promo.BookEnd = bookEnd.Time
+	return json.Unmarshal(roomTypeIds, &promo.RoomTypeIds)
+}
+
+// nullDate stores a zero time as NULL.
+func nullDate(t time.Time) interface{} {
+	if t.IsZero() {
+		return nil
+	}
+	return t
+}
+
+func jsonIds(ids []int) ([]byte, error) {
+	if ids == nil {
+		ids = []int{}
+	}
+	return json.Marshal(ids)
+}
+
+func translatePromoCodeError(err error) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23505" && pgErr.ConstraintName == "promo_codes_code_idx" {
+		return repository.ErrDuplicatePromoCode
+	}
+	return err
+}
+
+func (m *postgresDBRepo) AllPromoCodes(ctx context.Context) ([]models.PromoCode, error) {
+	ctx, cancel := context.WithTimeout(ctx, m.timeout())
+	defer cancel()
+
+	var promos []models.PromoCode
+
+	query := `select ` + promoCodeColumns + ` from promo_codes order by code`
+	rows, err := m.DB.QueryContext(ctx, query)
+	if err != nil {
+		return promos, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var promo models.PromoCode
+		if err := scanPromoCode(rows, &promo); err != nil {
+			return promos, err
+		}
+		promos = append(promos, promo)
+	}
+
+	if err = rows.Err(); err != nil {
+		return promos, err
+	}
+	return promos, nil
+}
+
+func (m *postgresDBRepo) GetPromoCodeById(ctx context.Context, id int) (models.PromoCode, error) {
+	ctx, cancel := context.WithTimeout(ctx, m.timeout())
+	defer cancel()
+
+	var promo models.PromoCode
+
+	query := `select ` + promoCodeColumns + ` from promo_codes where id = $1`
+	err := scanPromoCode(m.DB.QueryRowContext(ctx, query, id), &promo)
+	if err != nil {
+		return promo, err
+	}
+	return promo, nil
+}
+
+func (m *postgresDBRepo) GetPromoCodeByCode(ctx context.Context, code string) (models.PromoCode, error) {
+	ctx, cancel := context.WithTimeout(ctx, m.timeout())
+	defer cancel()
+
+	var promo models.PromoCode
+
+	query := `select ` + promoCodeColumns + ` from promo_codes where code = $1`
+	err := scanPromoCode(m.DB.QueryRowContext(ctx, query, models.NormalizePromoCode(code)), &promo)
+	if err != nil {
+		return promo, err
+	}
+	return promo, nil
+}
+
+func (m *postgresDBRepo) InsertPromoCode(ctx context.Context, promo models.PromoCode) (int, error) {
+	ctx, cancel := context.WithTimeout(ctx, m.timeout())
+	defer cancel()
+
+	roomTypeIds, err := jsonIds(promo.RoomTypeIds)
+	if err != nil {
+		return 0, err
+	}
+
+	query := `insert into promo_codes(
+		code, name, discount_type, discount_value, stay_start, stay_end, book_start, book_end,
+		min_nights, max_uses, room_type_ids, active, created_at, updated_at)
+		values ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14) returning id`
+
+	var id int
+
+	err = m.DB.QueryRowContext(ctx, query,
+		promo.Code,
+		promo.Name,
+		promo.DiscountType,
+		promo.DiscountValue,
+		nullDate(promo.StayStart),
+		nullDate(promo.StayEnd),
+		nullDate(promo.BookStart),
+		nullDate(promo.BookEnd),
+		promo.MinNights,
+		promo.MaxUses,
+		roomTypeIds,
+		promo.Active,
+		time.Now(),
+		time.Now(),
+	).Scan(&id)
+
+	if err != nil {
+		return 0, translatePromoCodeError(err)
+	}
+	return id, nil
+}
+
+// UpdatePromoCode saves the settings of a code. Its usage count is only
+// changed when reservations are created.
+func (m *postgresDBRepo) UpdatePromoCode(ctx context.Context, promo models.PromoCode) error {
+	ctx, cancel := context.WithTimeout(ctx, m.timeout())
+	defer cancel()
+
+	roomTypeIds, err := jsonIds(promo.RoomTypeIds)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		update promo_codes
+		set code = $1, name = $2, discount_type = $3, discount_value = $4, stay_start = $5,
+			stay_end = $6, book_start = $7, book_end = $8, min_nights = $9, max_uses = $10,
+			room_type_ids = $11, active = $12, updated_at = $13
+		where id = $14
+	`
+	_, err = m.DB.ExecContext(ctx, query,
+		promo.Code,
+		promo.Name,
+		promo.DiscountType,
+		promo.DiscountValue,
+		nullDate(promo.StayStart),
+		nullDate(promo.StayEnd),
+		nullDate(promo.BookStart),
+		nullDate(promo.BookEnd),
+		promo.MinNights,
+		promo.MaxUses,
+		roomTypeIds,
+		promo.Active,
+		time.Now(),
+		promo.ID,
+	)
+
+	return translatePromoCodeError(err)
+}
+
+func (m *postgresDBRepo) DeletePromoCode(ctx context.Context, id int) error {
+	ctx, cancel := context.WithTimeout(ctx, m.timeout())
+	defer cancel()
+
+	_, err := m.DB.ExecContext(ctx, `delete from promo_codes where id = $1`, id)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,150 @@
+package dbrepo
+
+import (
+	"context"
+	"time"
+
+	"github.com/NhanNT-VNG/hotel-booking/internal/models"
+)
+
+const roomRateColumns = `
+	rr.id, rr.room_type_id, rr.name, rr.start_date, rr.end_date, rr.weekdays, rr.rate,
+	rr.created_at, rr.updated_at, rt.id, rt.name`
+
+func scanRoomRate(row rowScanner, rate *models.RoomRate) error {
+	return row.Scan(
+		&rate.ID,
+		&rate.RoomTypeId,
+		&rate.Name,
+		&rate.StartDate,
+		&rate.EndDate,
+		&rate.Weekdays,
+		&rate.Rate,
+		&rate.CreatedAt,
+		&rate.UpdatedAt,
+		&rate.RoomType.ID,
+		&rate.RoomType.Name,
+	)
+}
+
+func (m *postgresDBRepo) queryRoomRates(ctx context.Context, query string, args ...interface{}) ([]models.RoomRate, error) {
+	var rates []models.RoomRate
+
+	rows, err := m.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return rates, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var rate models.RoomRate
+		if err := scanRoomRate(rows, &rate); err != nil {
+			return rates, err
+		}
+		rates = append(rates, rate)
+	}
+
+	if err = rows.Err(); err != nil {
+		return rates, err
+	}
+	return rates, nil
+}
+
+func (m *postgresDBRepo) AllRoomRates(ctx context.Context) ([]models.RoomRate, error) {
+	ctx, cancel := context.WithTimeout(ctx, m.timeout())
+	defer cancel()
+
+	query := `
+		select ` + roomRateColumns + `
+		from room_rates rr
+		left join room_types rt on rt.id = rr.room_type_id
+		order by rt.name, rr.start_date, rr.id
+	`
+	return m.queryRoomRates(ctx, query)
+}
+
+func (m *postgresDBRepo) RoomRatesForPeriod(ctx context.Context, roomTypeId int, startDate, endDate time.Time) ([]models.RoomRate, error) {
+	ctx, cancel := context.WithTimeout(ctx, m.timeout())
+	defer cancel()
+
+	query := `
+		select ` + roomRateColumns + `
+		from room_rates rr
+		left join room_types rt on rt.id = rr.room_type_id
+		where rr.room_type_id = $1 and rr.start_date < $3 and rr.end_date >= $2
+		order by rr.id
+	`
+	return m.queryRoomRates(ctx, query, roomTypeId, startDate, endDate)
+}
+
+func (m *postgresDBRepo) GetRoomRateById(ctx context.Context, id int) (models.RoomRate, error) {
+	ctx, cancel := context.WithTimeout(ctx, m.timeout())
+	defer cancel()
+
+	var rate models.RoomRate
+
+	query := `
+		select ` + roomRateColumns + `
+		from room_rates rr
+		left join room_types rt on rt.id = rr.room_type_id
+		where rr.id = $1
+	`
+	err := scanRoomRate(m.DB.QueryRowContext(ctx, query, id), &rate)
+	if err != nil {
+		return rate, err
+	}
+	return rate, nil
+}
+
+func (m *postgresDBRepo) InsertRoomRate(ctx context.Context, rate models.RoomRate) (int, error) {
+	ctx, cancel := context.WithTimeout(ctx, m.timeout())
+	defer cancel()
+
+	query := `insert into room_rates(
+		room_type_id, name, start_date, end_date, weekdays, rate, created_at, updated_at)
+		values ($1, $2, $3, $4, $5, $6, $7, $8) returning id`
+
+	var id int
+
+	err := m.DB.QueryRowContext(ctx, query,
+		rate.RoomTypeId,
+		rate.Name,
+		rate.StartDate,
+		rate.EndDate,
+		rate.Weekdays,
+		rate.Rate,
+		time.Now(),
+		time.Now(),
+	).Scan(&id)
+
+	if err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+func (m *postgresDBRepo) DeleteRoomRate(ctx context.Context, id int) error {
+	ctx, cancel := context.WithTimeout(ctx, m.timeout())
+	defer cancel()
+
+	_, err := m.DB.ExecContext(ctx, `delete from room_rates where id = $1`, id)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (m *postgresDBRepo) UpdateRoomTypeBaseRate(ctx context.Context, roomTypeId, rate int) error {
+	ctx, cancel := context.WithTimeout(ctx, m.timeout())
+	defer cancel()
+
+	_, err := m.DB.ExecContext(ctx,
+		`update room_types set base_rate = $1, updated_at = $2 where id = $3`,
+		rate, time.Now(), roomTypeId)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
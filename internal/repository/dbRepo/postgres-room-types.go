@@ -0,0 +1,405 @@
+package dbrepo
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/NhanNT-VNG/hotel-booking/internal/models"
+	"github.com/NhanNT-VNG/hotel-booking/internal/repository"
+	"github.com/jackc/pgconn"
+)
+
+const roomTypeColumns = `
+	rt.id, rt.name, rt.slug, rt.description, rt.capacity, rt.max_adults, rt.amenities, rt.photos,
+	rt.base_rate, coalesce(rt.cancellation_policy_id, 0), rt.created_at, rt.updated_at`
+
+func scanRoomType(row rowScanner, roomType *models.RoomType, extra ...interface{}) error {
+	var amenities, photos []byte
+
+	dest := []interface{}{
+		&roomType.ID,
+		&roomType.Name,
+		&roomType.Slug,
+		&roomType.Description,
+		&roomType.Capacity,
+		&roomType.MaxAdults,
+		&amenities,
+		&photos,
+		&roomType.BaseRate,
+		&roomType.CancellationPolicyId,
+		&roomType.CreatedAt,
+		&roomType.UpdatedAt,
+	}
+
+	if err := row.Scan(append(dest, extra...)...); err != nil {
+		return err
+	}
+
+	if err := json.Unmarshal(amenities, &roomType.Amenities); err != nil {
+		return err
+	}
+	return json.Unmarshal(photos, &roomType.Photos)
+}
+
+func (m *postgresDBRepo) AllRoomTypes(ctx context.Context) ([]models.RoomType, error) {
+	ctx, cancel := context.WithTimeout(ctx, m.timeout())
+	defer cancel()
+
+	var roomTypes []models.RoomType
+
+	rows, err := m.DB.QueryContext(ctx, `select `+roomTypeColumns+` from room_types rt order by rt.id`)
+	if err != nil {
+		return roomTypes, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var roomType models.RoomType
+		if err := scanRoomType(rows, &roomType); err != nil {
+			return roomTypes, err
+		}
+		roomTypes = append(roomTypes, roomType)
+	}
+
+	if err = rows.Err(); err != nil {
+		return roomTypes, err
+	}
+	return roomTypes, nil
+}
+
+func (m *postgresDBRepo) GetRoomTypeById(ctx context.Context, id int) (models.RoomType, error) {
+	ctx, cancel := context.WithTimeout(ctx, m.timeout())
+	defer cancel()
+
+	var roomType models.RoomType
+
+	row := m.DB.QueryRowContext(ctx, `select `+roomTypeColumns+` from room_types rt where rt.id = $1`, id)
+	if err := scanRoomType(row, &roomType); err != nil {
+		return roomType, err
+	}
+	return roomType, nil
+}
+
+func (m *postgresDBRepo) GetRoomTypeBySlug(ctx context.Context, slug string) (models.RoomType, error) {
+	ctx, cancel := context.WithTimeout(ctx, m.timeout())
+	defer cancel()
+
+	var roomType models.RoomType
+
+	row := m.DB.QueryRowContext(ctx, `select `+roomTypeColumns+` from room_types rt where rt.slug = $1`, slug)
+	if err := scanRoomType(row, &roomType); err != nil {
+		return roomType, err
+	}
+	return roomType, nil
+}
+
+func jsonList(list []string) ([]byte, error) {
+	if list == nil {
+		list = []string{}
+	}
+	return json.Marshal(list)
+}
+
+func translateSlugError(err error) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23505" && pgErr.ConstraintName == "room_types_slug_idx" {
+		return repository.ErrDuplicateSlug
+	}
+	return err
+}
+
+// InsertRoomType creates a room type together with one physical room of the
+// same name, so that it can be booked straight away.
+func (m *postgresDBRepo) InsertRoomType(ctx context.Context, roomType models.RoomType) (int, error) {
+	ctx, cancel := context.WithTimeout(ctx, m.timeout())
+	defer cancel()
+
+	amenities, err := jsonList(roomType.Amenities)
+	if err != nil {
+		return 0, err
+	}
+	photos, err := jsonList(roomType.Photos)
+	if err != nil {
+		return 0, err
+	}
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	var policyId interface{}
+	if roomType.CancellationPolicyId > 0 {
+		policyId = roomType.CancellationPolicyId
+	}
+
+	query := `insert into room_types(
+		name, slug, description, capacity, max_adults, amenities, photos, base_rate,
+		cancellation_policy_id, created_at, updated_at)
+		values ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11) returning id`
+
+	var id int
+	err = tx.QueryRowContext(ctx, query,
+		roomType.Name,
+		roomType.Slug,
+		roomType.Description,
+		roomType.Capacity,
+		roomType.MaxAdults,
+		amenities,
+		photos,
+		roomType.BaseRate,
+		policyId,
+		time.Now(),
+		time.Now(),
+	).Scan(&id)
+	if err != nil {
+		return 0, translateSlugError(err)
+	}
+
+	_, err = tx.ExecContext(ctx,
+		`insert into rooms(room_name, room_type_id, sort_order, created_at, updated_at)
+		values ($1, $2, (select coalesce(max(sort_order), 0) + 1 from rooms), $3, $4)`,
+		roomType.Name, id, time.Now(), time.Now())
+	if err != nil {
+		return 0, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+func (m *postgresDBRepo) UpdateRoomType(ctx context.Context, roomType models.RoomType) error {
+	ctx, cancel := context.WithTimeout(ctx, m.timeout())
+	defer cancel()
+
+	amenities, err := jsonList(roomType.Amenities)
+	if err != nil {
+		return err
+	}
+	photos, err := jsonList(roomType.Photos)
+	if err != nil {
+		return err
+	}
+
+	query := `update room_types set name = $1, slug = $2, description = $3, capacity = $4,
+		max_adults = $5, amenities = $6, photos = $7, base_rate = $8, updated_at = $9
+		where id = $10`
+
+	_, err = m.DB.ExecContext(ctx, query,
+		roomType.Name,
+		roomType.Slug,
+		roomType.Description,
+		roomType.Capacity,
+		roomType.MaxAdults,
+		amenities,
+		photos,
+		roomType.BaseRate,
+		time.Now(),
+		roomType.ID,
+	)
+	return translateSlugError(err)
+}
+
+// DeleteRoomType removes a room type and its rooms, rates and blocks. Types
+// that have ever been reserved are kept for the booking history.
+func (m *postgresDBRepo) DeleteRoomType(ctx context.Context, id int) error {
+	ctx, cancel := context.WithTimeout(ctx, m.timeout())
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var reserved bool
+	err = tx.QueryRowContext(ctx, `
+		select exists (
+			select 1 from reservations r
+			left join rooms rm on rm.id = r.room_id
+			where r.room_type_id = $1 or rm.room_type_id = $1)`, id).Scan(&reserved)
+	if err != nil {
+		return err
+	}
+	if reserved {
+		return repository.ErrRoomTypeInUse
+	}
+
+	queries := []string{
+		`delete from room_restrictions where room_id in (select id from rooms where room_type_id = $1)`,
+		`delete from rooms where room_type_id = $1`,
+		`delete from room_types where id = $1`,
+	}
+	for _, query := range queries {
+		if _, err = tx.ExecContext(ctx, query, id); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// SearchAvailabilityAllRoomTypes returns the room types with enough free
+// rooms for the party when it is spread evenly over the rooms asked for.
+func (m *postgresDBRepo) SearchAvailabilityAllRoomTypes(ctx context.Context, startDate, endDate time.Time, guests models.Guests) ([]models.RoomTypeAvailability, error) {
+	ctx, cancel := context.WithTimeout(ctx, m.timeout())
+	defer cancel()
+
+	var availability []models.RoomTypeAvailability
+
+	adults, total := guests.PerRoom()
+
+	query := `
+		select ` + roomTypeColumns + `, count(rm.id)
+		from room_types rt
+		join rooms rm on rm.room_type_id = rt.id
+		where rm.active and not exists (
+			select 1 from room_restrictions rr
+			where rr.room_id = rm.id and $1 < rr.end_date and $2 > rr.start_date
+			and (rr.expires_at is null or rr.expires_at > $3))
+		and rt.capacity >= $4
+		and (case when rt.max_adults > 0 and rt.max_adults < rt.capacity then rt.max_adults else rt.capacity end) >= $5
+		group by rt.id
+		having count(rm.id) >= $6
+		order by rt.id
+	`
+	rows, err := m.DB.QueryContext(ctx, query, startDate, endDate, time.Now(), total, adults, guests.Rooms)
+	if err != nil {
+		return availability, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var item models.RoomTypeAvailability
+		if err := scanRoomType(rows, &item.RoomType, &item.FreeUnits); err != nil {
+			return availability, err
+		}
+		availability = append(availability, item)
+	}
+
+	if err = rows.Err(); err != nil {
+		return availability, err
+	}
+	return availability, nil
+}
+
+func (m *postgresDBRepo) SearchAvailabilityByDatesByRoomTypeId(ctx context.Context, startDate, endDate time.Time, roomTypeId int) (int, error) {
+	ctx, cancel := context.WithTimeout(ctx, m.timeout())
+	defer cancel()
+
+	var freeUnits int
+
+	query := `
+		select count(rm.id)
+		from rooms rm
+		where rm.room_type_id = $1 and rm.active and not exists (
+			select 1 from room_restrictions rr
+			where rr.room_id = rm.id and $2 < rr.end_date and $3 > rr.start_date
+			and (rr.expires_at is null or rr.expires_at > $4))
+	`
+	err := m.DB.QueryRowContext(ctx, query, roomTypeId, startDate, endDate, time.Now()).Scan(&freeUnits)
+	if err != nil {
+		return 0, err
+	}
+	return freeUnits, nil
+}
+
+func (m *postgresDBRepo) FreeRoomsByType(ctx context.Context, roomTypeId int, startDate, endDate time.Time) ([]models.Room, error) {
+	ctx, cancel := context.WithTimeout(ctx, m.timeout())
+	defer cancel()
+
+	var rooms []models.Room
+
+	query := `
+		select ` + roomColumns + `
+		from rooms rm
+		where rm.room_type_id = $1 and rm.active and not exists (
+			select 1 from room_restrictions rr
+			where rr.room_id = rm.id and $2 < rr.end_date and $3 > rr.start_date
+			and (rr.expires_at is null or rr.expires_at > $4))
+		order by rm.sort_order, rm.id
+	`
+	rows, err := m.DB.QueryContext(ctx, query, roomTypeId, startDate, endDate, time.Now())
+	if err != nil {
+		return rooms, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var room models.Room
+		if err := scanRoom(rows, &room); err != nil {
+			return rooms, err
+		}
+		rooms = append(rooms, room)
+	}
+
+	if err = rows.Err(); err != nil {
+		return rooms, err
+	}
+	return rooms, nil
+}
+
+func (m *postgresDBRepo) AssignReservationRoom(ctx context.Context, id, roomId int) error {
+	ctx, cancel := context.WithTimeout(ctx, m.timeout())
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var reservation models.Reservation
+	err = tx.QueryRowContext(ctx,
+		`select start_date, end_date, status from reservations where id = $1`, id).Scan(
+		&reservation.StartDate,
+		&reservation.EndDate,
+		&reservation.Status,
+	)
+	if err != nil {
+		return err
+	}
+
+	if err = m.lockRoom(ctx, tx, roomId); err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, `delete from room_restrictions where reservation_id = $1`, id)
+	if err != nil {
+		return err
+	}
+
+	if models.HoldsRoom(reservation.Status) {
+		available, err := m.isAvailableTx(ctx, tx, reservation.StartDate, reservation.EndDate, roomId)
+		if err != nil {
+			return err
+		}
+
+		if !available {
+			return repository.ErrRoomUnavailable
+		}
+
+		query := `insert into room_restrictions(
+			start_date, end_date, room_id, reservation_id,
+			restriction_id, created_at, updated_at) values ($1, $2, $3, $4, $5, $6, $7)`
+
+		_, err = tx.ExecContext(ctx, query, reservation.StartDate, reservation.EndDate, roomId, id, models.RestrictionReservation, time.Now(), time.Now())
+		if err != nil {
+			return translateError(err)
+		}
+	}
+
+	_, err = tx.ExecContext(ctx,
+		`update reservations set room_id = $1, updated_at = $2 where id = $3`,
+		roomId, time.Now(), id)
+	if err != nil {
+		return err
+	}
+
+	return translateError(tx.Commit())
+}
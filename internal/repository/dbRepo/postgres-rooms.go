@@ -0,0 +1,276 @@
+package dbrepo
+
+import (
+	"context"
+	"time"
+
+	"github.com/NhanNT-VNG/hotel-booking/internal/models"
+	"github.com/NhanNT-VNG/hotel-booking/internal/repository"
+)
+
+func (m *postgresDBRepo) InsertRoom(ctx context.Context, room models.Room) (int, error) {
+	ctx, cancel := context.WithTimeout(ctx, m.timeout())
+	defer cancel()
+
+	query := `insert into rooms(room_name, room_type_id, active, sort_order, created_at, updated_at)
+		values ($1, $2, $3, (select coalesce(max(sort_order), 0) + 1 from rooms), $4, $5) returning id`
+
+	var id int
+	err := m.DB.QueryRowContext(ctx, query,
+		room.RoomName,
+		room.RoomTypeId,
+		room.Active,
+		time.Now(),
+		time.Now(),
+	).Scan(&id)
+	if err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+func (m *postgresDBRepo) UpdateRoom(ctx context.Context, room models.Room) error {
+	ctx, cancel := context.WithTimeout(ctx, m.timeout())
+	defer cancel()
+
+	query := `update rooms set room_name = $1, room_type_id = $2, active = $3, updated_at = $4 where id = $5`
+
+	_, err := m.DB.ExecContext(ctx, query,
+		room.RoomName,
+		room.RoomTypeId,
+		room.Active,
+		time.Now(),
+		room.ID,
+	)
+	return err
+}
+
+// ReorderRooms stores the position of every room in roomIds.
+func (m *postgresDBRepo) ReorderRooms(ctx context.Context, roomIds []int) error {
+	ctx, cancel := context.WithTimeout(ctx, m.timeout())
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for order, roomId := range roomIds {
+		_, err = tx.ExecContext(ctx, `update rooms set sort_order = $1 where id = $2`, order+1, roomId)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// OccupancyForDate counts the active rooms that are sold or out of service on
+// the night starting at date, based on the restriction type's counts_as_sold.
+func (m *postgresDBRepo) OccupancyForDate(ctx context.Context, date time.Time) (models.Occupancy, error) {
+	ctx, cancel := context.WithTimeout(ctx, m.timeout())
+	defer cancel()
+
+	var occupancy models.Occupancy
+
+	query := `
+		select count(*), count(*) filter (where sold), count(*) filter (where blocked and not sold)
+		from (
+			select
+				bool_or(coalesce(r.counts_as_sold, false)) as sold,
+				bool_or(r.id is not null and not r.counts_as_sold) as blocked
+			from rooms rm
+			left join room_restrictions rr on rr.room_id = rm.id
+				and rr.start_date <= $1 and rr.end_date > $1
+				and (rr.expires_at is null or rr.expires_at > $2)
+			left join restrictions r on r.id = rr.restriction_id
+			where rm.active
+			group by rm.id
+		) nights
+	`
+	err := m.DB.QueryRowContext(ctx, query, date, time.Now()).Scan(
+		&occupancy.Rooms,
+		&occupancy.Sold,
+		&occupancy.OutOfService,
+	)
+	if err != nil {
+		return occupancy, err
+	}
+	return occupancy, nil
+}
+
+const restrictionColumns = `id, restriction_name, color, counts_as_sold, created_at, updated_at`
+
+func scanRestriction(row rowScanner, restriction *models.Restriction) error {
+	return row.Scan(
+		&restriction.ID,
+		&restriction.RestrictionName,
+		&restriction.Color,
+		&restriction.CountsAsSold,
+		&restriction.CreatedAt,
+		&restriction.UpdatedAt,
+	)
+}
+
+func (m *postgresDBRepo) AllRestrictions(ctx context.Context) ([]models.Restriction, error) {
+	ctx, cancel := context.WithTimeout(ctx, m.timeout())
+	defer cancel()
+
+	var restrictions []models.Restriction
+
+	rows, err := m.DB.QueryContext(ctx, `select `+restrictionColumns+` from restrictions order by id`)
+	if err != nil {
+		return restrictions, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var restriction models.Restriction
+		if err := scanRestriction(rows, &restriction); err != nil {
+			return restrictions, err
+		}
+		restrictions = append(restrictions, restriction)
+	}
+
+	if err = rows.Err(); err != nil {
+		return restrictions, err
+	}
+	return restrictions, nil
+}
+
+func (m *postgresDBRepo) GetRestrictionById(ctx context.Context, id int) (models.Restriction, error) {
+	ctx, cancel := context.WithTimeout(ctx, m.timeout())
+	defer cancel()
+
+	var restriction models.Restriction
+
+	row := m.DB.QueryRowContext(ctx, `select `+restrictionColumns+` from restrictions where id = $1`, id)
+	if err := scanRestriction(row, &restriction); err != nil {
+		return restriction, err
+	}
+	return restriction, nil
+}
+
+func (m *postgresDBRepo) InsertRestriction(ctx context.Context, restriction models.Restriction) (int, error) {
+	ctx, cancel := context.WithTimeout(ctx, m.timeout())
+	defer cancel()
+
+	query := `insert into restrictions(restriction_name, color, counts_as_sold, created_at, updated_at)
+		values ($1, $2, $3, $4, $5) returning id`
+
+	var id int
+	err := m.DB.QueryRowContext(ctx, query,
+		restriction.RestrictionName,
+		restriction.Color,
+		restriction.CountsAsSold,
+		time.Now(),
+		time.Now(),
+	).Scan(&id)
+	if err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+func (m *postgresDBRepo) UpdateRestriction(ctx context.Context, restriction models.Restriction) error {
+	ctx, cancel := context.WithTimeout(ctx, m.timeout())
+	defer cancel()
+
+	query := `update restrictions set restriction_name = $1, color = $2, counts_as_sold = $3, updated_at = $4
+		where id = $5`
+
+	_, err := m.DB.ExecContext(ctx, query,
+		restriction.RestrictionName,
+		restriction.Color,
+		restriction.CountsAsSold,
+		time.Now(),
+		restriction.ID,
+	)
+	return err
+}
+
+// DeleteRestriction removes a custom restriction type that no room
+// restriction refers to. Built-in types are never deleted.
+func (m *postgresDBRepo) DeleteRestriction(ctx context.Context, id int) error {
+	ctx, cancel := context.WithTimeout(ctx, m.timeout())
+	defer cancel()
+
+	if (models.Restriction{ID: id}).IsBuiltIn() {
+		return repository.ErrRestrictionInUse
+	}
+
+	res, err := m.DB.ExecContext(ctx, `
+		delete from restrictions
+		where id = $1 and not exists (select 1 from room_restrictions where restriction_id = $1)`, id)
+	if err != nil {
+		return err
+	}
+
+	if n, _ := res.RowsAffected(); n == 0 {
+		return repository.ErrRestrictionInUse
+	}
+	return nil
+}
+
+// RestrictionsForRoomByDate returns the reservations, blocks and live holds of
+// a room that overlap the period, with their restriction type.
+func (m *postgresDBRepo) RestrictionsForRoomByDate(ctx context.Context, roomId int, startDate, endDate time.Time) ([]models.RoomRestriction, error) {
+	ctx, cancel := context.WithTimeout(ctx, m.timeout())
+	defer cancel()
+
+	var restrictions []models.RoomRestriction
+
+	query := `
+		select rr.id, rr.start_date, rr.end_date, rr.room_id, coalesce(rr.reservation_id, 0),
+			rr.restriction_id, r.restriction_name, r.color, r.counts_as_sold
+		from room_restrictions rr
+		join restrictions r on r.id = rr.restriction_id
+		where rr.room_id = $1 and $2 < rr.end_date and $3 > rr.start_date
+		and (rr.expires_at is null or rr.expires_at > $4)
+		order by rr.start_date
+	`
+	rows, err := m.DB.QueryContext(ctx, query, roomId, startDate, endDate, time.Now())
+	if err != nil {
+		return restrictions, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var rr models.RoomRestriction
+		err := rows.Scan(
+			&rr.ID,
+			&rr.StartDate,
+			&rr.EndDate,
+			&rr.RoomId,
+			&rr.ReservationId,
+			&rr.RestrictionId,
+			&rr.Restriction.RestrictionName,
+			&rr.Restriction.Color,
+			&rr.Restriction.CountsAsSold,
+		)
+		if err != nil {
+			return restrictions, err
+		}
+		rr.Restriction.ID = rr.RestrictionId
+		restrictions = append(restrictions, rr)
+	}
+
+	if err = rows.Err(); err != nil {
+		return restrictions, err
+	}
+	return restrictions, nil
+}
+
+// DeleteBlockById removes an owner or maintenance block. Reservations and
+// holds are left alone.
+func (m *postgresDBRepo) DeleteBlockById(ctx context.Context, id int) error {
+	ctx, cancel := context.WithTimeout(ctx, m.timeout())
+	defer cancel()
+
+	_, err := m.DB.ExecContext(ctx, `
+		delete from room_restrictions
+		where id = $1 and reservation_id is null and hold_token is null and restriction_id not in ($2, $3)`,
+		id, models.RestrictionReservation, models.RestrictionHold)
+	return err
+}
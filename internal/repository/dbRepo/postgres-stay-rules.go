@@ -0,0 +1,146 @@
+package dbrepo
+
+import (
+	"context"
+	"time"
+
+	"github.com/NhanNT-VNG/hotel-booking/internal/models"
+)
+
+const stayRuleColumns = `
+	sr.id, sr.room_type_id, sr.name, sr.start_date, sr.end_date, sr.min_nights, sr.max_nights,
+	sr.closed_to_arrival, sr.closed_to_departure, sr.arrival_weekdays,
+	sr.created_at, sr.updated_at, rt.id, rt.name`
+
+func scanStayRule(row rowScanner, rule *models.StayRule) error {
+	return row.Scan(
+		&rule.ID,
+		&rule.RoomTypeId,
+		&rule.Name,
+		&rule.StartDate,
+		&rule.EndDate,
+		&rule.MinNights,
+		&rule.MaxNights,
+		&rule.ClosedToArrival,
+		&rule.ClosedToDeparture,
+		&rule.ArrivalWeekdays,
+		&rule.CreatedAt,
+		&rule.UpdatedAt,
+		&rule.RoomType.ID,
+		&rule.RoomType.Name,
+	)
+}
+
+func (m *postgresDBRepo) queryStayRules(ctx context.Context, query string, args ...interface{}) ([]models.StayRule, error) {
+	var rules []models.StayRule
+
+	rows, err := m.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return rules, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var rule models.StayRule
+		if err := scanStayRule(rows, &rule); err != nil {
+			return rules, err
+		}
+		rules = append(rules, rule)
+	}
+
+	if err = rows.Err(); err != nil {
+		return rules, err
+	}
+	return rules, nil
+}
+
+func (m *postgresDBRepo) AllStayRules(ctx context.Context) ([]models.StayRule, error) {
+	ctx, cancel := context.WithTimeout(ctx, m.timeout())
+	defer cancel()
+
+	query := `
+		select ` + stayRuleColumns + `
+		from stay_rules sr
+		left join room_types rt on rt.id = sr.room_type_id
+		order by rt.name, sr.start_date, sr.id
+	`
+	return m.queryStayRules(ctx, query)
+}
+
+// StayRulesForPeriod returns the rules of a room type that cover the arrival
+// or the departure date of a stay.
+func (m *postgresDBRepo) StayRulesForPeriod(ctx context.Context, roomTypeId int, startDate, endDate time.Time) ([]models.StayRule, error) {
+	ctx, cancel := context.WithTimeout(ctx, m.timeout())
+	defer cancel()
+
+	query := `
+		select ` + stayRuleColumns + `
+		from stay_rules sr
+		left join room_types rt on rt.id = sr.room_type_id
+		where sr.room_type_id = $1 and sr.start_date <= $3 and sr.end_date >= $2
+		order by sr.id
+	`
+	return m.queryStayRules(ctx, query, roomTypeId, startDate, endDate)
+}
+
+func (m *postgresDBRepo) GetStayRuleById(ctx context.Context, id int) (models.StayRule, error) {
+	ctx, cancel := context.WithTimeout(ctx, m.timeout())
+	defer cancel()
+
+	var rule models.StayRule
+
+	query := `
+		select ` + stayRuleColumns + `
+		from stay_rules sr
+		left join room_types rt on rt.id = sr.room_type_id
+		where sr.id = $1
+	`
+	err := scanStayRule(m.DB.QueryRowContext(ctx, query, id), &rule)
+	if err != nil {
+		return rule, err
+	}
+	return rule, nil
+}
+
+func (m *postgresDBRepo) InsertStayRule(ctx context.Context, rule models.StayRule) (int, error) {
+	ctx, cancel := context.WithTimeout(ctx, m.timeout())
+	defer cancel()
+
+	query := `insert into stay_rules(
+		room_type_id, name, start_date, end_date, min_nights, max_nights,
+		closed_to_arrival, closed_to_departure, arrival_weekdays, created_at, updated_at)
+		values ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11) returning id`
+
+	var id int
+
+	err := m.DB.QueryRowContext(ctx, query,
+		rule.RoomTypeId,
+		rule.Name,
+		rule.StartDate,
+		rule.EndDate,
+		rule.MinNights,
+		rule.MaxNights,
+		rule.ClosedToArrival,
+		rule.ClosedToDeparture,
+		rule.ArrivalWeekdays,
+		time.Now(),
+		time.Now(),
+	).Scan(&id)
+
+	if err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+func (m *postgresDBRepo) DeleteStayRule(ctx context.Context, id int) error {
+	ctx, cancel := context.WithTimeout(ctx, m.timeout())
+	defer cancel()
+
+	_, err := m.DB.ExecContext(ctx, `delete from stay_rules where id = $1`, id)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
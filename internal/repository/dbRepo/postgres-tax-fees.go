@@ -0,0 +1,142 @@
+package dbrepo
+
+import (
+	"context"
+	"time"
+
+	"github.com/NhanNT-VNG/hotel-booking/internal/models"
+)
+
+func (m *postgresDBRepo) AllTaxFees(ctx context.Context) ([]models.TaxFee, error) {
+	ctx, cancel := context.WithTimeout(ctx, m.timeout())
+	defer cancel()
+
+	var fees []models.TaxFee
+
+	query := `
+		select id, name, kind, amount, exempt_children, active, created_at, updated_at
+		from tax_fees
+		order by id
+	`
+	rows, err := m.DB.QueryContext(ctx, query)
+	if err != nil {
+		return fees, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var fee models.TaxFee
+		err := rows.Scan(
+			&fee.ID,
+			&fee.Name,
+			&fee.Kind,
+			&fee.Amount,
+			&fee.ExemptChildren,
+			&fee.Active,
+			&fee.CreatedAt,
+			&fee.UpdatedAt,
+		)
+		if err != nil {
+			return fees, err
+		}
+		fees = append(fees, fee)
+	}
+
+	if err = rows.Err(); err != nil {
+		return fees, err
+	}
+	return fees, nil
+}
+
+func (m *postgresDBRepo) GetTaxFeeById(ctx context.Context, id int) (models.TaxFee, error) {
+	ctx, cancel := context.WithTimeout(ctx, m.timeout())
+	defer cancel()
+
+	var fee models.TaxFee
+
+	query := `
+		select id, name, kind, amount, exempt_children, active, created_at, updated_at
+		from tax_fees
+		where id = $1
+	`
+	err := m.DB.QueryRowContext(ctx, query, id).Scan(
+		&fee.ID,
+		&fee.Name,
+		&fee.Kind,
+		&fee.Amount,
+		&fee.ExemptChildren,
+		&fee.Active,
+		&fee.CreatedAt,
+		&fee.UpdatedAt,
+	)
+
+	if err != nil {
+		return fee, err
+	}
+	return fee, nil
+}
+
+func (m *postgresDBRepo) InsertTaxFee(ctx context.Context, fee models.TaxFee) (int, error) {
+	ctx, cancel := context.WithTimeout(ctx, m.timeout())
+	defer cancel()
+
+	query := `insert into tax_fees(
+		name, kind, amount, exempt_children, active, created_at, updated_at)
+		values ($1, $2, $3, $4, $5, $6, $7) returning id`
+
+	var id int
+
+	err := m.DB.QueryRowContext(ctx, query,
+		fee.Name,
+		fee.Kind,
+		fee.Amount,
+		fee.ExemptChildren,
+		fee.Active,
+		time.Now(),
+		time.Now(),
+	).Scan(&id)
+
+	if err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+func (m *postgresDBRepo) UpdateTaxFee(ctx context.Context, fee models.TaxFee) error {
+	ctx, cancel := context.WithTimeout(ctx, m.timeout())
+	defer cancel()
+
+	query := `
+		update tax_fees
+		set name = $1, kind = $2, amount = $3, exempt_children = $4, active = $5, updated_at = $6
+		where id = $7
+	`
+	_, err := m.DB.ExecContext(ctx, query,
+		fee.Name,
+		fee.Kind,
+		fee.Amount,
+		fee.ExemptChildren,
+		fee.Active,
+		time.Now(),
+		fee.ID,
+	)
+
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// DeleteTaxFee removes a catalog entry. Charges already stored with
+// reservations are kept.
+func (m *postgresDBRepo) DeleteTaxFee(ctx context.Context, id int) error {
+	ctx, cancel := context.WithTimeout(ctx, m.timeout())
+	defer cancel()
+
+	_, err := m.DB.ExecContext(ctx, `delete from tax_fees where id = $1`, id)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -2,19 +2,111 @@ package dbrepo
 
 import (
 	"context"
+	"database/sql"
 	"errors"
 	"time"
 
 	"github.com/NhanNT-VNG/hotel-booking/internal/models"
+	"github.com/NhanNT-VNG/hotel-booking/internal/repository"
+	"github.com/jackc/pgconn"
 	"golang.org/x/crypto/bcrypt"
 )
 
-func (m *postgresDBRepo) AllUsers() bool {
+const defaultDBTimeout = 3 * time.Second
+
+func (m *postgresDBRepo) timeout() time.Duration {
+	if m.App == nil || m.App.DBTimeout <= 0 {
+		return defaultDBTimeout
+	}
+	return m.App.DBTimeout
+}
+
+func translateError(err error) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23P01" {
+		return repository.ErrRoomUnavailable
+	}
+	return err
+}
+
+const roomColumns = `
+	rm.id, rm.room_name, rm.room_type_id, rm.active, rm.sort_order, rm.created_at, rm.updated_at`
+
+const reservationColumns = `
+	r.id, r.first_name, r.last_name, r.email, r.phone, r.start_date,
+	r.end_date, r.room_id, r.created_at, r.updated_at, rm.id, rm.room_name,
+	r.status, coalesce(r.confirmation_code, ''), r.cancellation_penalty, r.cancelled_at,
+	r.deleted_at, coalesce(r.deleted_by, 0), r.total,
+	coalesce(r.room_type_id, 0), coalesce(rt.name, ''), r.adults, r.children,
+	coalesce(r.parent_id, 0), coalesce(r.promo_code_id, 0),
+	coalesce((select pc.code from promo_codes pc where pc.id = r.promo_code_id), ''), r.discount,
+	r.first_night`
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanRoom(row rowScanner, room *models.Room) error {
+	return row.Scan(
+		&room.ID,
+		&room.RoomName,
+		&room.RoomTypeId,
+		&room.Active,
+		&room.SortOrder,
+		&room.CreatedAt,
+		&room.UpdatedAt,
+	)
+}
+
+func scanReservation(row rowScanner, reservation *models.Reservation) error {
+	var cancelledAt, deletedAt sql.NullTime
+
+	err := row.Scan(
+		&reservation.ID,
+		&reservation.FirstName,
+		&reservation.LastName,
+		&reservation.Email,
+		&reservation.Phone,
+		&reservation.StartDate,
+		&reservation.EndDate,
+		&reservation.RoomId,
+		&reservation.CreatedAt,
+		&reservation.UpdatedAt,
+		&reservation.Room.ID,
+		&reservation.Room.RoomName,
+		&reservation.Status,
+		&reservation.ConfirmationCode,
+		&reservation.CancellationPenalty,
+		&cancelledAt,
+		&deletedAt,
+		&reservation.DeletedBy,
+		&reservation.Total,
+		&reservation.RoomTypeId,
+		&reservation.RoomType.Name,
+		&reservation.Adults,
+		&reservation.Children,
+		&reservation.ParentId,
+		&reservation.PromoCodeId,
+		&reservation.PromoCode,
+		&reservation.Discount,
+		&reservation.FirstNight,
+	)
+	if err != nil {
+		return err
+	}
+
+	reservation.RoomType.ID = reservation.RoomTypeId
+	reservation.CancelledAt = cancelledAt.Time
+	reservation.DeletedAt = deletedAt.Time
+	return nil
+}
+
+func (m *postgresDBRepo) AllUsers(ctx context.Context) bool {
 	return true
 }
 
-func (m *postgresDBRepo) InsertReservation(reservation models.Reservation) (int, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+func (m *postgresDBRepo) InsertReservation(ctx context.Context, reservation models.Reservation) (int, error) {
+	ctx, cancel := context.WithTimeout(ctx, m.timeout())
 	defer cancel()
 
 	query := `insert into reservations(
@@ -42,105 +134,452 @@ func (m *postgresDBRepo) InsertReservation(reservation models.Reservation) (int,
 	return reservationId, nil
 }
 
-func (m *postgresDBRepo) InsertRoomRestrictions(rr models.RoomRestriction) error {
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+func (m *postgresDBRepo) InsertRoomRestrictions(ctx context.Context, rr models.RoomRestriction) error {
+	ctx, cancel := context.WithTimeout(ctx, m.timeout())
 	defer cancel()
 	query := `insert into room_restrictions(
 		start_date, end_date, room_id, reservation_id, 
 		restriction_id, created_at, updated_at) values ($1, $2, $3, $4, $5, $6, $7)`
 
+	var reservationId sql.NullInt64
+	if rr.ReservationId > 0 {
+		reservationId = sql.NullInt64{Int64: int64(rr.ReservationId), Valid: true}
+	}
+
 	_, err := m.DB.ExecContext(
 		ctx, query,
 		rr.StartDate,
 		rr.EndDate,
 		rr.RoomId,
-		rr.ReservationId,
+		reservationId,
 		rr.RestrictionId,
 		time.Now(),
 		time.Now(),
 	)
 
 	if err != nil {
-		return err
+		return translateError(err)
 	}
 
 	return nil
 }
 
-func (m *postgresDBRepo) SearchAvailabilityByDatesByRoomId(statDate, endDate time.Time, roomId int) (bool, error) {
-	ctx, cancer := context.WithTimeout(context.Background(), 3*time.Second)
-	defer cancer()
+func (m *postgresDBRepo) lockRoom(ctx context.Context, tx *sql.Tx, roomId int) error {
+	_, err := tx.ExecContext(ctx, `select id from rooms where id = $1 for update`, roomId)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx,
+		`delete from room_restrictions where room_id = $1 and expires_at < $2`,
+		roomId, time.Now())
+
+	return err
+}
+
+func (m *postgresDBRepo) isAvailableTx(ctx context.Context, tx *sql.Tx, startDate, endDate time.Time, roomId int) (bool, error) {
 	var numRows int
 	query := `
 		select count(id)
 		from room_restrictions
-		where 
+		where
 			room_id = $1 and
 			$2 < end_date and $3 > start_date;
 	`
-	row := m.DB.QueryRowContext(ctx, query, roomId, statDate, endDate)
-	err := row.Scan(&numRows)
+	err := tx.QueryRowContext(ctx, query, roomId, startDate, endDate).Scan(&numRows)
 	if err != nil {
 		return false, err
 	}
 
-	if numRows == 0 {
-		return true, nil
+	return numRows == 0, nil
+}
+
+// pickRoomTx locks every room of the type and returns the preferred room when
+// it is free for the dates, otherwise the first other free unit of the type.
+func (m *postgresDBRepo) pickRoomTx(ctx context.Context, tx *sql.Tx, roomTypeId, preferredRoomId int, startDate, endDate time.Time) (int, error) {
+	if roomTypeId == 0 {
+		if err := m.lockRoom(ctx, tx, preferredRoomId); err != nil {
+			return 0, err
+		}
+
+		available, err := m.isAvailableTx(ctx, tx, startDate, endDate, preferredRoomId)
+		if err != nil {
+			return 0, err
+		}
+		if !available {
+			return 0, repository.ErrRoomUnavailable
+		}
+		return preferredRoomId, nil
+	}
+
+	rows, err := tx.QueryContext(ctx, `select id from rooms where room_type_id = $1 and active order by sort_order, id for update`, roomTypeId)
+	if err != nil {
+		return 0, err
+	}
+
+	var roomIds []int
+	for rows.Next() {
+		var roomId int
+		if err := rows.Scan(&roomId); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		if roomId == preferredRoomId {
+			roomIds = append([]int{roomId}, roomIds...)
+		} else {
+			roomIds = append(roomIds, roomId)
+		}
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return 0, err
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		delete from room_restrictions
+		where expires_at < $1 and room_id in (select id from rooms where room_type_id = $2)`,
+		time.Now(), roomTypeId)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, roomId := range roomIds {
+		available, err := m.isAvailableTx(ctx, tx, startDate, endDate, roomId)
+		if err != nil {
+			return 0, err
+		}
+		if available {
+			return roomId, nil
+		}
 	}
-	return false, nil
+
+	return 0, repository.ErrRoomUnavailable
 }
 
-func (m *postgresDBRepo) SearchAvailabilityAllRooms(startDate, endDate time.Time) ([]models.Room, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+// CreateReservation books the reservation and the extra rooms in its Lines in
+// one transaction. The holds of the reservation and of every line are
+// released first.
+func (m *postgresDBRepo) CreateReservation(ctx context.Context, reservation models.Reservation, restrictionId int, holdToken string) (int, error) {
+	ctx, cancel := context.WithTimeout(ctx, m.timeout())
 	defer cancel()
 
-	var rooms []models.Room
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	holdTokens := []string{holdToken}
+	for _, line := range reservation.Lines {
+		holdTokens = append(holdTokens, line.HoldToken)
+	}
+	for _, token := range holdTokens {
+		if token == "" {
+			continue
+		}
+		_, err = tx.ExecContext(ctx, `delete from room_restrictions where hold_token = $1`, token)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	// the usage limit is checked and counted in the same statement, so two
+	// guests cannot both take the last use of a code
+	if reservation.PromoCodeId > 0 {
+		result, err := tx.ExecContext(ctx, `
+			update promo_codes set uses = uses + 1, updated_at = $1
+			where id = $2 and active and (max_uses = 0 or uses < max_uses)`,
+			time.Now(), reservation.PromoCodeId)
+		if err != nil {
+			return 0, err
+		}
+		if n, err := result.RowsAffected(); err != nil {
+			return 0, err
+		} else if n == 0 {
+			return 0, repository.ErrPromoCodeUsedUp
+		}
+	}
+
+	reservationId, err := m.insertReservationTx(ctx, tx, reservation, nil, restrictionId)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, line := range reservation.Lines {
+		line.FirstName = reservation.FirstName
+		line.LastName = reservation.LastName
+		line.Email = reservation.Email
+		line.Phone = reservation.Phone
+		line.ConfirmationCode = ""
+		line.PromoCodeId = reservation.PromoCodeId
+		if _, err = m.insertReservationTx(ctx, tx, line, reservationId, restrictionId); err != nil {
+			return 0, err
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return 0, translateError(err)
+	}
+
+	return reservationId, nil
+}
+
+func (m *postgresDBRepo) insertReservationTx(ctx context.Context, tx *sql.Tx, reservation models.Reservation, parentId interface{}, restrictionId int) (int, error) {
+	var err error
+
+	reservation.RoomId, err = m.pickRoomTx(ctx, tx, reservation.RoomTypeId, reservation.RoomId, reservation.StartDate, reservation.EndDate)
+	if err != nil {
+		return 0, err
+	}
+
+	var confirmationCode, promoCodeId interface{}
+	if reservation.ConfirmationCode != "" {
+		confirmationCode = reservation.ConfirmationCode
+	}
+	if reservation.PromoCodeId > 0 {
+		promoCodeId = reservation.PromoCodeId
+	}
+
+	query := `insert into reservations(
+		first_name, last_name, email, phone, start_date,
+		end_date, room_id, room_type_id, confirmation_code, total, adults, children,
+		parent_id, promo_code_id, discount, first_night, created_at, updated_at)
+		values ($1, $2, $3, $4, $5, $6, $7, (select room_type_id from rooms where id = $7), $8, $9, $10, $11,
+		$12, $13, $14, $15, $16, $17)
+		returning id`
+
+	var reservationId int
+
+	err = tx.QueryRowContext(ctx, query,
+		reservation.FirstName,
+		reservation.LastName,
+		reservation.Email,
+		reservation.Phone,
+		reservation.StartDate,
+		reservation.EndDate,
+		reservation.RoomId,
+		confirmationCode,
+		reservation.Total,
+		reservation.Adults,
+		reservation.Children,
+		parentId,
+		promoCodeId,
+		reservation.Discount,
+		reservation.FirstNight,
+		time.Now(),
+		time.Now(),
+	).Scan(&reservationId)
+
+	if err != nil {
+		return 0, err
+	}
+
+	query = `insert into room_restrictions(
+		start_date, end_date, room_id, reservation_id,
+		restriction_id, created_at, updated_at) values ($1, $2, $3, $4, $5, $6, $7)`
+
+	_, err = tx.ExecContext(ctx, query,
+		reservation.StartDate,
+		reservation.EndDate,
+		reservation.RoomId,
+		reservationId,
+		restrictionId,
+		time.Now(),
+		time.Now(),
+	)
+
+	if err != nil {
+		return 0, translateError(err)
+	}
+
+	if err = insertChargesTx(ctx, tx, reservationId, reservation.Charges); err != nil {
+		return 0, err
+	}
+
+	return reservationId, nil
+}
+
+func insertChargesTx(ctx context.Context, tx *sql.Tx, reservationId int, charges []models.ReservationCharge) error {
+	query := `insert into reservation_charges(
+		reservation_id, tax_fee_id, name, kind, quantity, amount, created_at, updated_at)
+		values ($1, $2, $3, $4, $5, $6, $7, $8)`
+
+	for _, charge := range charges {
+		var taxFeeId interface{}
+		if charge.TaxFeeId > 0 {
+			taxFeeId = charge.TaxFeeId
+		}
+
+		_, err := tx.ExecContext(ctx, query,
+			reservationId,
+			taxFeeId,
+			charge.Name,
+			charge.Kind,
+			charge.Quantity,
+			charge.Amount,
+			time.Now(),
+			time.Now(),
+		)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *postgresDBRepo) ReservationCharges(ctx context.Context, reservationId int) ([]models.ReservationCharge, error) {
+	ctx, cancel := context.WithTimeout(ctx, m.timeout())
+	defer cancel()
+
+	var charges []models.ReservationCharge
 
 	query := `
-		select r.id, r.room_name
-		from rooms r
-		where r.id not in 
-		(select room_id from room_restrictions where $1 < end_date and $2 > start_date);
+		select id, reservation_id, coalesce(tax_fee_id, 0), name, kind, quantity, amount
+		from reservation_charges
+		where reservation_id = $1
+		order by id
 	`
-	rows, err := m.DB.QueryContext(ctx, query, startDate, endDate)
-
+	rows, err := m.DB.QueryContext(ctx, query, reservationId)
 	if err != nil {
-		return rooms, err
+		return charges, err
 	}
+	defer rows.Close()
 
 	for rows.Next() {
-		var room models.Room
-		err := rows.Scan(&room.ID, &room.RoomName)
-
+		var charge models.ReservationCharge
+		err := rows.Scan(
+			&charge.ID,
+			&charge.ReservationId,
+			&charge.TaxFeeId,
+			&charge.Name,
+			&charge.Kind,
+			&charge.Quantity,
+			&charge.Amount,
+		)
 		if err != nil {
-			return rooms, err
+			return charges, err
 		}
+		charges = append(charges, charge)
+	}
 
-		rooms = append(rooms, room)
+	if err = rows.Err(); err != nil {
+		return charges, err
+	}
+	return charges, nil
+}
+
+// ReservationLines returns the extra rooms booked together with a
+// reservation, including deleted ones.
+func (m *postgresDBRepo) ReservationLines(ctx context.Context, parentId int) ([]models.Reservation, error) {
+	ctx, cancel := context.WithTimeout(ctx, m.timeout())
+	defer cancel()
+
+	var lines []models.Reservation
+
+	query := `
+		select ` + reservationColumns + `
+		from reservations r
+		left join rooms rm on rm.id = r.room_id
+		left join room_types rt on rt.id = r.room_type_id
+		where r.parent_id = $1
+		order by r.id
+	`
+	rows, err := m.DB.QueryContext(ctx, query, parentId)
+	if err != nil {
+		return lines, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var line models.Reservation
+		if err := scanReservation(rows, &line); err != nil {
+			return lines, err
+		}
+		lines = append(lines, line)
 	}
 
 	if err = rows.Err(); err != nil {
-		return rooms, err
+		return lines, err
 	}
-	return rooms, nil
+	return lines, nil
+}
+
+func (m *postgresDBRepo) CreateRoomHold(ctx context.Context, rr models.RoomRestriction, roomTypeId int) (int, error) {
+	ctx, cancel := context.WithTimeout(ctx, m.timeout())
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	rr.RoomId, err = m.pickRoomTx(ctx, tx, roomTypeId, rr.RoomId, rr.StartDate, rr.EndDate)
+	if err != nil {
+		return 0, err
+	}
+
+	query := `insert into room_restrictions(
+		start_date, end_date, room_id, restriction_id,
+		hold_token, expires_at, created_at, updated_at)
+		values ($1, $2, $3, $4, $5, $6, $7, $8)`
+
+	_, err = tx.ExecContext(ctx, query,
+		rr.StartDate,
+		rr.EndDate,
+		rr.RoomId,
+		rr.RestrictionId,
+		rr.HoldToken,
+		rr.ExpiresAt,
+		time.Now(),
+		time.Now(),
+	)
+
+	if err != nil {
+		return 0, translateError(err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return 0, translateError(err)
+	}
+
+	return rr.RoomId, nil
+}
+
+func (m *postgresDBRepo) DeleteRoomHold(ctx context.Context, holdToken string) error {
+	ctx, cancel := context.WithTimeout(ctx, m.timeout())
+	defer cancel()
+
+	_, err := m.DB.ExecContext(ctx, `delete from room_restrictions where hold_token = $1`, holdToken)
+	if err != nil {
+		return err
+	}
+
+	return nil
 }
 
-func (m *postgresDBRepo) GetRoomById(roomId int) (models.Room, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+func (m *postgresDBRepo) DeleteExpiredHolds(ctx context.Context) (int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, m.timeout())
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, `delete from room_restrictions where expires_at < $1`, time.Now())
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
+
+func (m *postgresDBRepo) GetRoomById(ctx context.Context, roomId int) (models.Room, error) {
+	ctx, cancel := context.WithTimeout(ctx, m.timeout())
 	defer cancel()
 
 	var room models.Room
 
-	query := `select id, room_name, created_at, updated_at from rooms where id = $1`
+	query := `select ` + roomColumns + ` from rooms rm where rm.id = $1`
 
 	row := m.DB.QueryRowContext(ctx, query, roomId)
 
-	err := row.Scan(
-		&room.ID,
-		&room.RoomName,
-		&room.CreatedAt,
-		&room.UpdatedAt,
-	)
+	err := scanRoom(row, &room)
 
 	if err != nil {
 		return room, err
@@ -149,8 +588,8 @@ func (m *postgresDBRepo) GetRoomById(roomId int) (models.Room, error) {
 	return room, nil
 }
 
-func (m *postgresDBRepo) GetUserById(userId int) (models.User, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+func (m *postgresDBRepo) GetUserById(ctx context.Context, userId int) (models.User, error) {
+	ctx, cancel := context.WithTimeout(ctx, m.timeout())
 	defer cancel()
 
 	query := `
@@ -177,8 +616,8 @@ func (m *postgresDBRepo) GetUserById(userId int) (models.User, error) {
 	return user, nil
 }
 
-func (m *postgresDBRepo) UpdateUser(user models.User) error {
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+func (m *postgresDBRepo) UpdateUser(ctx context.Context, user models.User) error {
+	ctx, cancel := context.WithTimeout(ctx, m.timeout())
 	defer cancel()
 	query := `
 		update users
@@ -205,8 +644,8 @@ func (m *postgresDBRepo) UpdateUser(user models.User) error {
 	return nil
 }
 
-func (m *postgresDBRepo) Authenticate(email, password string) (int, string, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+func (m *postgresDBRepo) Authenticate(ctx context.Context, email, password string) (int, string, error) {
+	ctx, cancel := context.WithTimeout(ctx, m.timeout())
 	defer cancel()
 
 	var id int
@@ -228,43 +667,28 @@ func (m *postgresDBRepo) Authenticate(email, password string) (int, string, erro
 	return id, hashedPassword, nil
 }
 
-func (m *postgresDBRepo) AllReservations() ([]models.Reservation, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+func (m *postgresDBRepo) ReservationsByStatus(ctx context.Context, status string) ([]models.Reservation, error) {
+	ctx, cancel := context.WithTimeout(ctx, m.timeout())
 	defer cancel()
 
 	var reservationList []models.Reservation
 
 	query := `
-		select 
-			r.id, r.first_name, r.last_name, r.email, r.phone, r.start_date,
-			r.end_date, r.room_id, r.created_at, r.updated_at, rm.id, rm.room_name,
-			r.processed
+		select ` + reservationColumns + `
 		from reservations r
 		left join rooms rm on rm.id = r.room_id
+		left join room_types rt on rt.id = r.room_type_id
+		where r.deleted_at is null and r.parent_id is null and ($1 = '' or r.status = $1)
 		order by r.start_date
 	`
-	rows, err := m.DB.QueryContext(ctx, query)
+	rows, err := m.DB.QueryContext(ctx, query, status)
 	if err != nil {
 		return reservationList, err
 	}
 	defer rows.Close()
 	for rows.Next() {
 		var reservation models.Reservation
-		err := rows.Scan(
-			&reservation.ID,
-			&reservation.FirstName,
-			&reservation.LastName,
-			&reservation.Email,
-			&reservation.Phone,
-			&reservation.StartDate,
-			&reservation.EndDate,
-			&reservation.RoomId,
-			&reservation.CreatedAt,
-			&reservation.UpdatedAt,
-			&reservation.Room.ID,
-			&reservation.Room.RoomName,
-			&reservation.Processed,
-		)
+		err := scanReservation(rows, &reservation)
 
 		if err != nil {
 			return reservationList, err
@@ -278,85 +702,42 @@ func (m *postgresDBRepo) AllReservations() ([]models.Reservation, error) {
 	return reservationList, nil
 }
 
-func (m *postgresDBRepo) AllNewReservations() ([]models.Reservation, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+func (m *postgresDBRepo) GetReservationById(ctx context.Context, id int) (models.Reservation, error) {
+	ctx, cancel := context.WithTimeout(ctx, m.timeout())
 	defer cancel()
 
-	var reservationList []models.Reservation
+	var reservation models.Reservation
 
 	query := `
-		select 
-			r.id, r.first_name, r.last_name, r.email, r.phone, r.start_date, 
-			r.end_date, r.room_id, r.created_at, r.updated_at, rm.id, rm.room_name
+		select ` + reservationColumns + `
 		from reservations r
 		left join rooms rm on rm.id = r.room_id
-		where r.processed = 0
-		order by r.start_date
+		left join room_types rt on rt.id = r.room_type_id
+		where r.id = $1
 	`
-	rows, err := m.DB.QueryContext(ctx, query)
-	if err != nil {
-		return reservationList, err
-	}
-	defer rows.Close()
-	for rows.Next() {
-		var reservation models.Reservation
-		err := rows.Scan(
-			&reservation.ID,
-			&reservation.FirstName,
-			&reservation.LastName,
-			&reservation.Email,
-			&reservation.Phone,
-			&reservation.StartDate,
-			&reservation.EndDate,
-			&reservation.RoomId,
-			&reservation.CreatedAt,
-			&reservation.UpdatedAt,
-			&reservation.Room.ID,
-			&reservation.Room.RoomName,
-		)
+	err := scanReservation(m.DB.QueryRowContext(ctx, query, id), &reservation)
 
-		if err != nil {
-			return reservationList, err
-		}
-		reservationList = append(reservationList, reservation)
+	if err != nil {
+		return reservation, err
 	}
 
-	if err = rows.Err(); err != nil {
-		return reservationList, err
-	}
-	return reservationList, nil
+	return reservation, nil
 }
 
-func (m *postgresDBRepo) GetReservationById(id int) (models.Reservation, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+func (m *postgresDBRepo) GetReservationByCode(ctx context.Context, code string) (models.Reservation, error) {
+	ctx, cancel := context.WithTimeout(ctx, m.timeout())
 	defer cancel()
 
 	var reservation models.Reservation
 
 	query := `
-		select 
-			r.id, r.first_name, r.last_name, r.email, r.phone, r.start_date, 
-			r.end_date, r.room_id, r.created_at, r.updated_at, rm.id, rm.room_name,
-			r.processed
+		select ` + reservationColumns + `
 		from reservations r
 		left join rooms rm on rm.id = r.room_id
-		where r.id = $1
+		left join room_types rt on rt.id = r.room_type_id
+		where r.confirmation_code = $1 and r.deleted_at is null
 	`
-	err := m.DB.QueryRowContext(ctx, query, id).Scan(
-		&reservation.ID,
-		&reservation.FirstName,
-		&reservation.LastName,
-		&reservation.Email,
-		&reservation.Phone,
-		&reservation.StartDate,
-		&reservation.EndDate,
-		&reservation.RoomId,
-		&reservation.CreatedAt,
-		&reservation.UpdatedAt,
-		&reservation.Room.ID,
-		&reservation.Room.RoomName,
-		&reservation.Processed,
-	)
+	err := scanReservation(m.DB.QueryRowContext(ctx, query, code), &reservation)
 
 	if err != nil {
 		return reservation, err
@@ -365,8 +746,69 @@ func (m *postgresDBRepo) GetReservationById(id int) (models.Reservation, error)
 	return reservation, nil
 }
 
-func (m *postgresDBRepo) UpdateReservation(reservation models.Reservation) error {
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+// ChangeReservationDates moves a reservation to its StartDate and EndDate and
+// saves its new price, discount and charges.
+func (m *postgresDBRepo) ChangeReservationDates(ctx context.Context, reservation models.Reservation) error {
+	ctx, cancel := context.WithTimeout(ctx, m.timeout())
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	id := reservation.ID
+	startDate, endDate := reservation.StartDate, reservation.EndDate
+
+	var roomId, roomTypeId int
+	err = tx.QueryRowContext(ctx,
+		`select room_id, coalesce(room_type_id, 0) from reservations where id = $1`, id).Scan(&roomId, &roomTypeId)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, `delete from room_restrictions where reservation_id = $1`, id)
+	if err != nil {
+		return err
+	}
+
+	roomId, err = m.pickRoomTx(ctx, tx, roomTypeId, roomId, startDate, endDate)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx,
+		`update reservations set start_date = $1, end_date = $2, room_id = $3, total = $4, discount = $5,
+		first_night = $6, updated_at = $7 where id = $8`,
+		startDate, endDate, roomId, reservation.Total, reservation.Discount, reservation.FirstNight, time.Now(), id)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, `delete from reservation_charges where reservation_id = $1`, id)
+	if err != nil {
+		return err
+	}
+
+	if err = insertChargesTx(ctx, tx, id, reservation.Charges); err != nil {
+		return err
+	}
+
+	query := `insert into room_restrictions(
+		start_date, end_date, room_id, reservation_id,
+		restriction_id, created_at, updated_at) values ($1, $2, $3, $4, $5, $6, $7)`
+
+	_, err = tx.ExecContext(ctx, query, startDate, endDate, roomId, id, models.RestrictionReservation, time.Now(), time.Now())
+	if err != nil {
+		return translateError(err)
+	}
+
+	return translateError(tx.Commit())
+}
+
+func (m *postgresDBRepo) UpdateReservation(ctx context.Context, reservation models.Reservation) error {
+	ctx, cancel := context.WithTimeout(ctx, m.timeout())
 	defer cancel()
 	query := `
 		update reservations
@@ -393,37 +835,366 @@ func (m *postgresDBRepo) UpdateReservation(reservation models.Reservation) error
 	return nil
 }
 
-func (m *postgresDBRepo) DeleteReservation(id int) error {
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+// DeleteReservation moves a reservation to the trash, together with the
+// other rooms of the booking when it is the first room.
+func (m *postgresDBRepo) DeleteReservation(ctx context.Context, id, userId int) error {
+	ctx, cancel := context.WithTimeout(ctx, m.timeout())
 	defer cancel()
-	query := `delete from reservations where id = $1`
 
-	_, err := m.DB.ExecContext(ctx, query, id)
+	tx, err := m.DB.BeginTx(ctx, nil)
 	if err != nil {
 		return err
 	}
+	defer tx.Rollback()
 
-	return nil
+	var deletedBy sql.NullInt64
+	if userId > 0 {
+		deletedBy = sql.NullInt64{Int64: int64(userId), Valid: true}
+	}
+
+	query := `update reservations set deleted_at = $1, deleted_by = $2 where id = $3 and deleted_at is null
+		returning status`
+
+	var status string
+	err = tx.QueryRowContext(ctx, query, time.Now(), deletedBy, id).Scan(&status)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	held := models.HoldsRoom(status)
+
+	// the other rooms of a booking go with its first room
+	rows, err := tx.QueryContext(ctx, `
+		update reservations set deleted_at = $1, deleted_by = $2
+		where parent_id = $3 and deleted_at is null
+		returning status`,
+		time.Now(), deletedBy, id)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var lineStatus string
+		if err = rows.Scan(&lineStatus); err != nil {
+			return err
+		}
+		held = held || models.HoldsRoom(lineStatus)
+	}
+	if err = rows.Err(); err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		delete from room_restrictions
+		where reservation_id in (select id from reservations where id = $1 or parent_id = $1)`, id)
+	if err != nil {
+		return err
+	}
+
+	if held {
+		if err = releasePromoUseTx(ctx, tx, id); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (m *postgresDBRepo) DeletedReservations(ctx context.Context) ([]models.Reservation, error) {
+	ctx, cancel := context.WithTimeout(ctx, m.timeout())
+	defer cancel()
+
+	var reservationList []models.Reservation
+
+	query := `
+		select ` + reservationColumns + `
+		from reservations r
+		left join rooms rm on rm.id = r.room_id
+		left join room_types rt on rt.id = r.room_type_id
+		where r.deleted_at is not null and r.parent_id is null
+		order by r.deleted_at desc
+	`
+	rows, err := m.DB.QueryContext(ctx, query)
+	if err != nil {
+		return reservationList, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var reservation models.Reservation
+		err := scanReservation(rows, &reservation)
+
+		if err != nil {
+			return reservationList, err
+		}
+		reservationList = append(reservationList, reservation)
+	}
+
+	if err = rows.Err(); err != nil {
+		return reservationList, err
+	}
+	return reservationList, nil
 }
 
-func (m *postgresDBRepo) UpdateProcessedReservation(id, processed int) error {
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+func (m *postgresDBRepo) RestoreReservation(ctx context.Context, id int) error {
+	ctx, cancel := context.WithTimeout(ctx, m.timeout())
 	defer cancel()
-	query := `update reservations set processed = $1 where id = $2`
 
-	_, err := m.DB.ExecContext(ctx, query, processed, id)
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var reservation models.Reservation
+	query := `
+		select room_id, coalesce(room_type_id, 0), start_date, end_date, status, coalesce(promo_code_id, 0)
+		from reservations where id = $1 and deleted_at is not null`
+
+	err = tx.QueryRowContext(ctx, query, id).Scan(
+		&reservation.RoomId,
+		&reservation.RoomTypeId,
+		&reservation.StartDate,
+		&reservation.EndDate,
+		&reservation.Status,
+		&reservation.PromoCodeId,
+	)
+	if err != nil {
+		return err
+	}
+
+	if models.HoldsRoom(reservation.Status) {
+		// the booking takes its promo code use back if it had given it up,
+		// which is refused once the code is used up by other bookings
+		active, err := bookingActiveTx(ctx, tx, id)
+		if err != nil {
+			return err
+		}
+		if !active && reservation.PromoCodeId > 0 {
+			result, err := tx.ExecContext(ctx, `
+				update promo_codes set uses = uses + 1, updated_at = $1
+				where id = $2 and (max_uses = 0 or uses < max_uses)`,
+				time.Now(), reservation.PromoCodeId)
+			if err != nil {
+				return err
+			}
+			if n, err := result.RowsAffected(); err != nil {
+				return err
+			} else if n == 0 {
+				return repository.ErrPromoCodeUsedUp
+			}
+		}
+
+		reservation.RoomId, err = m.pickRoomTx(ctx, tx, reservation.RoomTypeId, reservation.RoomId, reservation.StartDate, reservation.EndDate)
+		if err != nil {
+			return err
+		}
+
+		query = `insert into room_restrictions(
+			start_date, end_date, room_id, reservation_id,
+			restriction_id, created_at, updated_at) values ($1, $2, $3, $4, $5, $6, $7)`
+
+		_, err = tx.ExecContext(ctx, query,
+			reservation.StartDate,
+			reservation.EndDate,
+			reservation.RoomId,
+			id,
+			models.RestrictionReservation,
+			time.Now(),
+			time.Now(),
+		)
+		if err != nil {
+			return translateError(err)
+		}
+	}
+
+	_, err = tx.ExecContext(ctx,
+		`update reservations set deleted_at = null, deleted_by = null, room_id = $1, updated_at = $2 where id = $3`,
+		reservation.RoomId, time.Now(), id)
+	if err != nil {
+		return err
+	}
+
+	return translateError(tx.Commit())
+}
+
+func (m *postgresDBRepo) changeStatusTx(ctx context.Context, tx *sql.Tx, id int, status string) error {
+	var current string
+	var deleted bool
+	err := tx.QueryRowContext(ctx,
+		`select status, deleted_at is not null from reservations where id = $1 for update`, id).Scan(&current, &deleted)
+	if err != nil {
+		return err
+	}
+
+	if !models.CanTransition(current, status) {
+		return repository.ErrInvalidStatusTransition
+	}
+
+	_, err = tx.ExecContext(ctx,
+		`update reservations set status = $1, updated_at = $2 where id = $3`,
+		status, time.Now(), id)
+	if err != nil {
+		return err
+	}
+
+	query := `insert into reservation_status_changes(
+		reservation_id, from_status, to_status, created_at, updated_at)
+		values ($1, $2, $3, $4, $5)`
+
+	_, err = tx.ExecContext(ctx, query, id, current, status, time.Now(), time.Now())
 	if err != nil {
 		return err
 	}
 
+	if status == models.StatusCancelled || status == models.StatusNoShow {
+		_, err = tx.ExecContext(ctx, `delete from room_restrictions where reservation_id = $1`, id)
+		if err != nil {
+			return err
+		}
+
+		if !deleted {
+			if err = releasePromoUseTx(ctx, tx, id); err != nil {
+				return err
+			}
+		}
+	}
+
 	return nil
 }
 
-func (m *postgresDBRepo) AllRooms() ([]models.Room, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+// bookingActiveTx reports whether a room of the booking reservation id
+// belongs to is still held, that is neither deleted, cancelled nor a no-show.
+func bookingActiveTx(ctx context.Context, tx *sql.Tx, id int) (bool, error) {
+	query := `
+		select exists (
+			select 1 from reservations r
+			join reservations b on b.id = $1
+			where coalesce(b.parent_id, b.id) in (r.id, r.parent_id)
+			and r.deleted_at is null and r.status not in ($2, $3)
+		)`
+
+	var active bool
+	err := tx.QueryRowContext(ctx, query, id, models.StatusCancelled, models.StatusNoShow).Scan(&active)
+	return active, err
+}
+
+// changePromoUsesTx changes the uses of the promo code reservation id was
+// booked with, if any.
+func changePromoUsesTx(ctx context.Context, tx *sql.Tx, id, change int) error {
+	_, err := tx.ExecContext(ctx, `
+		update promo_codes set uses = greatest(uses + $1, 0), updated_at = $2
+		where id = (select promo_code_id from reservations where id = $3)`,
+		change, time.Now(), id)
+	return err
+}
+
+// releasePromoUseTx gives back the promo code use of a booking once none of
+// its rooms is held any more, so cancelled and deleted bookings do not count
+// against the code's limit. id is a room that just stopped being held.
+func releasePromoUseTx(ctx context.Context, tx *sql.Tx, id int) error {
+	active, err := bookingActiveTx(ctx, tx, id)
+	if err != nil || active {
+		return err
+	}
+	return changePromoUsesTx(ctx, tx, id, -1)
+}
+
+func (m *postgresDBRepo) UpdateReservationStatus(ctx context.Context, id int, status string) error {
+	return m.ChangeRoomsStatus(ctx, []models.Reservation{{ID: id}}, status)
+}
+
+func (m *postgresDBRepo) CancelReservation(ctx context.Context, id int, penalty int) error {
+	return m.ChangeRoomsStatus(ctx, []models.Reservation{{ID: id, CancellationPenalty: penalty}}, models.StatusCancelled)
+}
+
+// ChangeRoomsStatus moves the rooms of a booking to status together. A
+// cancelled room is charged its CancellationPenalty. Nothing is changed
+// when any of the rooms cannot be moved to status.
+func (m *postgresDBRepo) ChangeRoomsStatus(ctx context.Context, rooms []models.Reservation, status string) error {
+	ctx, cancel := context.WithTimeout(ctx, m.timeout())
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, room := range rooms {
+		if status == models.StatusCancelled {
+			err = m.cancelTx(ctx, tx, room.ID, room.CancellationPenalty)
+		} else {
+			err = m.changeStatusTx(ctx, tx, room.ID, status)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// cancelTx cancels a room and records what the cancellation costs.
+func (m *postgresDBRepo) cancelTx(ctx context.Context, tx *sql.Tx, id, penalty int) error {
+	if err := m.changeStatusTx(ctx, tx, id, models.StatusCancelled); err != nil {
+		return err
+	}
+
+	_, err := tx.ExecContext(ctx,
+		`update reservations set cancellation_penalty = $1, cancelled_at = $2 where id = $3`,
+		penalty, time.Now(), id)
+	return err
+}
+
+func (m *postgresDBRepo) GetReservationStatusChanges(ctx context.Context, id int) ([]models.ReservationStatusChange, error) {
+	ctx, cancel := context.WithTimeout(ctx, m.timeout())
+	defer cancel()
+
+	var changes []models.ReservationStatusChange
+
+	query := `
+		select id, reservation_id, from_status, to_status, created_at
+		from reservation_status_changes
+		where reservation_id = $1
+		order by created_at
+	`
+	rows, err := m.DB.QueryContext(ctx, query, id)
+	if err != nil {
+		return changes, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var change models.ReservationStatusChange
+		err := rows.Scan(
+			&change.ID,
+			&change.ReservationId,
+			&change.FromStatus,
+			&change.ToStatus,
+			&change.CreatedAt,
+		)
+
+		if err != nil {
+			return changes, err
+		}
+		changes = append(changes, change)
+	}
+
+	if err = rows.Err(); err != nil {
+		return changes, err
+	}
+	return changes, nil
+}
+
+func (m *postgresDBRepo) AllRooms(ctx context.Context) ([]models.Room, error) {
+	ctx, cancel := context.WithTimeout(ctx, m.timeout())
 	defer cancel()
 	var rooms []models.Room
-	query := `select id, room_name, created_at, updated_at from rooms order by room_name`
+	query := `select ` + roomColumns + ` from rooms rm order by rm.sort_order, rm.id`
 
 	rows, err := m.DB.QueryContext(ctx, query)
 
@@ -436,12 +1207,7 @@ func (m *postgresDBRepo) AllRooms() ([]models.Room, error) {
 	for rows.Next() {
 		var room models.Room
 
-		err := rows.Scan(
-			&room.ID,
-			&room.RoomName,
-			&room.CreatedAt,
-			&room.UpdatedAt,
-		)
+		err := scanRoom(rows, &room)
 
 		if err != nil {
 			return rooms, err
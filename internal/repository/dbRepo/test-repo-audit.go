@@ -0,0 +1,47 @@
+package dbrepo
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/NhanNT-VNG/hotel-booking/internal/models"
+)
+
+func (m *testDBRepo) InsertAuditEvent(ctx context.Context, event models.AuditEvent) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	event.ID = m.nextId("audit_events")
+	event.CreatedAt = time.Now()
+	event.User = models.User{}
+
+	m.auditEvents = append(m.auditEvents, event)
+	return nil
+}
+
+func (m *testDBRepo) AuditEventsByEntity(ctx context.Context, entityType string, entityId int) ([]models.AuditEvent, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var events []models.AuditEvent
+
+	for _, event := range m.auditEvents {
+		if event.EntityType != entityType || event.EntityId != entityId {
+			continue
+		}
+
+		for _, user := range m.users {
+			if user.ID == event.UserId {
+				event.User = user
+				event.User.Password = ""
+			}
+		}
+		events = append(events, event)
+	}
+
+	sort.SliceStable(events, func(i, j int) bool {
+		return events[i].ID > events[j].ID
+	})
+	return events, nil
+}
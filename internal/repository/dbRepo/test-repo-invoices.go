@@ -0,0 +1,28 @@
+package dbrepo
+
+import (
+	"context"
+	"time"
+
+	"github.com/NhanNT-VNG/hotel-booking/internal/models"
+)
+
+func (m *testDBRepo) IssueInvoice(ctx context.Context, reservationId int) (models.Invoice, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, invoice := range m.invoices {
+		if invoice.ReservationId == reservationId {
+			return invoice, nil
+		}
+	}
+
+	invoice := models.Invoice{
+		ID:            m.nextId("invoices"),
+		Number:        len(m.invoices) + 1,
+		ReservationId: reservationId,
+		IssuedAt:      time.Now(),
+	}
+	m.invoices = append(m.invoices, invoice)
+	return invoice, nil
+}
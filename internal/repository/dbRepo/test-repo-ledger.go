@@ -0,0 +1,132 @@
+package dbrepo
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/NhanNT-VNG/hotel-booking/internal/models"
+	"github.com/NhanNT-VNG/hotel-booking/internal/repository"
+)
+
+func (m *testDBRepo) ReservationRefunds(ctx context.Context, reservationId int) ([]models.Refund, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var refunds []models.Refund
+	for _, refund := range m.refunds {
+		if refund.ReservationId == reservationId {
+			refunds = append(refunds, refund)
+		}
+	}
+	return refunds, nil
+}
+
+func (m *testDBRepo) InsertPendingRefunds(ctx context.Context, reservationId, refunded int, refunds []models.Refund) ([]models.Refund, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	current := 0
+	for _, refund := range m.refunds {
+		if refund.ReservationId == reservationId && refund.Status != models.RefundFailed {
+			current += refund.Amount
+		}
+	}
+	if current != refunded {
+		return nil, repository.ErrRefundsChanged
+	}
+
+	for i := range refunds {
+		refunds[i].ID = m.nextId("refunds")
+		refunds[i].ReservationId = reservationId
+		refunds[i].Status = models.RefundPending
+		refunds[i].CreatedAt = time.Now()
+		refunds[i].UpdatedAt = time.Now()
+		m.refunds = append(m.refunds, refunds[i])
+	}
+	return refunds, nil
+}
+
+func (m *testDBRepo) CompleteRefund(ctx context.Context, refund models.Refund, description string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	i := m.refundIndex(refund.ID)
+	if i < 0 || m.refunds[i].Status != models.RefundPending {
+		return sql.ErrNoRows
+	}
+	m.refunds[i].Status = models.RefundSucceeded
+	m.refunds[i].ProviderRef = refund.ProviderRef
+	m.refunds[i].UpdatedAt = time.Now()
+
+	m.ledger = append(m.ledger, models.LedgerEntry{
+		ID:            m.nextId("ledger_entries"),
+		ReservationId: refund.ReservationId,
+		Kind:          models.LedgerRefund,
+		Description:   description,
+		Amount:        refund.Amount,
+		PaymentId:     refund.PaymentId,
+		RefundId:      refund.ID,
+		UserId:        refund.UserId,
+		CreatedAt:     time.Now(),
+	})
+
+	refunded := 0
+	for _, r := range m.refunds {
+		if r.PaymentId == refund.PaymentId && r.Status == models.RefundSucceeded {
+			refunded += r.Amount
+		}
+	}
+	for i := range m.payments {
+		if m.payments[i].ID == refund.PaymentId && m.payments[i].Amount <= refunded {
+			m.payments[i].Status = models.PaymentRefunded
+			m.payments[i].UpdatedAt = time.Now()
+		}
+	}
+
+	return nil
+}
+
+func (m *testDBRepo) FailRefund(ctx context.Context, id int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if i := m.refundIndex(id); i >= 0 && m.refunds[i].Status == models.RefundPending {
+		m.refunds[i].Status = models.RefundFailed
+		m.refunds[i].UpdatedAt = time.Now()
+	}
+	return nil
+}
+
+func (m *testDBRepo) refundIndex(id int) int {
+	for i, refund := range m.refunds {
+		if refund.ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+func (m *testDBRepo) LedgerEntries(ctx context.Context, reservationId int) ([]models.LedgerEntry, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var entries []models.LedgerEntry
+	for _, entry := range m.ledger {
+		if entry.ReservationId == reservationId {
+			entries = append(entries, entry)
+		}
+	}
+	return entries, nil
+}
+
+func (m *testDBRepo) InsertLedgerEntry(ctx context.Context, entry models.LedgerEntry) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	entry.ID = m.nextId("ledger_entries")
+	entry.CreatedAt = time.Now()
+
+	m.ledger = append(m.ledger, entry)
+	return entry.ID, nil
+}
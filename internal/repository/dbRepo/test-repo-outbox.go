@@ -0,0 +1,94 @@
+package dbrepo
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/NhanNT-VNG/hotel-booking/internal/models"
+)
+
+func (m *testDBRepo) InsertMail(ctx context.Context, mail models.MailData) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	outboxMail := models.OutboxMail{
+		ID:            m.nextId("mail_outbox"),
+		Mail:          mail,
+		Status:        models.MailPending,
+		NextAttemptAt: time.Now(),
+		CreatedAt:     time.Now(),
+		UpdatedAt:     time.Now(),
+	}
+	m.outbox = append(m.outbox, outboxMail)
+	return outboxMail.ID, nil
+}
+
+func (m *testDBRepo) ClaimMail(ctx context.Context, lease time.Duration) (models.OutboxMail, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	next := -1
+	for i, mail := range m.outbox {
+		if mail.Status != models.MailPending || mail.NextAttemptAt.After(now) {
+			continue
+		}
+		if next < 0 || mail.NextAttemptAt.Before(m.outbox[next].NextAttemptAt) {
+			next = i
+		}
+	}
+	if next < 0 {
+		return models.OutboxMail{}, sql.ErrNoRows
+	}
+
+	m.outbox[next].Attempts++
+	m.outbox[next].NextAttemptAt = now.Add(lease)
+	m.outbox[next].UpdatedAt = now
+	return m.outbox[next], nil
+}
+
+func (m *testDBRepo) UpdateOutboxMail(ctx context.Context, mail models.OutboxMail) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for i := range m.outbox {
+		if m.outbox[i].ID == mail.ID {
+			mail.Mail = m.outbox[i].Mail
+			mail.CreatedAt = m.outbox[i].CreatedAt
+			mail.UpdatedAt = time.Now()
+			m.outbox[i] = mail
+			return nil
+		}
+	}
+	return nil
+}
+
+func (m *testDBRepo) OutboxMailByStatus(ctx context.Context, status string) ([]models.OutboxMail, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var mails []models.OutboxMail
+	for i := len(m.outbox) - 1; i >= 0; i-- {
+		if m.outbox[i].Status == status {
+			mails = append(mails, m.outbox[i])
+		}
+	}
+	return mails, nil
+}
+
+func (m *testDBRepo) ResendMail(ctx context.Context, id int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for i := range m.outbox {
+		if m.outbox[i].ID == id && m.outbox[i].Status == models.MailFailed {
+			m.outbox[i].Status = models.MailPending
+			m.outbox[i].Attempts = 0
+			m.outbox[i].NextAttemptAt = time.Now()
+			m.outbox[i].UpdatedAt = time.Now()
+			return nil
+		}
+	}
+	return sql.ErrNoRows
+}
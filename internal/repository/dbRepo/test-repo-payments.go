@@ -0,0 +1,158 @@
+package dbrepo
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/NhanNT-VNG/hotel-booking/internal/models"
+	"github.com/NhanNT-VNG/hotel-booking/internal/repository"
+)
+
+// activePayment mirrors the unique index on payments that are processing,
+// authorized or captured.
+func activePayment(status string) bool {
+	return status == models.PaymentProcessing || status == models.PaymentAuthorized || status == models.PaymentCaptured
+}
+
+func (m *testDBRepo) ReservationPayments(ctx context.Context, reservationId int) ([]models.Payment, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var payments []models.Payment
+	for _, payment := range m.payments {
+		if payment.ReservationId == reservationId {
+			payments = append(payments, payment)
+		}
+	}
+	return payments, nil
+}
+
+func (m *testDBRepo) GetPaymentByProviderRef(ctx context.Context, provider, ref string) (models.Payment, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, payment := range m.payments {
+		if payment.Provider == provider && payment.ProviderRef == ref {
+			return payment, nil
+		}
+	}
+	return models.Payment{}, sql.ErrNoRows
+}
+
+func (m *testDBRepo) InsertPayment(ctx context.Context, payment models.Payment) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	pending := false
+	for _, reservation := range m.reservations {
+		if reservation.ID == payment.ReservationId && reservation.DeletedAt.IsZero() {
+			pending = reservation.Status == models.StatusPending
+		}
+	}
+	if !pending {
+		return 0, repository.ErrReservationNotPending
+	}
+
+	if activePayment(payment.Status) {
+		for _, other := range m.payments {
+			if other.ReservationId == payment.ReservationId && activePayment(other.Status) {
+				return 0, repository.ErrPaymentInProgress
+			}
+		}
+	}
+
+	payment.ID = m.nextId("payments")
+	payment.CreatedAt = time.Now()
+	payment.UpdatedAt = time.Now()
+
+	m.payments = append(m.payments, payment)
+	return payment.ID, nil
+}
+
+func (m *testDBRepo) UpdatePaymentStatus(ctx context.Context, id int, status, message string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for i := range m.payments {
+		if m.payments[i].ID == id {
+			m.payments[i].Status = status
+			m.payments[i].Message = message
+			m.payments[i].UpdatedAt = time.Now()
+			return nil
+		}
+	}
+	return nil
+}
+
+func (m *testDBRepo) AuthorizePayment(ctx context.Context, id int, providerRef string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for i := range m.payments {
+		if m.payments[i].ID == id {
+			m.payments[i].Status = models.PaymentAuthorized
+			m.payments[i].ProviderRef = providerRef
+			m.payments[i].UpdatedAt = time.Now()
+			return nil
+		}
+	}
+	return nil
+}
+
+func (m *testDBRepo) UnpaidReservations(ctx context.Context, createdBefore time.Time) ([]models.Reservation, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var reservationList []models.Reservation
+
+	for _, reservation := range m.reservations {
+		if !reservation.DeletedAt.IsZero() || reservation.ParentId != 0 ||
+			reservation.Status != models.StatusPending || !reservation.CreatedAt.Before(createdBefore) {
+			continue
+		}
+
+		paying := false
+		for _, payment := range m.payments {
+			if payment.ReservationId == reservation.ID && activePayment(payment.Status) {
+				paying = true
+			}
+		}
+		if !paying {
+			reservationList = append(reservationList, m.withRoom(reservation))
+		}
+	}
+	return reservationList, nil
+}
+
+func (m *testDBRepo) CancelUnpaidReservation(ctx context.Context, id int) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	pending := false
+	for _, reservation := range m.reservations {
+		if reservation.ID == id && reservation.ParentId == 0 && reservation.DeletedAt.IsZero() {
+			pending = reservation.Status == models.StatusPending
+		}
+	}
+	for _, payment := range m.payments {
+		if payment.ReservationId == id && activePayment(payment.Status) {
+			pending = false
+		}
+	}
+	if !pending {
+		return false, nil
+	}
+
+	var rooms []models.Reservation
+	for _, reservation := range m.reservations {
+		if reservation.ParentId == id && reservation.DeletedAt.IsZero() && reservation.Status == models.StatusPending {
+			rooms = append(rooms, models.Reservation{ID: reservation.ID})
+		}
+	}
+
+	if err := m.changeRoomsStatus(append(rooms, models.Reservation{ID: id}), models.StatusCancelled); err != nil {
+		return false, err
+	}
+	return true, nil
+}
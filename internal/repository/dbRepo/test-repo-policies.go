@@ -0,0 +1,103 @@
+package dbrepo
+
+import (
+	"context"
+	"database/sql"
+	"sort"
+	"time"
+
+	"github.com/NhanNT-VNG/hotel-booking/internal/models"
+)
+
+func (m *testDBRepo) AllCancellationPolicies(ctx context.Context) ([]models.CancellationPolicy, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	policies := make([]models.CancellationPolicy, len(m.policies))
+	copy(policies, m.policies)
+
+	sort.SliceStable(policies, func(i, j int) bool {
+		return policies[i].Name < policies[j].Name
+	})
+	return policies, nil
+}
+
+func (m *testDBRepo) findPolicy(id int) (models.CancellationPolicy, bool) {
+	for _, policy := range m.policies {
+		if policy.ID == id {
+			return policy, true
+		}
+	}
+	return models.CancellationPolicy{}, false
+}
+
+func (m *testDBRepo) GetCancellationPolicyById(ctx context.Context, id int) (models.CancellationPolicy, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	policy, ok := m.findPolicy(id)
+	if !ok {
+		return policy, sql.ErrNoRows
+	}
+	return policy, nil
+}
+
+func (m *testDBRepo) GetCancellationPolicyByRoomTypeId(ctx context.Context, roomTypeId int) (models.CancellationPolicy, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	roomType, ok := m.findRoomType(roomTypeId)
+	if !ok {
+		return models.CancellationPolicy{}, sql.ErrNoRows
+	}
+
+	policy, ok := m.findPolicy(roomType.CancellationPolicyId)
+	if !ok {
+		return policy, sql.ErrNoRows
+	}
+	return policy, nil
+}
+
+func (m *testDBRepo) InsertCancellationPolicy(ctx context.Context, policy models.CancellationPolicy) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	policy.ID = m.nextId("cancellation_policies")
+	policy.CreatedAt = time.Now()
+	policy.UpdatedAt = time.Now()
+
+	m.policies = append(m.policies, policy)
+
+	return policy.ID, nil
+}
+
+func (m *testDBRepo) UpdateCancellationPolicy(ctx context.Context, policy models.CancellationPolicy) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for i := range m.policies {
+		if m.policies[i].ID == policy.ID {
+			m.policies[i].Name = policy.Name
+			m.policies[i].FreeCancellationDays = policy.FreeCancellationDays
+			m.policies[i].PenaltyType = policy.PenaltyType
+			m.policies[i].PenaltyPercent = policy.PenaltyPercent
+			m.policies[i].UpdatedAt = time.Now()
+			return nil
+		}
+	}
+	return nil
+}
+
+func (m *testDBRepo) UpdateRoomTypeCancellationPolicy(ctx context.Context, roomTypeId, policyId int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for i := range m.roomTypes {
+		if m.roomTypes[i].ID == roomTypeId {
+			m.roomTypes[i].CancellationPolicyId = policyId
+			m.roomTypes[i].UpdatedAt = time.Now()
+			return nil
+		}
+	}
+	return nil
+}
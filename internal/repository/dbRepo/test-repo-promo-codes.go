@@ -0,0 +1,115 @@
+package dbrepo
+
+import (
+	"context"
+	"database/sql"
+	"sort"
+	"time"
+
+	"github.com/NhanNT-VNG/hotel-booking/internal/models"
+	"github.com/NhanNT-VNG/hotel-booking/internal/repository"
+)
+
+func (m *testDBRepo) AllPromoCodes(ctx context.Context) ([]models.PromoCode, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	promos := append([]models.PromoCode(nil), m.promoCodes...)
+	sort.SliceStable(promos, func(i, j int) bool {
+		return promos[i].Code < promos[j].Code
+	})
+	return promos, nil
+}
+
+func (m *testDBRepo) GetPromoCodeById(ctx context.Context, id int) (models.PromoCode, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, promo := range m.promoCodes {
+		if promo.ID == id {
+			return promo, nil
+		}
+	}
+	return models.PromoCode{}, sql.ErrNoRows
+}
+
+func (m *testDBRepo) GetPromoCodeByCode(ctx context.Context, code string) (models.PromoCode, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	code = models.NormalizePromoCode(code)
+	for _, promo := range m.promoCodes {
+		if promo.Code == code {
+			return promo, nil
+		}
+	}
+	return models.PromoCode{}, sql.ErrNoRows
+}
+
+func (m *testDBRepo) codeTaken(code string, id int) bool {
+	for _, promo := range m.promoCodes {
+		if promo.Code == code && promo.ID != id {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *testDBRepo) InsertPromoCode(ctx context.Context, promo models.PromoCode) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.codeTaken(promo.Code, 0) {
+		return 0, repository.ErrDuplicatePromoCode
+	}
+
+	promo.ID = m.nextId("promo_codes")
+	promo.Uses = 0
+	promo.CreatedAt = time.Now()
+	promo.UpdatedAt = time.Now()
+
+	m.promoCodes = append(m.promoCodes, promo)
+	return promo.ID, nil
+}
+
+func (m *testDBRepo) UpdatePromoCode(ctx context.Context, promo models.PromoCode) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.codeTaken(promo.Code, promo.ID) {
+		return repository.ErrDuplicatePromoCode
+	}
+
+	for i := range m.promoCodes {
+		if m.promoCodes[i].ID == promo.ID {
+			promo.Uses = m.promoCodes[i].Uses
+			promo.CreatedAt = m.promoCodes[i].CreatedAt
+			promo.UpdatedAt = time.Now()
+			m.promoCodes[i] = promo
+			return nil
+		}
+	}
+	return nil
+}
+
+func (m *testDBRepo) DeletePromoCode(ctx context.Context, id int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	promos := m.promoCodes[:0]
+	for _, promo := range m.promoCodes {
+		if promo.ID != id {
+			promos = append(promos, promo)
+		}
+	}
+	m.promoCodes = promos
+
+	for i := range m.reservations {
+		if m.reservations[i].PromoCodeId == id {
+			m.reservations[i].PromoCodeId = 0
+			m.reservations[i].PromoCode = ""
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,108 @@
+package dbrepo
+
+import (
+	"context"
+	"database/sql"
+	"sort"
+	"time"
+
+	"github.com/NhanNT-VNG/hotel-booking/internal/models"
+)
+
+func (m *testDBRepo) rateWithRoomType(rate models.RoomRate) models.RoomRate {
+	if roomType, ok := m.findRoomType(rate.RoomTypeId); ok {
+		rate.RoomType = models.RoomType{ID: roomType.ID, Name: roomType.Name}
+	}
+	return rate
+}
+
+func (m *testDBRepo) AllRoomRates(ctx context.Context) ([]models.RoomRate, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var rates []models.RoomRate
+
+	for _, rate := range m.roomRates {
+		rates = append(rates, m.rateWithRoomType(rate))
+	}
+
+	sort.SliceStable(rates, func(i, j int) bool {
+		if rates[i].RoomType.Name != rates[j].RoomType.Name {
+			return rates[i].RoomType.Name < rates[j].RoomType.Name
+		}
+		return rates[i].StartDate.Before(rates[j].StartDate)
+	})
+	return rates, nil
+}
+
+func (m *testDBRepo) RoomRatesForPeriod(ctx context.Context, roomTypeId int, startDate, endDate time.Time) ([]models.RoomRate, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var rates []models.RoomRate
+
+	for _, rate := range m.roomRates {
+		if rate.RoomTypeId == roomTypeId && rate.StartDate.Before(endDate) && !rate.EndDate.Before(startDate) {
+			rates = append(rates, m.rateWithRoomType(rate))
+		}
+	}
+	return rates, nil
+}
+
+func (m *testDBRepo) GetRoomRateById(ctx context.Context, id int) (models.RoomRate, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, rate := range m.roomRates {
+		if rate.ID == id {
+			return m.rateWithRoomType(rate), nil
+		}
+	}
+	return models.RoomRate{}, sql.ErrNoRows
+}
+
+func (m *testDBRepo) InsertRoomRate(ctx context.Context, rate models.RoomRate) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.findRoomType(rate.RoomTypeId); !ok {
+		return 0, sql.ErrNoRows
+	}
+
+	rate.ID = m.nextId("room_rates")
+	rate.CreatedAt = time.Now()
+	rate.UpdatedAt = time.Now()
+	rate.RoomType = models.RoomType{}
+
+	m.roomRates = append(m.roomRates, rate)
+	return rate.ID, nil
+}
+
+func (m *testDBRepo) DeleteRoomRate(ctx context.Context, id int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	rates := m.roomRates[:0]
+	for _, rate := range m.roomRates {
+		if rate.ID != id {
+			rates = append(rates, rate)
+		}
+	}
+	m.roomRates = rates
+
+	return nil
+}
+
+func (m *testDBRepo) UpdateRoomTypeBaseRate(ctx context.Context, roomTypeId, rate int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for i := range m.roomTypes {
+		if m.roomTypes[i].ID == roomTypeId {
+			m.roomTypes[i].BaseRate = rate
+			m.roomTypes[i].UpdatedAt = time.Now()
+			return nil
+		}
+	}
+	return sql.ErrNoRows
+}
@@ -0,0 +1,240 @@
+package dbrepo
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/NhanNT-VNG/hotel-booking/internal/models"
+	"github.com/NhanNT-VNG/hotel-booking/internal/repository"
+)
+
+func (m *testDBRepo) AllRoomTypes(ctx context.Context) ([]models.RoomType, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	roomTypes := make([]models.RoomType, len(m.roomTypes))
+	copy(roomTypes, m.roomTypes)
+
+	return roomTypes, nil
+}
+
+func (m *testDBRepo) GetRoomTypeById(ctx context.Context, id int) (models.RoomType, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	roomType, ok := m.findRoomType(id)
+	if !ok {
+		return roomType, sql.ErrNoRows
+	}
+	return roomType, nil
+}
+
+func (m *testDBRepo) GetRoomTypeBySlug(ctx context.Context, slug string) (models.RoomType, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, roomType := range m.roomTypes {
+		if roomType.Slug == slug {
+			return roomType, nil
+		}
+	}
+	return models.RoomType{}, sql.ErrNoRows
+}
+
+func (m *testDBRepo) slugTaken(slug string, id int) bool {
+	for _, roomType := range m.roomTypes {
+		if roomType.Slug == slug && roomType.ID != id {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *testDBRepo) InsertRoomType(ctx context.Context, roomType models.RoomType) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.slugTaken(roomType.Slug, 0) {
+		return 0, repository.ErrDuplicateSlug
+	}
+
+	roomType.ID = m.nextId("room_types")
+	roomType.CreatedAt = time.Now()
+	roomType.UpdatedAt = time.Now()
+	m.roomTypes = append(m.roomTypes, roomType)
+
+	m.rooms = append(m.rooms, models.Room{
+		ID:         m.nextId("rooms"),
+		RoomName:   roomType.Name,
+		RoomTypeId: roomType.ID,
+		Active:     true,
+		SortOrder:  m.nextSortOrder(),
+		CreatedAt:  time.Now(),
+		UpdatedAt:  time.Now(),
+	})
+
+	return roomType.ID, nil
+}
+
+func (m *testDBRepo) UpdateRoomType(ctx context.Context, roomType models.RoomType) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.slugTaken(roomType.Slug, roomType.ID) {
+		return repository.ErrDuplicateSlug
+	}
+
+	for i := range m.roomTypes {
+		if m.roomTypes[i].ID == roomType.ID {
+			m.roomTypes[i].Name = roomType.Name
+			m.roomTypes[i].Slug = roomType.Slug
+			m.roomTypes[i].Description = roomType.Description
+			m.roomTypes[i].Capacity = roomType.Capacity
+			m.roomTypes[i].MaxAdults = roomType.MaxAdults
+			m.roomTypes[i].Amenities = roomType.Amenities
+			m.roomTypes[i].Photos = roomType.Photos
+			m.roomTypes[i].BaseRate = roomType.BaseRate
+			m.roomTypes[i].UpdatedAt = time.Now()
+			return nil
+		}
+	}
+	return nil
+}
+
+func (m *testDBRepo) DeleteRoomType(ctx context.Context, id int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	roomIds := make(map[int]bool)
+	for _, room := range m.rooms {
+		if room.RoomTypeId == id {
+			roomIds[room.ID] = true
+		}
+	}
+
+	for _, reservation := range m.reservations {
+		if reservation.RoomTypeId == id || roomIds[reservation.RoomId] {
+			return repository.ErrRoomTypeInUse
+		}
+	}
+
+	restrictions := m.roomRestrictions[:0]
+	for _, rr := range m.roomRestrictions {
+		if !roomIds[rr.RoomId] {
+			restrictions = append(restrictions, rr)
+		}
+	}
+	m.roomRestrictions = restrictions
+
+	rooms := m.rooms[:0]
+	for _, room := range m.rooms {
+		if !roomIds[room.ID] {
+			rooms = append(rooms, room)
+		}
+	}
+	m.rooms = rooms
+
+	rates := m.roomRates[:0]
+	for _, rate := range m.roomRates {
+		if rate.RoomTypeId != id {
+			rates = append(rates, rate)
+		}
+	}
+	m.roomRates = rates
+
+	for i := range m.roomTypes {
+		if m.roomTypes[i].ID == id {
+			m.roomTypes = append(m.roomTypes[:i], m.roomTypes[i+1:]...)
+			break
+		}
+	}
+	return nil
+}
+
+func (m *testDBRepo) freeUnits(roomTypeId int, startDate, endDate time.Time) []models.Room {
+	var rooms []models.Room
+
+	for _, room := range m.rooms {
+		if room.RoomTypeId == roomTypeId && room.Active && m.isAvailable(startDate, endDate, room.ID) {
+			rooms = append(rooms, room)
+		}
+	}
+	return rooms
+}
+
+func (m *testDBRepo) SearchAvailabilityAllRoomTypes(ctx context.Context, startDate, endDate time.Time, guests models.Guests) ([]models.RoomTypeAvailability, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var availability []models.RoomTypeAvailability
+
+	adults, total := guests.PerRoom()
+
+	for _, roomType := range m.roomTypes {
+		if !roomType.Fits(adults, total) {
+			continue
+		}
+		if free := len(m.freeUnits(roomType.ID, startDate, endDate)); free > 0 && free >= guests.Rooms {
+			availability = append(availability, models.RoomTypeAvailability{
+				RoomType:  roomType,
+				FreeUnits: free,
+			})
+		}
+	}
+
+	return availability, nil
+}
+
+func (m *testDBRepo) SearchAvailabilityByDatesByRoomTypeId(ctx context.Context, startDate, endDate time.Time, roomTypeId int) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return len(m.freeUnits(roomTypeId, startDate, endDate)), nil
+}
+
+func (m *testDBRepo) FreeRoomsByType(ctx context.Context, roomTypeId int, startDate, endDate time.Time) ([]models.Room, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	rooms := m.freeUnits(roomTypeId, startDate, endDate)
+
+	sortRooms(rooms)
+	return rooms, nil
+}
+
+func (m *testDBRepo) AssignReservationRoom(ctx context.Context, id, roomId int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.findRoom(roomId); !ok {
+		return sql.ErrNoRows
+	}
+
+	for i := range m.reservations {
+		reservation := m.reservations[i]
+		if reservation.ID != id {
+			continue
+		}
+
+		if models.HoldsRoom(reservation.Status) {
+			for _, rr := range m.roomRestrictions {
+				if rr.RoomId == roomId && rr.ReservationId != id && !isExpired(rr) && overlaps(reservation.StartDate, reservation.EndDate, rr) {
+					return repository.ErrRoomUnavailable
+				}
+			}
+		}
+
+		for j := range m.roomRestrictions {
+			if m.roomRestrictions[j].ReservationId == id {
+				m.roomRestrictions[j].RoomId = roomId
+				m.roomRestrictions[j].UpdatedAt = time.Now()
+			}
+		}
+
+		m.reservations[i].RoomId = roomId
+		m.reservations[i].UpdatedAt = time.Now()
+		return nil
+	}
+	return sql.ErrNoRows
+}
@@ -0,0 +1,228 @@
+package dbrepo
+
+import (
+	"context"
+	"database/sql"
+	"sort"
+	"time"
+
+	"github.com/NhanNT-VNG/hotel-booking/internal/models"
+	"github.com/NhanNT-VNG/hotel-booking/internal/repository"
+)
+
+func sortRooms(rooms []models.Room) {
+	sort.SliceStable(rooms, func(i, j int) bool {
+		if rooms[i].SortOrder != rooms[j].SortOrder {
+			return rooms[i].SortOrder < rooms[j].SortOrder
+		}
+		return rooms[i].ID < rooms[j].ID
+	})
+}
+
+func (m *testDBRepo) nextSortOrder() int {
+	next := 1
+	for _, room := range m.rooms {
+		if room.SortOrder >= next {
+			next = room.SortOrder + 1
+		}
+	}
+	return next
+}
+
+func (m *testDBRepo) InsertRoom(ctx context.Context, room models.Room) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.findRoomType(room.RoomTypeId); !ok {
+		return 0, sql.ErrNoRows
+	}
+
+	room.ID = m.nextId("rooms")
+	room.SortOrder = m.nextSortOrder()
+	room.CreatedAt = time.Now()
+	room.UpdatedAt = time.Now()
+	room.RoomType = models.RoomType{}
+	m.rooms = append(m.rooms, room)
+
+	return room.ID, nil
+}
+
+func (m *testDBRepo) UpdateRoom(ctx context.Context, room models.Room) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.findRoomType(room.RoomTypeId); !ok {
+		return sql.ErrNoRows
+	}
+
+	for i := range m.rooms {
+		if m.rooms[i].ID == room.ID {
+			m.rooms[i].RoomName = room.RoomName
+			m.rooms[i].RoomTypeId = room.RoomTypeId
+			m.rooms[i].Active = room.Active
+			m.rooms[i].UpdatedAt = time.Now()
+			return nil
+		}
+	}
+	return sql.ErrNoRows
+}
+
+func (m *testDBRepo) ReorderRooms(ctx context.Context, roomIds []int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for order, roomId := range roomIds {
+		for i := range m.rooms {
+			if m.rooms[i].ID == roomId {
+				m.rooms[i].SortOrder = order + 1
+			}
+		}
+	}
+	return nil
+}
+
+func (m *testDBRepo) OccupancyForDate(ctx context.Context, date time.Time) (models.Occupancy, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	countsAsSold := make(map[int]bool)
+	for _, restriction := range m.restrictions {
+		countsAsSold[restriction.ID] = restriction.CountsAsSold
+	}
+
+	var occupancy models.Occupancy
+	for _, room := range m.rooms {
+		if !room.Active {
+			continue
+		}
+		occupancy.Rooms++
+
+		sold, blocked := false, false
+		for _, rr := range m.roomRestrictions {
+			if rr.RoomId != room.ID || isExpired(rr) || !overlaps(date, date.AddDate(0, 0, 1), rr) {
+				continue
+			}
+			if countsAsSold[rr.RestrictionId] {
+				sold = true
+			} else {
+				blocked = true
+			}
+		}
+
+		switch {
+		case sold:
+			occupancy.Sold++
+		case blocked:
+			occupancy.OutOfService++
+		}
+	}
+	return occupancy, nil
+}
+
+func (m *testDBRepo) AllRestrictions(ctx context.Context) ([]models.Restriction, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	restrictions := make([]models.Restriction, len(m.restrictions))
+	copy(restrictions, m.restrictions)
+
+	return restrictions, nil
+}
+
+func (m *testDBRepo) GetRestrictionById(ctx context.Context, id int) (models.Restriction, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, restriction := range m.restrictions {
+		if restriction.ID == id {
+			return restriction, nil
+		}
+	}
+	return models.Restriction{}, sql.ErrNoRows
+}
+
+func (m *testDBRepo) InsertRestriction(ctx context.Context, restriction models.Restriction) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	restriction.ID = m.nextId("restrictions")
+	restriction.CreatedAt = time.Now()
+	restriction.UpdatedAt = time.Now()
+	m.restrictions = append(m.restrictions, restriction)
+
+	return restriction.ID, nil
+}
+
+func (m *testDBRepo) UpdateRestriction(ctx context.Context, restriction models.Restriction) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for i := range m.restrictions {
+		if m.restrictions[i].ID == restriction.ID {
+			m.restrictions[i].RestrictionName = restriction.RestrictionName
+			m.restrictions[i].Color = restriction.Color
+			m.restrictions[i].CountsAsSold = restriction.CountsAsSold
+			m.restrictions[i].UpdatedAt = time.Now()
+			return nil
+		}
+	}
+	return sql.ErrNoRows
+}
+
+func (m *testDBRepo) DeleteRestriction(ctx context.Context, id int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if (models.Restriction{ID: id}).IsBuiltIn() {
+		return repository.ErrRestrictionInUse
+	}
+
+	for _, rr := range m.roomRestrictions {
+		if rr.RestrictionId == id {
+			return repository.ErrRestrictionInUse
+		}
+	}
+
+	for i := range m.restrictions {
+		if m.restrictions[i].ID == id {
+			m.restrictions = append(m.restrictions[:i], m.restrictions[i+1:]...)
+			return nil
+		}
+	}
+	return sql.ErrNoRows
+}
+
+func (m *testDBRepo) RestrictionsForRoomByDate(ctx context.Context, roomId int, startDate, endDate time.Time) ([]models.RoomRestriction, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var restrictions []models.RoomRestriction
+
+	for _, rr := range m.roomRestrictions {
+		if rr.RoomId != roomId || isExpired(rr) || !overlaps(startDate, endDate, rr) {
+			continue
+		}
+		for _, restriction := range m.restrictions {
+			if restriction.ID == rr.RestrictionId {
+				rr.Restriction = restriction
+			}
+		}
+		restrictions = append(restrictions, rr)
+	}
+
+	sort.SliceStable(restrictions, func(i, j int) bool {
+		return restrictions[i].StartDate.Before(restrictions[j].StartDate)
+	})
+	return restrictions, nil
+}
+
+func (m *testDBRepo) DeleteBlockById(ctx context.Context, id int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.removeRoomRestrictions(func(rr models.RoomRestriction) bool {
+		return rr.ID != id || rr.ReservationId > 0 || rr.HoldToken != "" ||
+			rr.RestrictionId == models.RestrictionReservation || rr.RestrictionId == models.RestrictionHold
+	})
+	return nil
+}
@@ -0,0 +1,94 @@
+package dbrepo
+
+import (
+	"context"
+	"database/sql"
+	"sort"
+	"time"
+
+	"github.com/NhanNT-VNG/hotel-booking/internal/models"
+)
+
+func (m *testDBRepo) stayRuleWithRoomType(rule models.StayRule) models.StayRule {
+	if roomType, ok := m.findRoomType(rule.RoomTypeId); ok {
+		rule.RoomType = models.RoomType{ID: roomType.ID, Name: roomType.Name}
+	}
+	return rule
+}
+
+func (m *testDBRepo) AllStayRules(ctx context.Context) ([]models.StayRule, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var rules []models.StayRule
+
+	for _, rule := range m.stayRules {
+		rules = append(rules, m.stayRuleWithRoomType(rule))
+	}
+
+	sort.SliceStable(rules, func(i, j int) bool {
+		if rules[i].RoomType.Name != rules[j].RoomType.Name {
+			return rules[i].RoomType.Name < rules[j].RoomType.Name
+		}
+		return rules[i].StartDate.Before(rules[j].StartDate)
+	})
+	return rules, nil
+}
+
+func (m *testDBRepo) StayRulesForPeriod(ctx context.Context, roomTypeId int, startDate, endDate time.Time) ([]models.StayRule, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var rules []models.StayRule
+
+	for _, rule := range m.stayRules {
+		if rule.RoomTypeId == roomTypeId && !rule.StartDate.After(endDate) && !rule.EndDate.Before(startDate) {
+			rules = append(rules, m.stayRuleWithRoomType(rule))
+		}
+	}
+	return rules, nil
+}
+
+func (m *testDBRepo) GetStayRuleById(ctx context.Context, id int) (models.StayRule, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, rule := range m.stayRules {
+		if rule.ID == id {
+			return m.stayRuleWithRoomType(rule), nil
+		}
+	}
+	return models.StayRule{}, sql.ErrNoRows
+}
+
+func (m *testDBRepo) InsertStayRule(ctx context.Context, rule models.StayRule) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.findRoomType(rule.RoomTypeId); !ok {
+		return 0, sql.ErrNoRows
+	}
+
+	rule.ID = m.nextId("stay_rules")
+	rule.CreatedAt = time.Now()
+	rule.UpdatedAt = time.Now()
+	rule.RoomType = models.RoomType{}
+
+	m.stayRules = append(m.stayRules, rule)
+	return rule.ID, nil
+}
+
+func (m *testDBRepo) DeleteStayRule(ctx context.Context, id int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	rules := m.stayRules[:0]
+	for _, rule := range m.stayRules {
+		if rule.ID != id {
+			rules = append(rules, rule)
+		}
+	}
+	m.stayRules = rules
+
+	return nil
+}
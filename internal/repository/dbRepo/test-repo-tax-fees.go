@@ -0,0 +1,76 @@
+package dbrepo
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/NhanNT-VNG/hotel-booking/internal/models"
+)
+
+func (m *testDBRepo) AllTaxFees(ctx context.Context) ([]models.TaxFee, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return append([]models.TaxFee(nil), m.taxFees...), nil
+}
+
+func (m *testDBRepo) GetTaxFeeById(ctx context.Context, id int) (models.TaxFee, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, fee := range m.taxFees {
+		if fee.ID == id {
+			return fee, nil
+		}
+	}
+	return models.TaxFee{}, sql.ErrNoRows
+}
+
+func (m *testDBRepo) InsertTaxFee(ctx context.Context, fee models.TaxFee) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	fee.ID = m.nextId("tax_fees")
+	fee.CreatedAt = time.Now()
+	fee.UpdatedAt = time.Now()
+
+	m.taxFees = append(m.taxFees, fee)
+	return fee.ID, nil
+}
+
+func (m *testDBRepo) UpdateTaxFee(ctx context.Context, fee models.TaxFee) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for i := range m.taxFees {
+		if m.taxFees[i].ID == fee.ID {
+			fee.CreatedAt = m.taxFees[i].CreatedAt
+			fee.UpdatedAt = time.Now()
+			m.taxFees[i] = fee
+			return nil
+		}
+	}
+	return nil
+}
+
+func (m *testDBRepo) DeleteTaxFee(ctx context.Context, id int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	fees := m.taxFees[:0]
+	for _, fee := range m.taxFees {
+		if fee.ID != id {
+			fees = append(fees, fee)
+		}
+	}
+	m.taxFees = fees
+
+	for i := range m.charges {
+		if m.charges[i].TaxFeeId == id {
+			m.charges[i].TaxFeeId = 0
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,846 @@
+package dbrepo
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/NhanNT-VNG/hotel-booking/internal/models"
+	"github.com/NhanNT-VNG/hotel-booking/internal/repository"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func (m *testDBRepo) seed() {
+	now := time.Now()
+
+	hashedPassword, _ := bcrypt.GenerateFromPassword([]byte("password"), bcrypt.MinCost)
+
+	m.users = []models.User{
+		{
+			ID:          m.nextId("users"),
+			FirstName:   "Admin",
+			LastName:    "User",
+			Email:       "admin@admin.com",
+			Password:    string(hashedPassword),
+			AccessLevel: 3,
+			CreatedAt:   now,
+			UpdatedAt:   now,
+		},
+	}
+
+	m.policies = []models.CancellationPolicy{
+		{
+			ID:                   m.nextId("cancellation_policies"),
+			Name:                 "Flexible",
+			FreeCancellationDays: 2,
+			PenaltyType:          models.PenaltyFirstNight,
+			CreatedAt:            now,
+			UpdatedAt:            now,
+		},
+		{
+			ID:                   m.nextId("cancellation_policies"),
+			Name:                 "Moderate",
+			FreeCancellationDays: 7,
+			PenaltyType:          models.PenaltyPercent,
+			PenaltyPercent:       50,
+			CreatedAt:            now,
+			UpdatedAt:            now,
+		},
+		{
+			ID:          m.nextId("cancellation_policies"),
+			Name:        "Non-refundable",
+			PenaltyType: models.PenaltyNonRefundable,
+			CreatedAt:   now,
+			UpdatedAt:   now,
+		},
+	}
+
+	m.roomTypes = []models.RoomType{
+		{
+			ID:                   m.nextId("room_types"),
+			Name:                 "General's Quarters",
+			Slug:                 "generals-quarters",
+			Description:          "Your home away from home, set on the majestic waters of the Atlantic Ocean.",
+			Capacity:             2,
+			Amenities:            []string{"Ocean view", "King bed", "Free Wi-Fi"},
+			Photos:               []string{"/static/images/generals-quarters.png"},
+			BaseRate:             10000,
+			CancellationPolicyId: 1,
+			CreatedAt:            now,
+			UpdatedAt:            now,
+		},
+		{
+			ID:                   m.nextId("room_types"),
+			Name:                 "Major's Suite",
+			Slug:                 "majors-suite",
+			Description:          "Your home away from home, set on the majestic waters of the Atlantic Ocean.",
+			Capacity:             4,
+			Amenities:            []string{"Ocean view", "Two queen beds", "Sitting area", "Free Wi-Fi"},
+			Photos:               []string{"/static/images/marjors-suite.png"},
+			BaseRate:             15000,
+			CancellationPolicyId: 2,
+			CreatedAt:            now,
+			UpdatedAt:            now,
+		},
+	}
+
+	m.rooms = []models.Room{
+		{ID: m.nextId("rooms"), RoomName: "General's Quarters", RoomTypeId: 1, Active: true, SortOrder: 1, CreatedAt: now, UpdatedAt: now},
+		{ID: m.nextId("rooms"), RoomName: "Major's Suite", RoomTypeId: 2, Active: true, SortOrder: 2, CreatedAt: now, UpdatedAt: now},
+	}
+
+	m.restrictions = []models.Restriction{
+		{ID: m.nextId("restrictions"), RestrictionName: "Reservation", Color: "#007bff", CountsAsSold: true, CreatedAt: now, UpdatedAt: now},
+		{ID: m.nextId("restrictions"), RestrictionName: "Owner Block", Color: "#dc3545", CreatedAt: now, UpdatedAt: now},
+		{ID: m.nextId("restrictions"), RestrictionName: "Hold", Color: "#ffc107", CreatedAt: now, UpdatedAt: now},
+		{ID: m.nextId("restrictions"), RestrictionName: "Maintenance", Color: "#6c757d", CreatedAt: now, UpdatedAt: now},
+	}
+}
+
+func (m *testDBRepo) nextId(table string) int {
+	m.lastIds[table]++
+	return m.lastIds[table]
+}
+
+func (m *testDBRepo) findRoom(roomId int) (models.Room, bool) {
+	for _, room := range m.rooms {
+		if room.ID == roomId {
+			return room, true
+		}
+	}
+	return models.Room{}, false
+}
+
+func (m *testDBRepo) findRoomType(roomTypeId int) (models.RoomType, bool) {
+	for _, roomType := range m.roomTypes {
+		if roomType.ID == roomTypeId {
+			return roomType, true
+		}
+	}
+	return models.RoomType{}, false
+}
+
+func (m *testDBRepo) withRoom(reservation models.Reservation) models.Reservation {
+	if room, ok := m.findRoom(reservation.RoomId); ok {
+		reservation.Room.ID = room.ID
+		reservation.Room.RoomName = room.RoomName
+	}
+	if roomType, ok := m.findRoomType(reservation.RoomTypeId); ok {
+		reservation.RoomType.ID = roomType.ID
+		reservation.RoomType.Name = roomType.Name
+	}
+	return reservation
+}
+
+func overlaps(startDate, endDate time.Time, rr models.RoomRestriction) bool {
+	return startDate.Before(rr.EndDate) && endDate.After(rr.StartDate)
+}
+
+func isExpired(rr models.RoomRestriction) bool {
+	return !rr.ExpiresAt.IsZero() && rr.ExpiresAt.Before(time.Now())
+}
+
+func (m *testDBRepo) isAvailable(startDate, endDate time.Time, roomId int) bool {
+	for _, rr := range m.roomRestrictions {
+		if rr.RoomId == roomId && !isExpired(rr) && overlaps(startDate, endDate, rr) {
+			return false
+		}
+	}
+	return true
+}
+
+// pickRoom returns the preferred room when it is free for the dates, otherwise
+// the first other free unit of the same type.
+func (m *testDBRepo) pickRoom(roomTypeId, preferredRoomId int, startDate, endDate time.Time) (int, error) {
+	if room, ok := m.findRoom(preferredRoomId); ok && room.Active && (roomTypeId == 0 || room.RoomTypeId == roomTypeId) {
+		if m.isAvailable(startDate, endDate, room.ID) {
+			return room.ID, nil
+		}
+	}
+
+	if roomTypeId > 0 {
+		for _, room := range m.rooms {
+			if room.RoomTypeId == roomTypeId && room.Active && m.isAvailable(startDate, endDate, room.ID) {
+				return room.ID, nil
+			}
+		}
+	}
+
+	return 0, repository.ErrRoomUnavailable
+}
+
+func sortByStartDate(reservations []models.Reservation) {
+	sort.SliceStable(reservations, func(i, j int) bool {
+		return reservations[i].StartDate.Before(reservations[j].StartDate)
+	})
+}
+
+func (m *testDBRepo) AllUsers(ctx context.Context) bool {
+	return true
+}
+
+func (m *testDBRepo) InsertReservation(ctx context.Context, reservation models.Reservation) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.findRoom(reservation.RoomId); !ok {
+		return 0, errors.New("room does not exist")
+	}
+
+	reservation.ID = m.nextId("reservations")
+	reservation.Status = models.StatusPending
+	reservation.CreatedAt = time.Now()
+	reservation.UpdatedAt = time.Now()
+	reservation.Room = models.Room{}
+	reservation.RoomType = models.RoomType{}
+
+	m.reservations = append(m.reservations, reservation)
+
+	return reservation.ID, nil
+}
+
+func (m *testDBRepo) InsertRoomRestrictions(ctx context.Context, rr models.RoomRestriction) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.findRoom(rr.RoomId); !ok {
+		return errors.New("room does not exist")
+	}
+
+	if !m.isAvailable(rr.StartDate, rr.EndDate, rr.RoomId) {
+		return repository.ErrRoomUnavailable
+	}
+
+	rr.ID = m.nextId("room_restrictions")
+	rr.CreatedAt = time.Now()
+	rr.UpdatedAt = time.Now()
+
+	m.roomRestrictions = append(m.roomRestrictions, rr)
+
+	return nil
+}
+
+func (m *testDBRepo) removeRoomRestrictions(keep func(rr models.RoomRestriction) bool) int64 {
+	var removed int64
+
+	roomRestrictions := m.roomRestrictions[:0]
+	for _, rr := range m.roomRestrictions {
+		if keep(rr) {
+			roomRestrictions = append(roomRestrictions, rr)
+		} else {
+			removed++
+		}
+	}
+	m.roomRestrictions = roomRestrictions
+
+	return removed
+}
+
+func (m *testDBRepo) CreateReservation(ctx context.Context, reservation models.Reservation, restrictionId int, holdToken string) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	reservations := append([]models.Reservation(nil), m.reservations...)
+	roomRestrictions := append([]models.RoomRestriction(nil), m.roomRestrictions...)
+	charges := append([]models.ReservationCharge(nil), m.charges...)
+	rollback := func() {
+		m.reservations = reservations
+		m.roomRestrictions = roomRestrictions
+		m.charges = charges
+	}
+
+	holdTokens := map[string]bool{holdToken: true}
+	for _, line := range reservation.Lines {
+		holdTokens[line.HoldToken] = true
+	}
+	m.removeRoomRestrictions(func(rr models.RoomRestriction) bool {
+		return rr.HoldToken == "" || !holdTokens[rr.HoldToken]
+	})
+
+	promoIndex := -1
+	if reservation.PromoCodeId > 0 {
+		for i, promo := range m.promoCodes {
+			if promo.ID == reservation.PromoCodeId {
+				promoIndex = i
+			}
+		}
+		if promoIndex < 0 || !m.promoCodes[promoIndex].Active ||
+			(m.promoCodes[promoIndex].MaxUses > 0 && m.promoCodes[promoIndex].Uses >= m.promoCodes[promoIndex].MaxUses) {
+			rollback()
+			return 0, repository.ErrPromoCodeUsedUp
+		}
+	}
+
+	lines := reservation.Lines
+	reservation.Lines = nil
+
+	reservationId, err := m.insertReservation(reservation, 0, restrictionId)
+	if err != nil {
+		rollback()
+		return 0, err
+	}
+
+	for _, line := range lines {
+		line.FirstName = reservation.FirstName
+		line.LastName = reservation.LastName
+		line.Email = reservation.Email
+		line.Phone = reservation.Phone
+		line.ConfirmationCode = ""
+		line.PromoCodeId = reservation.PromoCodeId
+		line.PromoCode = reservation.PromoCode
+		if _, err = m.insertReservation(line, reservationId, restrictionId); err != nil {
+			rollback()
+			return 0, err
+		}
+	}
+
+	if promoIndex >= 0 {
+		m.promoCodes[promoIndex].Uses++
+	}
+
+	return reservationId, nil
+}
+
+func (m *testDBRepo) insertReservation(reservation models.Reservation, parentId, restrictionId int) (int, error) {
+	if _, ok := m.findRoom(reservation.RoomId); !ok && reservation.RoomTypeId == 0 {
+		return 0, errors.New("room does not exist")
+	}
+
+	roomId, err := m.pickRoom(reservation.RoomTypeId, reservation.RoomId, reservation.StartDate, reservation.EndDate)
+	if err != nil {
+		return 0, err
+	}
+	room, _ := m.findRoom(roomId)
+	reservation.RoomId = room.ID
+	reservation.RoomTypeId = room.RoomTypeId
+
+	reservation.ID = m.nextId("reservations")
+	reservation.ParentId = parentId
+	reservation.HoldToken = ""
+	reservation.Status = models.StatusPending
+	reservation.CreatedAt = time.Now()
+	reservation.UpdatedAt = time.Now()
+	reservation.Room = models.Room{}
+	reservation.RoomType = models.RoomType{}
+	m.insertCharges(reservation.ID, reservation.Charges)
+	reservation.Charges = nil
+
+	m.reservations = append(m.reservations, reservation)
+
+	m.roomRestrictions = append(m.roomRestrictions, models.RoomRestriction{
+		ID:            m.nextId("room_restrictions"),
+		StartDate:     reservation.StartDate,
+		EndDate:       reservation.EndDate,
+		RoomId:        reservation.RoomId,
+		ReservationId: reservation.ID,
+		RestrictionId: restrictionId,
+		CreatedAt:     time.Now(),
+		UpdatedAt:     time.Now(),
+	})
+
+	return reservation.ID, nil
+}
+
+func (m *testDBRepo) ReservationLines(ctx context.Context, parentId int) ([]models.Reservation, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var lines []models.Reservation
+
+	for _, reservation := range m.reservations {
+		if reservation.ParentId == parentId && parentId > 0 {
+			lines = append(lines, m.withRoom(reservation))
+		}
+	}
+
+	return lines, nil
+}
+
+func (m *testDBRepo) CreateRoomHold(ctx context.Context, rr models.RoomRestriction, roomTypeId int) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.findRoom(rr.RoomId); !ok && roomTypeId == 0 {
+		return 0, errors.New("room does not exist")
+	}
+
+	roomId, err := m.pickRoom(roomTypeId, rr.RoomId, rr.StartDate, rr.EndDate)
+	if err != nil {
+		return 0, err
+	}
+
+	rr.ID = m.nextId("room_restrictions")
+	rr.RoomId = roomId
+	rr.CreatedAt = time.Now()
+	rr.UpdatedAt = time.Now()
+
+	m.roomRestrictions = append(m.roomRestrictions, rr)
+
+	return rr.RoomId, nil
+}
+
+func (m *testDBRepo) DeleteRoomHold(ctx context.Context, holdToken string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.removeRoomRestrictions(func(rr models.RoomRestriction) bool {
+		return rr.HoldToken == "" || rr.HoldToken != holdToken
+	})
+
+	return nil
+}
+
+func (m *testDBRepo) DeleteExpiredHolds(ctx context.Context) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	removed := m.removeRoomRestrictions(func(rr models.RoomRestriction) bool {
+		return !isExpired(rr)
+	})
+
+	return removed, nil
+}
+
+func (m *testDBRepo) GetRoomById(ctx context.Context, roomId int) (models.Room, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	room, ok := m.findRoom(roomId)
+	if !ok {
+		return room, sql.ErrNoRows
+	}
+	return room, nil
+}
+
+func (m *testDBRepo) GetUserById(ctx context.Context, userId int) (models.User, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, user := range m.users {
+		if user.ID == userId {
+			return user, nil
+		}
+	}
+	return models.User{}, sql.ErrNoRows
+}
+
+func (m *testDBRepo) UpdateUser(ctx context.Context, user models.User) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for i := range m.users {
+		if m.users[i].ID == user.ID {
+			m.users[i].FirstName = user.FirstName
+			m.users[i].LastName = user.LastName
+			m.users[i].Email = user.Email
+			m.users[i].AccessLevel = user.AccessLevel
+			m.users[i].UpdatedAt = time.Now()
+			return nil
+		}
+	}
+	return nil
+}
+
+func (m *testDBRepo) Authenticate(ctx context.Context, email, password string) (int, string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, user := range m.users {
+		if user.Email != email {
+			continue
+		}
+
+		err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password))
+		if err == bcrypt.ErrMismatchedHashAndPassword {
+			return 0, "", errors.New("incorrect email or password")
+		} else if err != nil {
+			return 0, "", err
+		}
+
+		return user.ID, user.Password, nil
+	}
+	return 0, "", sql.ErrNoRows
+}
+
+func (m *testDBRepo) ReservationsByStatus(ctx context.Context, status string) ([]models.Reservation, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var reservationList []models.Reservation
+
+	for _, reservation := range m.reservations {
+		if reservation.DeletedAt.IsZero() && reservation.ParentId == 0 && (status == "" || reservation.Status == status) {
+			reservationList = append(reservationList, m.withRoom(reservation))
+		}
+	}
+
+	sortByStartDate(reservationList)
+	return reservationList, nil
+}
+
+func (m *testDBRepo) GetReservationById(ctx context.Context, id int) (models.Reservation, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, reservation := range m.reservations {
+		if reservation.ID == id {
+			return m.withRoom(reservation), nil
+		}
+	}
+	return models.Reservation{}, sql.ErrNoRows
+}
+
+func (m *testDBRepo) GetReservationByCode(ctx context.Context, code string) (models.Reservation, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, reservation := range m.reservations {
+		if code != "" && reservation.ConfirmationCode == code && reservation.DeletedAt.IsZero() {
+			return m.withRoom(reservation), nil
+		}
+	}
+	return models.Reservation{}, sql.ErrNoRows
+}
+
+func (m *testDBRepo) insertCharges(reservationId int, charges []models.ReservationCharge) {
+	for _, charge := range charges {
+		charge.ID = m.nextId("reservation_charges")
+		charge.ReservationId = reservationId
+		m.charges = append(m.charges, charge)
+	}
+}
+
+func (m *testDBRepo) ReservationCharges(ctx context.Context, reservationId int) ([]models.ReservationCharge, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var charges []models.ReservationCharge
+
+	for _, charge := range m.charges {
+		if charge.ReservationId == reservationId {
+			charges = append(charges, charge)
+		}
+	}
+	return charges, nil
+}
+
+func (m *testDBRepo) ChangeReservationDates(ctx context.Context, reservation models.Reservation) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	id := reservation.ID
+	startDate, endDate := reservation.StartDate, reservation.EndDate
+
+	for i := range m.reservations {
+		if m.reservations[i].ID != id {
+			continue
+		}
+
+		var removed []models.RoomRestriction
+		m.removeRoomRestrictions(func(rr models.RoomRestriction) bool {
+			if rr.ReservationId == id {
+				removed = append(removed, rr)
+				return false
+			}
+			return true
+		})
+
+		roomId, err := m.pickRoom(m.reservations[i].RoomTypeId, m.reservations[i].RoomId, startDate, endDate)
+		if err != nil {
+			m.roomRestrictions = append(m.roomRestrictions, removed...)
+			return err
+		}
+
+		m.reservations[i].RoomId = roomId
+		m.reservations[i].StartDate = startDate
+		m.reservations[i].EndDate = endDate
+		m.reservations[i].Total = reservation.Total
+		m.reservations[i].Discount = reservation.Discount
+		m.reservations[i].FirstNight = reservation.FirstNight
+		m.reservations[i].UpdatedAt = time.Now()
+
+		charges := m.charges[:0]
+		for _, charge := range m.charges {
+			if charge.ReservationId != id {
+				charges = append(charges, charge)
+			}
+		}
+		m.charges = charges
+		m.insertCharges(id, reservation.Charges)
+
+		m.roomRestrictions = append(m.roomRestrictions, models.RoomRestriction{
+			ID:            m.nextId("room_restrictions"),
+			StartDate:     startDate,
+			EndDate:       endDate,
+			RoomId:        roomId,
+			ReservationId: id,
+			RestrictionId: models.RestrictionReservation,
+			CreatedAt:     time.Now(),
+			UpdatedAt:     time.Now(),
+		})
+		return nil
+	}
+	return sql.ErrNoRows
+}
+
+func (m *testDBRepo) UpdateReservation(ctx context.Context, reservation models.Reservation) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for i := range m.reservations {
+		if m.reservations[i].ID == reservation.ID {
+			m.reservations[i].FirstName = reservation.FirstName
+			m.reservations[i].LastName = reservation.LastName
+			m.reservations[i].Email = reservation.Email
+			m.reservations[i].Phone = reservation.Phone
+			m.reservations[i].UpdatedAt = time.Now()
+			return nil
+		}
+	}
+	return nil
+}
+
+func (m *testDBRepo) DeleteReservation(ctx context.Context, id, userId int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	held, last := false, -1
+	for i := range m.reservations {
+		if (m.reservations[i].ID == id || m.reservations[i].ParentId == id) && m.reservations[i].DeletedAt.IsZero() {
+			m.reservations[i].DeletedAt = time.Now()
+			m.reservations[i].DeletedBy = userId
+			held = held || models.HoldsRoom(m.reservations[i].Status)
+			last = i
+		}
+	}
+	if last < 0 {
+		return nil
+	}
+
+	m.removeRoomRestrictions(func(rr models.RoomRestriction) bool {
+		for _, reservation := range m.reservations {
+			if reservation.ID == rr.ReservationId && (reservation.ID == id || reservation.ParentId == id) {
+				return false
+			}
+		}
+		return true
+	})
+
+	if held {
+		m.releasePromoUse(last)
+	}
+	return nil
+}
+
+func (m *testDBRepo) DeletedReservations(ctx context.Context) ([]models.Reservation, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var reservationList []models.Reservation
+
+	for _, reservation := range m.reservations {
+		if !reservation.DeletedAt.IsZero() && reservation.ParentId == 0 {
+			reservationList = append(reservationList, m.withRoom(reservation))
+		}
+	}
+
+	sort.SliceStable(reservationList, func(i, j int) bool {
+		return reservationList[i].DeletedAt.After(reservationList[j].DeletedAt)
+	})
+	return reservationList, nil
+}
+
+func (m *testDBRepo) RestoreReservation(ctx context.Context, id int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for i := range m.reservations {
+		reservation := m.reservations[i]
+		if reservation.ID != id || reservation.DeletedAt.IsZero() {
+			continue
+		}
+
+		if models.HoldsRoom(reservation.Status) {
+			takeUse := !m.bookingActive(i)
+			if takeUse && m.promoCodeUsedUp(reservation.PromoCodeId) {
+				return repository.ErrPromoCodeUsedUp
+			}
+
+			roomId, err := m.pickRoom(reservation.RoomTypeId, reservation.RoomId, reservation.StartDate, reservation.EndDate)
+			if err != nil {
+				return err
+			}
+			if takeUse {
+				m.changePromoUses(i, 1)
+			}
+			reservation.RoomId = roomId
+			m.reservations[i].RoomId = roomId
+
+			m.roomRestrictions = append(m.roomRestrictions, models.RoomRestriction{
+				ID:            m.nextId("room_restrictions"),
+				StartDate:     reservation.StartDate,
+				EndDate:       reservation.EndDate,
+				RoomId:        reservation.RoomId,
+				ReservationId: id,
+				RestrictionId: models.RestrictionReservation,
+				CreatedAt:     time.Now(),
+				UpdatedAt:     time.Now(),
+			})
+		}
+
+		m.reservations[i].DeletedAt = time.Time{}
+		m.reservations[i].DeletedBy = 0
+		m.reservations[i].UpdatedAt = time.Now()
+		return nil
+	}
+	return sql.ErrNoRows
+}
+
+func (m *testDBRepo) changeStatus(id int, status string) (int, error) {
+	for i := range m.reservations {
+		if m.reservations[i].ID != id {
+			continue
+		}
+
+		current := m.reservations[i].Status
+		if !models.CanTransition(current, status) {
+			return 0, repository.ErrInvalidStatusTransition
+		}
+
+		m.reservations[i].Status = status
+		m.reservations[i].UpdatedAt = time.Now()
+
+		m.statusChanges = append(m.statusChanges, models.ReservationStatusChange{
+			ID:            m.nextId("reservation_status_changes"),
+			ReservationId: id,
+			FromStatus:    current,
+			ToStatus:      status,
+			CreatedAt:     time.Now(),
+		})
+
+		if status == models.StatusCancelled || status == models.StatusNoShow {
+			m.removeRoomRestrictions(func(rr models.RoomRestriction) bool {
+				return rr.ReservationId != id
+			})
+			if m.reservations[i].DeletedAt.IsZero() {
+				m.releasePromoUse(i)
+			}
+		}
+		return i, nil
+	}
+	return 0, sql.ErrNoRows
+}
+
+func (m *testDBRepo) bookingActive(i int) bool {
+	root := m.reservations[i].ParentId
+	if root == 0 {
+		root = m.reservations[i].ID
+	}
+
+	for _, reservation := range m.reservations {
+		if (reservation.ID == root || reservation.ParentId == root) &&
+			reservation.DeletedAt.IsZero() && models.HoldsRoom(reservation.Status) {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *testDBRepo) changePromoUses(i, change int) {
+	for j := range m.promoCodes {
+		if m.promoCodes[j].ID == m.reservations[i].PromoCodeId {
+			m.promoCodes[j].Uses += change
+			if m.promoCodes[j].Uses < 0 {
+				m.promoCodes[j].Uses = 0
+			}
+		}
+	}
+}
+
+func (m *testDBRepo) promoCodeUsedUp(id int) bool {
+	for _, code := range m.promoCodes {
+		if code.ID == id && id > 0 {
+			return code.MaxUses > 0 && code.Uses >= code.MaxUses
+		}
+	}
+	return false
+}
+
+func (m *testDBRepo) releasePromoUse(i int) {
+	if !m.bookingActive(i) {
+		m.changePromoUses(i, -1)
+	}
+}
+
+func (m *testDBRepo) UpdateReservationStatus(ctx context.Context, id int, status string) error {
+	return m.ChangeRoomsStatus(ctx, []models.Reservation{{ID: id}}, status)
+}
+
+func (m *testDBRepo) CancelReservation(ctx context.Context, id int, penalty int) error {
+	return m.ChangeRoomsStatus(ctx, []models.Reservation{{ID: id, CancellationPenalty: penalty}}, models.StatusCancelled)
+}
+
+func (m *testDBRepo) ChangeRoomsStatus(ctx context.Context, rooms []models.Reservation, status string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.changeRoomsStatus(rooms, status)
+}
+
+// changeRoomsStatus checks every room can be moved to status before any is
+// changed, as the transaction of the Postgres repository would.
+func (m *testDBRepo) changeRoomsStatus(rooms []models.Reservation, status string) error {
+	for _, room := range rooms {
+		found := false
+		for _, reservation := range m.reservations {
+			if reservation.ID == room.ID {
+				found = true
+				if !models.CanTransition(reservation.Status, status) {
+					return repository.ErrInvalidStatusTransition
+				}
+			}
+		}
+		if !found {
+			return sql.ErrNoRows
+		}
+	}
+
+	for _, room := range rooms {
+		i, err := m.changeStatus(room.ID, status)
+		if err != nil {
+			return err
+		}
+		if status == models.StatusCancelled {
+			m.reservations[i].CancellationPenalty = room.CancellationPenalty
+			m.reservations[i].CancelledAt = time.Now()
+		}
+	}
+	return nil
+}
+
+func (m *testDBRepo) GetReservationStatusChanges(ctx context.Context, id int) ([]models.ReservationStatusChange, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var changes []models.ReservationStatusChange
+
+	for _, change := range m.statusChanges {
+		if change.ReservationId == id {
+			changes = append(changes, change)
+		}
+	}
+	return changes, nil
+}
+
+func (m *testDBRepo) AllRooms(ctx context.Context) ([]models.Room, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	rooms := make([]models.Room, len(m.rooms))
+	copy(rooms, m.rooms)
+
+	sortRooms(rooms)
+	return rooms, nil
+}
@@ -0,0 +1,16 @@
+package repository
+
+import "errors"
+
+var (
+	ErrRoomUnavailable         = errors.New("room is not available for the selected dates")
+	ErrInvalidStatusTransition = errors.New("reservation status change is not allowed")
+	ErrDuplicateSlug           = errors.New("slug is already in use")
+	ErrRoomTypeInUse           = errors.New("room type has reservations")
+	ErrRestrictionInUse        = errors.New("restriction type is in use")
+	ErrPromoCodeUsedUp         = errors.New("promo code has reached its usage limit")
+	ErrDuplicatePromoCode      = errors.New("promo code already exists")
+	ErrPaymentInProgress       = errors.New("reservation already has a payment")
+	ErrReservationNotPending   = errors.New("reservation is not waiting for payment")
+	ErrRefundsChanged          = errors.New("reservation was refunded by someone else")
+)
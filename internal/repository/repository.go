@@ -1,28 +1,122 @@
 package repository
 
 import (
+	"context"
 	"time"
 
 	"github.com/NhanNT-VNG/hotel-booking/internal/models"
 )
 
 type DatabaseRepo interface {
-	AllUsers() bool
-	InsertReservation(res models.Reservation) (int, error)
-	InsertRoomRestrictions(rr models.RoomRestriction) error
-	SearchAvailabilityByDatesByRoomId(statDate, endDate time.Time, roomId int) (bool, error)
-	SearchAvailabilityAllRooms(startDate, endDate time.Time) ([]models.Room, error)
-	GetRoomById(roomId int) (models.Room, error)
-
-	GetUserById(userId int) (models.User, error)
-	UpdateUser(user models.User) error
-	Authenticate(email, password string) (int, string, error)
-
-	AllReservations() ([]models.Reservation, error)
-	AllNewReservations() ([]models.Reservation, error)
-	GetReservationById(id int) (models.Reservation, error)
-	UpdateReservation(reservation models.Reservation) error
-	DeleteReservation(id int) error
-	UpdateProcessedReservation(id, processed int) error
-	AllRooms() ([]models.Room, error)
+	AllUsers(ctx context.Context) bool
+	InsertReservation(ctx context.Context, res models.Reservation) (int, error)
+	InsertRoomRestrictions(ctx context.Context, rr models.RoomRestriction) error
+	CreateReservation(ctx context.Context, res models.Reservation, restrictionId int, holdToken string) (int, error)
+	CreateRoomHold(ctx context.Context, rr models.RoomRestriction, roomTypeId int) (int, error)
+	DeleteRoomHold(ctx context.Context, holdToken string) error
+	DeleteExpiredHolds(ctx context.Context) (int64, error)
+	SearchAvailabilityByDatesByRoomTypeId(ctx context.Context, startDate, endDate time.Time, roomTypeId int) (int, error)
+	SearchAvailabilityAllRoomTypes(ctx context.Context, startDate, endDate time.Time, guests models.Guests) ([]models.RoomTypeAvailability, error)
+	GetRoomById(ctx context.Context, roomId int) (models.Room, error)
+
+	GetUserById(ctx context.Context, userId int) (models.User, error)
+	UpdateUser(ctx context.Context, user models.User) error
+	Authenticate(ctx context.Context, email, password string) (int, string, error)
+
+	ReservationsByStatus(ctx context.Context, status string) ([]models.Reservation, error)
+	GetReservationById(ctx context.Context, id int) (models.Reservation, error)
+	GetReservationByCode(ctx context.Context, code string) (models.Reservation, error)
+	ReservationLines(ctx context.Context, parentId int) ([]models.Reservation, error)
+	ReservationCharges(ctx context.Context, reservationId int) ([]models.ReservationCharge, error)
+	ChangeReservationDates(ctx context.Context, reservation models.Reservation) error
+	UpdateReservation(ctx context.Context, reservation models.Reservation) error
+	DeleteReservation(ctx context.Context, id, userId int) error
+	DeletedReservations(ctx context.Context) ([]models.Reservation, error)
+	RestoreReservation(ctx context.Context, id int) error
+	UpdateReservationStatus(ctx context.Context, id int, status string) error
+	CancelReservation(ctx context.Context, id int, penalty int) error
+	ChangeRoomsStatus(ctx context.Context, rooms []models.Reservation, status string) error
+	GetReservationStatusChanges(ctx context.Context, id int) ([]models.ReservationStatusChange, error)
+
+	AllRooms(ctx context.Context) ([]models.Room, error)
+	InsertRoom(ctx context.Context, room models.Room) (int, error)
+	UpdateRoom(ctx context.Context, room models.Room) error
+	ReorderRooms(ctx context.Context, roomIds []int) error
+	OccupancyForDate(ctx context.Context, date time.Time) (models.Occupancy, error)
+
+	RestrictionsForRoomByDate(ctx context.Context, roomId int, startDate, endDate time.Time) ([]models.RoomRestriction, error)
+	DeleteBlockById(ctx context.Context, id int) error
+
+	AllRestrictions(ctx context.Context) ([]models.Restriction, error)
+	GetRestrictionById(ctx context.Context, id int) (models.Restriction, error)
+	InsertRestriction(ctx context.Context, restriction models.Restriction) (int, error)
+	UpdateRestriction(ctx context.Context, restriction models.Restriction) error
+	DeleteRestriction(ctx context.Context, id int) error
+
+	AllRoomTypes(ctx context.Context) ([]models.RoomType, error)
+	GetRoomTypeById(ctx context.Context, id int) (models.RoomType, error)
+	GetRoomTypeBySlug(ctx context.Context, slug string) (models.RoomType, error)
+	InsertRoomType(ctx context.Context, roomType models.RoomType) (int, error)
+	UpdateRoomType(ctx context.Context, roomType models.RoomType) error
+	DeleteRoomType(ctx context.Context, id int) error
+	FreeRoomsByType(ctx context.Context, roomTypeId int, startDate, endDate time.Time) ([]models.Room, error)
+	AssignReservationRoom(ctx context.Context, id, roomId int) error
+
+	AllCancellationPolicies(ctx context.Context) ([]models.CancellationPolicy, error)
+	GetCancellationPolicyById(ctx context.Context, id int) (models.CancellationPolicy, error)
+	GetCancellationPolicyByRoomTypeId(ctx context.Context, roomTypeId int) (models.CancellationPolicy, error)
+	InsertCancellationPolicy(ctx context.Context, policy models.CancellationPolicy) (int, error)
+	UpdateCancellationPolicy(ctx context.Context, policy models.CancellationPolicy) error
+	UpdateRoomTypeCancellationPolicy(ctx context.Context, roomTypeId, policyId int) error
+
+	AllRoomRates(ctx context.Context) ([]models.RoomRate, error)
+	RoomRatesForPeriod(ctx context.Context, roomTypeId int, startDate, endDate time.Time) ([]models.RoomRate, error)
+	InsertRoomRate(ctx context.Context, rate models.RoomRate) (int, error)
+	GetRoomRateById(ctx context.Context, id int) (models.RoomRate, error)
+	DeleteRoomRate(ctx context.Context, id int) error
+	UpdateRoomTypeBaseRate(ctx context.Context, roomTypeId, rate int) error
+
+	AllStayRules(ctx context.Context) ([]models.StayRule, error)
+	StayRulesForPeriod(ctx context.Context, roomTypeId int, startDate, endDate time.Time) ([]models.StayRule, error)
+	GetStayRuleById(ctx context.Context, id int) (models.StayRule, error)
+	InsertStayRule(ctx context.Context, rule models.StayRule) (int, error)
+	DeleteStayRule(ctx context.Context, id int) error
+
+	AllPromoCodes(ctx context.Context) ([]models.PromoCode, error)
+	GetPromoCodeById(ctx context.Context, id int) (models.PromoCode, error)
+	GetPromoCodeByCode(ctx context.Context, code string) (models.PromoCode, error)
+	InsertPromoCode(ctx context.Context, promo models.PromoCode) (int, error)
+	UpdatePromoCode(ctx context.Context, promo models.PromoCode) error
+	DeletePromoCode(ctx context.Context, id int) error
+
+	AllTaxFees(ctx context.Context) ([]models.TaxFee, error)
+	GetTaxFeeById(ctx context.Context, id int) (models.TaxFee, error)
+	InsertTaxFee(ctx context.Context, fee models.TaxFee) (int, error)
+	UpdateTaxFee(ctx context.Context, fee models.TaxFee) error
+	DeleteTaxFee(ctx context.Context, id int) error
+
+	ReservationPayments(ctx context.Context, reservationId int) ([]models.Payment, error)
+	GetPaymentByProviderRef(ctx context.Context, provider, ref string) (models.Payment, error)
+	InsertPayment(ctx context.Context, payment models.Payment) (int, error)
+	UpdatePaymentStatus(ctx context.Context, id int, status, message string) error
+	AuthorizePayment(ctx context.Context, id int, providerRef string) error
+	UnpaidReservations(ctx context.Context, createdBefore time.Time) ([]models.Reservation, error)
+	CancelUnpaidReservation(ctx context.Context, id int) (bool, error)
+	ReservationRefunds(ctx context.Context, reservationId int) ([]models.Refund, error)
+	InsertPendingRefunds(ctx context.Context, reservationId, refunded int, refunds []models.Refund) ([]models.Refund, error)
+	CompleteRefund(ctx context.Context, refund models.Refund, description string) error
+	FailRefund(ctx context.Context, id int) error
+	LedgerEntries(ctx context.Context, reservationId int) ([]models.LedgerEntry, error)
+	InsertLedgerEntry(ctx context.Context, entry models.LedgerEntry) (int, error)
+
+	IssueInvoice(ctx context.Context, reservationId int) (models.Invoice, error)
+
+	InsertMail(ctx context.Context, mail models.MailData) (int, error)
+	ClaimMail(ctx context.Context, lease time.Duration) (models.OutboxMail, error)
+	UpdateOutboxMail(ctx context.Context, mail models.OutboxMail) error
+	OutboxMailByStatus(ctx context.Context, status string) ([]models.OutboxMail, error)
+	ResendMail(ctx context.Context, id int) error
+
+	InsertAuditEvent(ctx context.Context, event models.AuditEvent) error
+	AuditEventsByEntity(ctx context.Context, entityType string, entityId int) ([]models.AuditEvent, error)
 }